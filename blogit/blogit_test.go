@@ -12,51 +12,9 @@ import (
 	"fmt"
 	"os"
 	"path"
-	"strings"
 	"testing"
 )
 
-func TestPrivateFuncs(t *testing.T) {
-	rsdoiel := "R. S. Doiel"
-	obj := map[string]string{}
-	obj["name"] = rsdoiel
-	objs := append([]interface{}{}, obj)
-	creators := unpackCreators(objs)
-	if len(creators) != 1 {
-		t.Errorf("expected one creator got %d", len(creators))
-	} else {
-		if fmt.Sprintf("%T", creators[0].Name) != "string" {
-			t.Errorf("Expected crestors[0].Name to ba a string, got %T", creators[0].Name)
-		}
-		if strings.Compare(creators[0].Name, rsdoiel) != 0 {
-			t.Errorf("expected creators[0].Name = %q, got %q", rsdoiel, creators[0].Name)
-		}
-	}
-	ymd, err := calcYMD("2003-01-02")
-	if err != nil {
-		t.Errorf("Unexpected err %s, calcYMD()", err)
-		t.FailNow()
-	}
-	if len(ymd) != 3 {
-		t.Errorf("expected len(ymd) = 3, got %+v", ymd)
-	} else {
-		for i, val := range []string{"2003", "01", "02"} {
-			if ymd[i] != val {
-				t.Errorf("expected %q, got %q", val, ymd[i])
-			}
-		}
-		expectedS := path.Join("blog", "2003", "01", "02")
-		resultS, err := calcPath("blog", ymd)
-		if err != nil {
-			t.Errorf("Unexpected err %s, calcPath()", err)
-		}
-
-		if expectedS != resultS {
-			t.Errorf("expected %q, got %q", expectedS, resultS)
-		}
-	}
-}
-
 func TestExportedFuncs(t *testing.T) {
 	var (
 		pName      string
@@ -17,10 +17,14 @@ import (
 
 	"github.com/rsdoiel/pttk"
 	"github.com/rsdoiel/pttk/blogit"
+	"github.com/rsdoiel/pttk/feed"
 	"github.com/rsdoiel/pttk/frontmatter"
 	"github.com/rsdoiel/pttk/gs"
 	"github.com/rsdoiel/pttk/include"
+	"github.com/rsdoiel/pttk/jsonfeed"
+	"github.com/rsdoiel/pttk/opml"
 	"github.com/rsdoiel/pttk/phlogit"
+	"github.com/rsdoiel/pttk/reader"
 	"github.com/rsdoiel/pttk/rss"
 	"github.com/rsdoiel/pttk/ws"
 )
@@ -77,8 +81,14 @@ with the form ` + "`" + `{app_name} VERB -h` + "`" + `
 **gs**
 : Runs a simple Gopher service for static site development
 
+**gopher**
+: A Gopher client to get, list (ls) or crawl (mirror) a Gopher hole
+
+**finger**
+: Runs a Finger service answering with each user's .plan and .project
+
 **frontmatter**
-: Reads a Pandoc markdown file with frontmatter and write out JSON
+: Reads the YAML, TOML, JSON or Pandoc frontmatter of a markdown file and write out JSON
 
 **blogit**
 : Renders a blog directory structure by "importing" Markdown documents
@@ -96,6 +106,18 @@ metadata and supporting RSS rendering as well as generating gophermap files.
 **rss**
 : Renders RSS feeds from the contents of a blog.json document
 
+**jsonfeed**
+: Renders JSON Feed documents from the contents of a blog.json document
+
+**feed**
+: Converts feeds between RSS 2.0, Atom and JSON Feed
+
+**opml**
+: Reads and writes OPML, converts newsboat urls files and YAML blogrolls to OPML and renders OPML as Markdown
+
+**reader**
+: A feed reader, renders a river of news from an OPML or newsboat subscription list
+
 **sitemap**
 : Renders sitemap.xml files for a static website
 
@@ -170,6 +192,41 @@ Using {app_name} to generate RSS for "myblog"
   {app_name} rss myblog
 ~~~
 
+## jsonfeed verb
+
+Using {app_name} to generate a JSON Feed for "myblog"
+
+~~~shell
+  {app_name} jsonfeed myblog
+~~~
+
+## feed verb
+
+Converting the RSS feed of "myblog" to a JSON Feed
+
+~~~shell
+  {app_name} feed convert myblog/rss.xml myblog/feed.json
+~~~
+
+## opml verb
+
+Sharing newsboat's subscriptions as OPML and rendering an OPML blogroll
+as Markdown
+
+~~~shell
+  {app_name} opml -o feeds.opml $HOME/.newsboat/urls
+  {app_name} opml -to markdown blogroll.opml
+~~~
+
+## reader verb
+
+Reading the feeds listed in "feeds.opml" and writing a river of news
+page "river.html"
+
+~~~shell
+  {app_name} reader -html river.html feeds.opml
+~~~
+
 ## sitemap verb
 
 Generating a sitemap in a current directory (i.e. the "." directory)
@@ -194,6 +251,24 @@ Running a static gopher server to view rendering site
   {app_name} gs $HOME/Sites/myblog
 ~~~
 
+## gopher verb
+
+Listing the menu of a Gopher hole and mirroring it
+
+~~~
+  {app_name} gopher ls gopher://localhost:7000
+  {app_name} gopher crawl gopher://localhost:7000 mirror
+~~~
+
+## finger verb
+
+Serving the ".plan" and ".project" of "$HOME/Sites/finger/USER"
+along with the latest posts in a phlog
+
+~~~
+  {app_name} finger -phlog $HOME/Sites/phlog/phlog.json $HOME/Sites/finger
+~~~
+
 ## include verb
 
 Including a table of contents "toc.md", and "chapters1.md"
@@ -288,6 +363,14 @@ func main() {
 		if err := gs.RunGS(appName, verb, args); err != nil {
 			handleError(eout, err)
 		}
+	case "gopher":
+		if err := gs.RunGopher(appName, verb, args); err != nil {
+			handleError(eout, err)
+		}
+	case "finger":
+		if err := gs.RunFinger(appName, verb, args); err != nil {
+			handleError(eout, err)
+		}
 	case "blogit":
 		if err := blogit.RunBlogIt(appName, verb, args); err != nil {
 			handleError(eout, err)
@@ -306,6 +389,28 @@ func main() {
 		if len(src) > 0 {
 			fmt.Fprintf(out, "%s\n", src)
 		}
+	case "jsonfeed":
+		src, err := jsonfeed.RunJSONFeed(appName, verb, args)
+		handleError(eout, err)
+		if len(src) > 0 {
+			fmt.Fprintf(out, "%s\n", src)
+		}
+	case "feed":
+		src, err := feed.RunFeed(appName, verb, args)
+		handleError(eout, err)
+		if len(src) > 0 {
+			fmt.Fprintf(out, "%s\n", src)
+		}
+	case "opml":
+		src, err := opml.RunOPML(appName, verb, args)
+		handleError(eout, err)
+		if len(src) > 0 {
+			fmt.Fprintf(out, "%s\n", src)
+		}
+	case "reader":
+		if err := reader.RunReader(appName, verb, args); err != nil {
+			handleError(eout, err)
+		}
 	case "include":
 		if err := include.RunInclude(appName, verb, args); err != nil {
 			handleError(eout, err)
@@ -0,0 +1,99 @@
+// pttk is software for working with plain text content.
+// Copyright (C) 2022 R. S. Doiel
+//
+// This program is free software: you can redistribute it and/or modify it under the terms of the GNU Affero General Public License as published by the Free Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License along with this program. If not, see <https://www.gnu.org/licenses/>.
+package collection
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"testing"
+)
+
+func TestPrivateFuncs(t *testing.T) {
+	rsdoiel := "R. S. Doiel"
+	obj := map[string]string{}
+	obj["name"] = rsdoiel
+	objs := append([]interface{}{}, obj)
+	creators := unpackCreators(objs)
+	if len(creators) != 1 {
+		t.Errorf("expected one creator got %d", len(creators))
+	} else {
+		if fmt.Sprintf("%T", creators[0].Name) != "string" {
+			t.Errorf("Expected crestors[0].Name to ba a string, got %T", creators[0].Name)
+		}
+		if strings.Compare(creators[0].Name, rsdoiel) != 0 {
+			t.Errorf("expected creators[0].Name = %q, got %q", rsdoiel, creators[0].Name)
+		}
+	}
+	ymd, err := calcYMD("2003-01-02")
+	if err != nil {
+		t.Errorf("Unexpected err %s, calcYMD()", err)
+		t.FailNow()
+	}
+	if len(ymd) != 3 {
+		t.Errorf("expected len(ymd) = 3, got %+v", ymd)
+	} else {
+		for i, val := range []string{"2003", "01", "02"} {
+			if ymd[i] != val {
+				t.Errorf("expected %q, got %q", val, ymd[i])
+			}
+		}
+		expectedS := path.Join("blog", "2003", "01", "02")
+		resultS, err := calcPath("blog", ymd)
+		if err != nil {
+			t.Errorf("Unexpected err %s, calcPath()", err)
+		}
+
+		if expectedS != resultS {
+			t.Errorf("expected %q, got %q", expectedS, resultS)
+		}
+	}
+}
+
+// testRenderer records the years it was asked to render.
+type testRenderer struct {
+	years []string
+}
+
+func (r *testRenderer) RenderYear(prefix string, yr *YearObj) error {
+	r.years = append(r.years, yr.Year)
+	return nil
+}
+
+func TestRefreshFromPath(t *testing.T) {
+	prefix := t.TempDir()
+	for _, day := range []string{"01", "15"} {
+		dName := path.Join(prefix, "2022", "07", day)
+		os.MkdirAll(dName, 0775)
+		src := []byte(fmt.Sprintf("---\ntitle: Day %s\nkeywords:\n  - test\n---\n\nHello\n", day))
+		if err := os.WriteFile(path.Join(dName, "post.md"), src, 0664); err != nil {
+			t.Errorf("can't create post, %s", err)
+			t.FailNow()
+		}
+	}
+	meta := new(Meta)
+	renderer := new(testRenderer)
+	if err := meta.RefreshFromPath(prefix, "2022", renderer); err != nil {
+		t.Errorf("expected nil, got %s", err)
+		t.FailNow()
+	}
+	if len(renderer.years) != 1 || renderer.years[0] != "2022" {
+		t.Errorf("expected 2022 to be rendered, got %+v", renderer.years)
+	}
+	yr := meta.FindYear("2022")
+	if yr == nil || len(yr.Months) != 1 || len(yr.Months[0].Days) != 2 {
+		t.Errorf("expected one month with two days, got %+v", yr)
+		t.FailNow()
+	}
+	post := yr.Months[0].Days[0].Posts[0]
+	if post.Title == "" || len(post.Keywords) != 1 {
+		t.Errorf("expected a title and keywords from front matter, got %+v", post)
+	}
+}
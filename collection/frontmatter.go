@@ -0,0 +1,57 @@
+// pttk is software for working with plain text content.
+// Copyright (C) 2022 R. S. Doiel
+//
+// This program is free software: you can redistribute it and/or modify it under the terms of the GNU Affero General Public License as published by the Free Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License along with this program. If not, see <https://www.gnu.org/licenses/>.
+package collection
+
+import (
+	"github.com/rsdoiel/pttk/frontmatter"
+)
+
+const (
+
+	//
+	// Supported types for Front Matter, see frontmatter.Type
+	//
+
+	// FrontMatterIsUnknown means front matter and we can't parse it
+	FrontMatterIsUnknown = int(frontmatter.Unknown)
+	// FrontMatterIsJSON means we have detected JSON front matter
+	FrontMatterIsJSON = int(frontmatter.JSON)
+	// FrontMatterIsPandocMetadata means we have detected a Pandoc
+	// style metadata block, e.g. opening lines start with
+	// '%' attribute name followed by value(s)
+	// E.g.
+	//      % title
+	//      % author(s)
+	//      % date
+	FrontMatterIsPandocMetadata = int(frontmatter.PandocMetadata)
+	// FrontMatterIsYAML means we have detected a Pandoc YAML
+	// front matter block.
+	FrontMatterIsYAML = int(frontmatter.YAML)
+	// FrontMatterIsTOML means we have detected a TOML front matter
+	// block delimited by "+++" lines.
+	FrontMatterIsTOML = int(frontmatter.TOML)
+)
+
+// MetadataBlock holds the Pandoc style Metadata block delimited
+// by start '%' at the being of the line in the start of a text file.
+type MetadataBlock = frontmatter.MetadataBlock
+
+// SplitFrontMatter takes a []byte input splits it into front matter type,
+// front matter source and Markdown source. If either is missing an
+// empty []byte is returned for the missing element.
+func SplitFrontMatter(input []byte) (int, []byte, []byte) {
+	fm := frontmatter.Split(input)
+	return int(fm.Type), fm.Source, fm.Body
+}
+
+// UnmarshalFrontMatter takes a []byte of front matter source
+// and unmarshalls it into obj according to its configType.
+func UnmarshalFrontMatter(configType int, src []byte, obj *map[string]interface{}) error {
+	return frontmatter.Unmarshal(frontmatter.Type(configType), src, obj)
+}
@@ -0,0 +1,108 @@
+// pttk is software for working with plain text content.
+// Copyright (C) 2022 R. S. Doiel
+//
+// This program is free software: you can redistribute it and/or modify it under the terms of the GNU Affero General Public License as published by the Free Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License along with this program. If not, see <https://www.gnu.org/licenses/>.
+package feed
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	// My packages
+	"github.com/rsdoiel/pttk/help"
+)
+
+var (
+	// Standard options
+	showHelp bool
+
+	// App specific options
+	outFormat string
+	feedURL   string
+)
+
+func usage(appName string, verb string, exitCode int) {
+	out := os.Stdout
+	if exitCode > 0 {
+		out = os.Stderr
+	}
+	fmt.Fprint(out, help.Render(appName, verb, helpText))
+	os.Exit(exitCode)
+}
+
+// RunFeed implements the feed verb. The "convert" action reads a
+// feed from a file, URL or standard input ("-") and writes it in
+// the format asked for, to OUT when given otherwise it is returned.
+func RunFeed(appName string, verb string, options []string) ([]byte, error) {
+	flagSet := flag.NewFlagSet(appName+":"+verb, flag.ExitOnError)
+
+	// Standard options
+	flagSet.BoolVar(&showHelp, "help", false, "display help")
+
+	// App specific options
+	flagSet.StringVar(&outFormat, "to", "", "format to write, rss, atom or jsonfeed, defaults to the one OUT's name suggests")
+	flagSet.StringVar(&feedURL, "feed-url", "", "set the URL the converted feed is published at")
+
+	flagSet.Parse(options)
+	args := flagSet.Args()
+
+	if showHelp {
+		usage(appName, verb, 0)
+	}
+	if len(args) == 0 {
+		return nil, fmt.Errorf("expected an action, e.g. convert")
+	}
+	action := args[0]
+	if action != "convert" {
+		return nil, fmt.Errorf("unknown action %q, expected convert", action)
+	}
+	// Options may follow the action
+	flagSet.Parse(args[1:])
+	args = flagSet.Args()
+	if len(args) < 1 || len(args) > 2 {
+		return nil, fmt.Errorf("convert expects IN and optionally OUT")
+	}
+	var (
+		src []byte
+		err error
+	)
+	if args[0] == "-" {
+		src, err = io.ReadAll(os.Stdin)
+	} else {
+		src, err = Fetch(args[0])
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Reading %q, %s", args[0], err)
+	}
+	f, _, err := Parse(src)
+	if err != nil {
+		return nil, fmt.Errorf("Parsing %q, %s", args[0], err)
+	}
+	if feedURL != "" {
+		f.FeedURL = feedURL
+	}
+	outName := ""
+	if len(args) == 2 && args[1] != "-" {
+		outName = args[1]
+	}
+	format := outFormat
+	if format == "" {
+		format = FormatOf(outName)
+	}
+	if src, err = f.Marshal(format); err != nil {
+		return nil, err
+	}
+	if outName != "" {
+		if err := os.WriteFile(outName, append(src, '\n'), 0664); err != nil {
+			return nil, fmt.Errorf("Writing %q, %s", outName, err)
+		}
+		return nil, nil
+	}
+	return src, nil
+}
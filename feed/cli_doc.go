@@ -0,0 +1,74 @@
+// pttk is software for working with plain text content.
+// Copyright (C) 2022 R. S. Doiel
+//
+// This program is free software: you can redistribute it and/or modify it under the terms of the GNU Affero General Public License as published by the Free Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License along with this program. If not, see <https://www.gnu.org/licenses/>.
+package feed
+
+const (
+	helpText = `% {app_name}-{verb}(1) {app_name}-{verb} user manual
+% R. S. Doiel
+% October 19, 2026
+
+# NAME
+
+{app_name} {verb}
+
+# SYNOPSIS
+
+{app_name} {verb} convert [OPTIONS] IN [OUT]
+
+# DESCRIPTION
+
+{app_name} {verb} works with feeds in any of the formats pttk
+publishes, RSS 2.0, Atom 1.0 and JSON Feed 1.1.
+
+The "convert" action reads the feed IN, a file, a http(s) URL or
+"-" for standard input, in any of the formats and writes it to OUT
+(standard output when OUT is "-" or missing). The format written is
+set by "-to", otherwise OUT's name decides, ".json" is a JSON Feed,
+".atom" or a name containing "atom" (e.g. "atom.xml") is Atom and
+anything else is RSS.
+
+The feeds are converted through a common model so titles, links,
+dates, authors, tags, summaries, HTML or text content, enclosures
+(JSON Feed attachments, Atom enclosure links), artwork and WebSub
+hubs carry over. What a format can't hold is simplified, RSS keeps
+the first author and enclosure of an item, tags are its categories.
+JSON Feed has no email for an author
+so a "mailto:" URL is used. An RSS item with content:encoded has
+its description as its summary, without it the description is the
+item's content.
+
+# OPTIONS
+
+-feed-url string
+: set the URL the converted feed is published at
+
+-help
+: display help
+
+-to string
+: format to write, rss, atom or jsonfeed, defaults to the one OUT's name suggests
+
+# EXAMPLES
+
+Handing a partner the blog's RSS feed as a JSON Feed
+
+~~~
+	{app_name} {verb} convert \
+	    -feed-url https://blog.example.org/partner.json \
+	    htdocs/rss.xml htdocs/partner.json
+~~~
+
+Converting a remote Atom feed to RSS
+
+~~~
+	{app_name} {verb} convert -to rss https://example.org/atom.xml
+~~~
+
+`
+)
@@ -0,0 +1,287 @@
+// pttk is software for working with plain text content.
+// Copyright (C) 2022 R. S. Doiel
+//
+// This program is free software: you can redistribute it and/or modify it under the terms of the GNU Affero General Public License as published by the Free Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+// Package feed holds the model common to RSS 2.0, Atom 1.0 and JSON
+// Feed 1.1 documents. Each format is read into a Feed, holding what
+// the formats share so authors, enclosures (attachments), tags and
+// content carry over, and written from it. The rss and jsonfeed
+// packages register their formats when imported, Parse and Marshal
+// use the formats registered.
+package feed
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	// My packages
+	"github.com/rsdoiel/pttk"
+)
+
+const (
+	// RSS is the name of the RSS 2.0 format
+	RSS = "rss"
+	// Atom is the name of the Atom 1.0 format
+	Atom = "atom"
+	// JSONFeed is the name of the JSON Feed 1.1 format
+	JSONFeed = "jsonfeed"
+)
+
+var (
+	// Timeout is how long Fetch waits for a feed and a WebSub hub
+	// is given to answer a ping. It is the one timeout used by the
+	// feed, rss, reader and websub packages.
+	Timeout = 30 * time.Second
+
+	// dateLayouts are the dates found in feeds, front matter and HTML
+	// pages. RSS uses RFC 1123 (or RFC 822) dates, Atom and JSON Feed
+	// RFC 3339 and front matter MySQL like dates.
+	dateLayouts = []string{
+		time.RFC3339,
+		time.RFC1123Z,
+		time.RFC1123,
+		time.RFC822Z,
+		time.RFC822,
+		"Mon, 2 Jan 2006 15:04:05 -0700",
+		"Mon, 2 Jan 2006 15:04:05 MST",
+		"2006-01-02T15:04:05",
+		"2006-01-02T15:04",
+		"2006-01-02 15:04:05 -0700",
+		"2006-01-02 15:04:05",
+		"2006-01-02 15:04",
+		"2006-01-02",
+	}
+
+	// formats holds the registered formats by name
+	formats = map[string]*format{}
+)
+
+// format reads and writes a feed format
+type format struct {
+	parse   func([]byte) (*Feed, error)
+	marshal func(*Feed) ([]byte, error)
+}
+
+// Person is the author of a feed or an entry
+type Person struct {
+	Name   string `json:"name,omitempty"`
+	Email  string `json:"email,omitempty"`
+	URL    string `json:"url,omitempty"`
+	Avatar string `json:"avatar,omitempty"`
+}
+
+// Enclosure is a file attached to an entry, e.g. the audio of a
+// podcast episode
+type Enclosure struct {
+	URL    string `json:"url"`
+	Type   string `json:"type,omitempty"`
+	Length int64  `json:"length,omitempty"`
+	Title  string `json:"title,omitempty"`
+	// Duration in seconds
+	Duration int `json:"duration,omitempty"`
+}
+
+// Source is the feed an aggregated entry came from
+type Source struct {
+	Title string `json:"title,omitempty"`
+	URL   string `json:"url"`
+}
+
+// Entry is an item of a feed
+type Entry struct {
+	ID          string `json:"id,omitempty"`
+	Link        string `json:"link,omitempty"`
+	Title       string `json:"title,omitempty"`
+	Summary     string `json:"summary,omitempty"`
+	ContentHTML string `json:"content_html,omitempty"`
+	ContentText string `json:"content_text,omitempty"`
+	Image       string `json:"image,omitempty"`
+	// Published and Updated are RFC 3339 dates
+	Published  string       `json:"published,omitempty"`
+	Updated    string       `json:"updated,omitempty"`
+	Authors    []*Person    `json:"authors,omitempty"`
+	Tags       []string     `json:"tags,omitempty"`
+	Enclosures []*Enclosure `json:"enclosures,omitempty"`
+	Source     *Source      `json:"source,omitempty"`
+}
+
+// Feed is a feed read from, or to be written as, any of the formats
+type Feed struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	// Link is the home page of the site
+	Link string `json:"link,omitempty"`
+	// FeedURL is the URL the feed is published at
+	FeedURL   string `json:"feed_url,omitempty"`
+	Language  string `json:"language,omitempty"`
+	Copyright string `json:"copyright,omitempty"`
+	Generator string `json:"generator,omitempty"`
+	// Icon is the feed's artwork, Favicon its small icon
+	Icon    string `json:"icon,omitempty"`
+	Favicon string `json:"favicon,omitempty"`
+	// Updated is an RFC 3339 date
+	Updated string    `json:"updated,omitempty"`
+	Authors []*Person `json:"authors,omitempty"`
+	Tags    []string  `json:"tags,omitempty"`
+	Hubs    []string  `json:"hubs,omitempty"`
+	Entries []*Entry  `json:"entries"`
+}
+
+// RegisterFormat registers a feed format by name, the function
+// reading a document of the format into a Feed and the one writing
+// a Feed as a document of the format.
+func RegisterFormat(name string, parse func([]byte) (*Feed, error), marshal func(*Feed) ([]byte, error)) {
+	formats[name] = &format{parse: parse, marshal: marshal}
+}
+
+// ParseDate parses the dates found in feeds, front matter and HTML
+// pages, e.g. RFC 1123, RFC 3339 or "2006-01-02 15:04:05 -0700".
+func ParseDate(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	for _, layout := range dateLayouts {
+		if dt, err := time.Parse(layout, s); err == nil {
+			return dt, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("can't parse date %q", s)
+}
+
+// NormalizeDate converts a date ParseDate understands to RFC 3339,
+// an empty string is returned if it can't.
+func NormalizeDate(s string) string {
+	if dt, err := ParseDate(s); err == nil {
+		return dt.Format(time.RFC3339)
+	}
+	return ""
+}
+
+// Fetch reads a feed from a http(s):// or file:// URL or a local
+// file, waiting at most Timeout for a http(s):// URL.
+func Fetch(uri string) ([]byte, error) {
+	u, err := url.Parse(uri)
+	if err != nil || !strings.Contains(uri, "://") {
+		return os.ReadFile(uri)
+	}
+	switch u.Scheme {
+	case "file":
+		return os.ReadFile(u.Path)
+	case "http", "https":
+		client := &http.Client{Timeout: Timeout}
+		req, err := http.NewRequest(http.MethodGet, uri, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("User-Agent", "pttk/"+pttk.Version)
+		res, err := client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		defer res.Body.Close()
+		if res.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("%s", res.Status)
+		}
+		return io.ReadAll(res.Body)
+	}
+	return nil, fmt.Errorf("%q, unsupported scheme %q", uri, u.Scheme)
+}
+
+// Sniff returns the format of a document, JSONFeed for a JSON
+// object, RSS or Atom for XML with an <rss> or <feed> root element.
+// An empty string is returned for anything else.
+func Sniff(src []byte) string {
+	txt := bytes.TrimSpace(src)
+	if bytes.HasPrefix(txt, []byte("{")) {
+		return JSONFeed
+	}
+	decoder := xml.NewDecoder(bytes.NewReader(txt))
+	// Only the element names are needed, whatever the encoding
+	decoder.CharsetReader = func(label string, input io.Reader) (io.Reader, error) {
+		return input, nil
+	}
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return ""
+		}
+		switch elem := token.(type) {
+		case xml.StartElement:
+			switch elem.Name.Local {
+			case "rss":
+				return RSS
+			case "feed":
+				return Atom
+			}
+			return ""
+		case xml.CharData:
+			// Text before the root element isn't XML, e.g. twtxt
+			if len(bytes.TrimSpace(elem)) > 0 {
+				return ""
+			}
+		}
+	}
+}
+
+// Parse reads an RSS 2.0, Atom 1.0 or JSON Feed document returning
+// the feed and the name of the format read.
+func Parse(src []byte) (*Feed, string, error) {
+	name := Sniff(src)
+	if name == "" {
+		return nil, "", fmt.Errorf("not an RSS, Atom or JSON Feed document")
+	}
+	format, ok := formats[name]
+	if !ok {
+		return nil, name, fmt.Errorf("%s isn't a registered feed format", name)
+	}
+	f, err := format.parse(bytes.TrimSpace(src))
+	if err != nil {
+		return nil, name, err
+	}
+	return f, name, nil
+}
+
+// Marshal renders the feed in format, RSS, Atom or JSONFeed.
+func (f *Feed) Marshal(name string) ([]byte, error) {
+	format, ok := formats[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown feed format %q, expected rss, atom or jsonfeed", name)
+	}
+	return format.marshal(f)
+}
+
+// FormatOf guesses the format of a feed from its file name, ".json"
+// is a JSON Feed, ".atom" or a name including "atom" (e.g.
+// "atom.xml") is Atom, otherwise it is RSS.
+func FormatOf(name string) string {
+	base := strings.ToLower(path.Base(name))
+	switch {
+	case path.Ext(base) == ".json":
+		return JSONFeed
+	case strings.Contains(base, "atom"):
+		return Atom
+	}
+	return RSS
+}
+
+// PageName returns the name (or URL) of a page of a feed, page one
+// is name itself, page two of "feed.json" is "feed-2.json", etc.
+func PageName(name string, page int) string {
+	if page <= 1 {
+		return name
+	}
+	ext := path.Ext(name)
+	return strings.TrimSuffix(name, ext) + "-" + strconv.Itoa(page) + ext
+}
@@ -0,0 +1,229 @@
+// pttk is software for working with plain text content.
+// Copyright (C) 2022 R. S. Doiel
+//
+// This program is free software: you can redistribute it and/or modify it under the terms of the GNU Affero General Public License as published by the Free Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License along with this program. If not, see <https://www.gnu.org/licenses/>.
+package feed_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	// My packages
+	"github.com/rsdoiel/pttk/feed"
+	_ "github.com/rsdoiel/pttk/jsonfeed"
+	_ "github.com/rsdoiel/pttk/rss"
+)
+
+func TestConvert(t *testing.T) {
+	src := []byte(`{
+    "version": "https://jsonfeed.org/version/1.1",
+    "title": "The Show",
+    "home_page_url": "https://example.org/",
+    "feed_url": "https://example.org/feed.json",
+    "description": "A podcast about plain text",
+    "language": "en-US",
+    "icon": "https://example.org/artwork.png",
+    "hubs": [ { "type": "WebSub", "url": "https://hub.example.org/" } ],
+    "items": [
+        {
+            "id": "https://example.org/episodes/1.html",
+            "url": "https://example.org/episodes/1.html",
+            "title": "Episode 1",
+            "summary": "We talk about <em>Markdown</em>",
+            "content_html": "<p>We talk about <em>Markdown</em> &amp; more.</p>",
+            "date_published": "2022-08-02T10:00:00-07:00",
+            "authors": [ { "name": "Jane Doe", "url": "mailto:jane@example.org" } ],
+            "tags": [ "markdown", "plain text" ],
+            "attachments": [
+                {
+                    "url": "https://example.org/episodes/1.mp3",
+                    "mime_type": "audio/mpeg",
+                    "size_in_bytes": 1234,
+                    "duration_in_seconds": 3723
+                }
+            ]
+        }
+    ]
+}`)
+	f, format, err := feed.Parse(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if format != feed.JSONFeed {
+		t.Errorf("expected %q, got %q", feed.JSONFeed, format)
+	}
+	// JSON Feed to RSS to Atom and back to JSON Feed
+	for _, to := range []string{feed.RSS, feed.Atom, feed.JSONFeed} {
+		out, err := f.Marshal(to)
+		if err != nil {
+			t.Fatalf("%s, %s", to, err)
+		}
+		if to == feed.RSS {
+			for _, s := range []string{
+				`<atom:link href="https://example.org/feed.json" rel="self" type="application/rss+xml"/>`,
+				`<author>jane@example.org (Jane Doe)</author>`,
+				`<category>markdown</category>`,
+				`<category>plain text</category>`,
+				`<image>`,
+				`<enclosure url="https://example.org/episodes/1.mp3" length="1234" type="audio/mpeg"/>`,
+				`<itunes:duration>1:02:03</itunes:duration>`,
+				`<itunes:image href="https://example.org/artwork.png"/>`,
+				`<pubDate>Tue, 02 Aug 2022 10:00:00 -0700</pubDate>`,
+			} {
+				if !bytes.Contains(out, []byte(s)) {
+					t.Errorf("expected %s in\n%s", s, out)
+				}
+			}
+		}
+		if f, format, err = feed.Parse(out); err != nil {
+			t.Fatalf("%s, %s\n%s", to, err, out)
+		}
+		if format != to {
+			t.Errorf("expected %q, got %q", to, format)
+		}
+	}
+	if f.Title != "The Show" || f.Link != "https://example.org/" || f.FeedURL != "https://example.org/feed.json" || f.Language != "en-US" || f.Icon != "https://example.org/artwork.png" {
+		t.Errorf("unexpected feed %+v", f)
+	}
+	if len(f.Hubs) != 1 || f.Hubs[0] != "https://hub.example.org/" {
+		t.Errorf("expected the hub to carry over, got %+v", f.Hubs)
+	}
+	if len(f.Entries) != 1 {
+		t.Fatalf("expected one entry, got %d", len(f.Entries))
+	}
+	entry := f.Entries[0]
+	if entry.ID != "https://example.org/episodes/1.html" || entry.Title != "Episode 1" {
+		t.Errorf("unexpected entry %+v", entry)
+	}
+	if entry.Published != "2022-08-02T10:00:00-07:00" {
+		t.Errorf("expected the date to carry over, got %q", entry.Published)
+	}
+	if entry.Summary != "We talk about <em>Markdown</em>" || entry.ContentHTML != "<p>We talk about <em>Markdown</em> &amp; more.</p>" {
+		t.Errorf("expected the summary and content to carry over, got %q and %q", entry.Summary, entry.ContentHTML)
+	}
+	if len(entry.Authors) != 1 || entry.Authors[0].Name != "Jane Doe" || entry.Authors[0].Email != "jane@example.org" {
+		t.Errorf("expected the author to carry over, got %+v", entry.Authors)
+	}
+	if strings.Join(entry.Tags, "|") != "markdown|plain text" {
+		t.Errorf("expected the tags to carry over, got %+v", entry.Tags)
+	}
+	if len(entry.Enclosures) != 1 {
+		t.Fatalf("expected one enclosure, got %+v", entry.Enclosures)
+	}
+	// Atom has no duration for its enclosures
+	if enclosure := entry.Enclosures[0]; enclosure.URL != "https://example.org/episodes/1.mp3" || enclosure.Type != "audio/mpeg" || enclosure.Length != 1234 {
+		t.Errorf("unexpected enclosure %+v", enclosure)
+	}
+}
+
+func TestAtomContent(t *testing.T) {
+	src := []byte(`<feed xmlns="http://www.w3.org/2005/Atom"><title>Notes</title><id>tag:example.org,2022:notes</id><updated>2022-08-03T10:00:00Z</updated>
+<link href="https://example.org/"/><author><name>Bea</name><uri>https://example.org/bea</uri></author>
+<entry><title>Text</title><id>tag:example.org,2022:1</id><updated>2022-08-03T10:00:00Z</updated><content>1 &lt; 2</content><category term="math"/></entry>
+</feed>`)
+	f, format, err := feed.Parse(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if format != feed.Atom || f.Link != "https://example.org/" || len(f.Authors) != 1 || f.Authors[0].URL != "https://example.org/bea" {
+		t.Errorf("unexpected %s feed %+v", format, f)
+	}
+	if entry := f.Entries[0]; entry.ContentText != "1 < 2" || entry.ContentHTML != "" || entry.Tags[0] != "math" {
+		t.Errorf("unexpected entry %+v", entry)
+	}
+	out, err := f.Marshal(feed.JSONFeed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(out, []byte(`"content_text": "1 \u003c 2"`)) || !bytes.Contains(out, []byte(`"date_modified": "2022-08-03T10:00:00Z"`)) {
+		t.Errorf("unexpected JSON Feed\n%s", out)
+	}
+	if out, err = f.Marshal(feed.RSS); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(out, []byte(`<description>&lt;p&gt;1 &amp;lt; 2&lt;/p&gt;</description>`)) {
+		t.Errorf("expected text content as HTML, got\n%s", out)
+	}
+	if _, err := f.Marshal("html"); err == nil {
+		t.Errorf("expected an error for an unknown format")
+	}
+	for name, expected := range map[string]string{"feed.json": feed.JSONFeed, "htdocs/atom.xml": feed.Atom, "news.atom": feed.Atom, "rss.xml": feed.RSS, "": feed.RSS} {
+		if format := feed.FormatOf(name); format != expected {
+			t.Errorf("expected %q for %q, got %q", expected, name, format)
+		}
+	}
+}
+
+func TestDates(t *testing.T) {
+	for s, expected := range map[string]string{
+		"Tue, 02 Aug 2022 10:00:00 -0700": "2022-08-02T10:00:00-07:00",
+		"Tue, 2 Aug 2022 10:00:00 GMT":    "2022-08-02T10:00:00Z",
+		"2022-08-02T10:00:00-07:00":       "2022-08-02T10:00:00-07:00",
+		"2022-08-02 10:00:00 -0700":       "2022-08-02T10:00:00-07:00",
+		"2022-08-02 10:00":                "2022-08-02T10:00:00Z",
+		" 2022-08-02 ":                    "2022-08-02T00:00:00Z",
+		"last Tuesday":                    "",
+	} {
+		if got := feed.NormalizeDate(s); got != expected {
+			t.Errorf("expected %q for %q, got %q", expected, s, got)
+		}
+	}
+	dt, err := feed.ParseDate("Tue, 02 Aug 2022 10:00:00 +0000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !dt.Equal(time.Date(2022, time.August, 2, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected date %s", dt)
+	}
+	if _, err := feed.ParseDate("last Tuesday"); err == nil {
+		t.Errorf("expected an error for an unknown date")
+	}
+}
+
+func TestSniff(t *testing.T) {
+	for src, expected := range map[string]string{
+		` { "version": "https://jsonfeed.org/version/1.1" }`:                   feed.JSONFeed,
+		`<?xml version="1.0" encoding="ISO-8859-1"?><rss version="2.0"></rss>`: feed.RSS,
+		`<!-- <rss> --><feed xmlns="http://www.w3.org/2005/Atom"></feed>`:      feed.Atom,
+		`<!DOCTYPE html><html><body><a href="feed.xml">rss</a></body></html>`:  "",
+		"2022-08-02T10:00:00Z\tA twtxt note about <rss>":                       "",
+	} {
+		if got := feed.Sniff([]byte(src)); got != expected {
+			t.Errorf("expected %q for %s, got %q", expected, src, got)
+		}
+	}
+}
+
+func TestMediaType(t *testing.T) {
+	for name, expected := range map[string]string{
+		"episode.MP3":       "audio/mpeg",
+		"episode.opus":      "audio/opus",
+		"clip.webm":         "video/webm",
+		"episode.vtt":       "text/vtt",
+		"chapters.json":     "application/json",
+		"notes.unknown-ext": "application/octet-stream",
+	} {
+		if got := feed.MediaType(name); got != expected {
+			t.Errorf("%s, expected %q, got %q", name, expected, got)
+		}
+	}
+}
+
+func TestPageName(t *testing.T) {
+	for page, expected := range map[int]string{
+		0: "feed.json",
+		1: "feed.json",
+		2: "feed-2.json",
+		3: "feed-3.json",
+	} {
+		if got := feed.PageName("feed.json", page); got != expected {
+			t.Errorf("page %d, expected %q, got %q", page, expected, got)
+		}
+	}
+}
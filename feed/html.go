@@ -0,0 +1,46 @@
+// pttk is software for working with plain text content.
+// Copyright (C) 2022 R. S. Doiel
+//
+// This program is free software: you can redistribute it and/or modify it under the terms of the GNU Affero General Public License as published by the Free Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License along with this program. If not, see <https://www.gnu.org/licenses/>.
+package feed
+
+import (
+	"os"
+	"path"
+	"regexp"
+	"strings"
+)
+
+var (
+	// articleExp and bodyExp find the content of an HTML page, an
+	// article is preferred over the whole body.
+	articleExp = regexp.MustCompile(`(?is)<article[^>]*>(.*)</article>`)
+	bodyExp    = regexp.MustCompile(`(?is)<body[^>]*>(.*)</body>`)
+)
+
+// RenderedHTML returns the content of the HTML page rendered from a
+// Markdown document, e.g. "post.html" for "post.md". An empty string
+// is returned if there isn't one.
+func RenderedHTML(fName string) string {
+	src, err := os.ReadFile(strings.TrimSuffix(fName, path.Ext(fName)) + ".html")
+	if err != nil {
+		return ""
+	}
+	return HTMLContent(src)
+}
+
+// HTMLContent returns the content of an HTML page, its article or
+// body, or the page itself.
+func HTMLContent(src []byte) string {
+	if m := articleExp.FindSubmatch(src); m != nil {
+		return strings.TrimSpace(string(m[1]))
+	}
+	if m := bodyExp.FindSubmatch(src); m != nil {
+		return strings.TrimSpace(string(m[1]))
+	}
+	return strings.TrimSpace(string(src))
+}
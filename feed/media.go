@@ -0,0 +1,48 @@
+// pttk is software for working with plain text content.
+// Copyright (C) 2022 R. S. Doiel
+//
+// This program is free software: you can redistribute it and/or modify it under the terms of the GNU Affero General Public License as published by the Free Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License along with this program. If not, see <https://www.gnu.org/licenses/>.
+package feed
+
+import (
+	"mime"
+	"path"
+	"strings"
+)
+
+var (
+	// mediaTypes is used before the system's MIME types so the
+	// types in a feed don't depend on the machine it is built on.
+	mediaTypes = map[string]string{
+		".mp3":  "audio/mpeg",
+		".m4a":  "audio/x-m4a",
+		".ogg":  "audio/ogg",
+		".oga":  "audio/ogg",
+		".opus": "audio/opus",
+		".flac": "audio/flac",
+		".wav":  "audio/wav",
+		".mp4":  "video/mp4",
+		".m4v":  "video/x-m4v",
+		".webm": "video/webm",
+		".vtt":  "text/vtt",
+		".srt":  "application/x-subrip",
+		".json": "application/json",
+	}
+)
+
+// MediaType returns the MIME type of a file, e.g. an attachment or
+// enclosure, based on its extension.
+func MediaType(fName string) string {
+	ext := strings.ToLower(path.Ext(fName))
+	if mediaType, ok := mediaTypes[ext]; ok {
+		return mediaType
+	}
+	if mediaType := mime.TypeByExtension(ext); mediaType != "" {
+		return mediaType
+	}
+	return "application/octet-stream"
+}
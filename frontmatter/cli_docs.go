@@ -26,6 +26,10 @@ pttk frontmatter [OPTIONS] [INPUT_FILENAME] [OUTPUT_FILENAME]
 The frontmatter action allows you to extract metadata from a
 text document that uses the Markdown style frontmatter. By default
 it will return the documents frontmatter as a JSON structure.
+The frontmatter may be a YAML block between "---" lines, a TOML
+block between "+++" lines (e.g. Hugo posts), a JSON object or a
+Pandoc title block, the "%" lines holding the title, author(s)
+and date.
 If you include a root level attribute name then you can extract
 the value as a unquoted string. By default frontmatter can read
 from standard input but if you provide a filename it'll ready
@@ -53,6 +57,12 @@ when combined with Stephen Dolan's jq.
 pttk frontmatter mypost.md | jq .title
 ~~~
 
+A Hugo post with TOML frontmatter is read the same way.
+
+~~~
+pttk frontmatter content/posts/hello.md | jq .tags
+~~~
+
 
 `
 )
@@ -19,116 +19,279 @@ import (
 	"gopkg.in/yaml.v3"
 )
 
+// Type is the format of a front matter block. The values match
+// collection's FrontMatterIs constants.
+type Type int
+
+const (
+	// Unknown means no front matter was found
+	Unknown Type = iota
+	// JSON is a JSON object starting the document
+	JSON
+	// PandocMetadata is a Pandoc title block, lines starting
+	// with '%' holding the title, author(s) and date.
+	// E.g.
+	//      % title
+	//      % author(s)
+	//      % date
+	PandocMetadata
+	// YAML is a block delimited by "---" lines
+	YAML
+	// TOML is a block delimited by "+++" lines, e.g. Hugo's posts
+	TOML
+)
+
 var (
-	LF      = byte(10)
-	sMarker = "---\n"
-	bMarker = []byte(sMarker)
+	LF = byte(10)
+
+	yamlMarker = "---"
+	tomlMarker = "+++"
 )
 
-// extractFrontmatter
-func extractFrontmatter(src []byte) ([]byte, error) {
-	out := bytes.Buffer{}
-	if bytes.HasPrefix(src, bMarker) {
-		src = bytes.TrimPrefix(src, bMarker)
-		buf := bytes.NewBuffer(src)
-		inFrontmatter := true
-		line, err := buf.ReadString(LF)
-		for (err != io.EOF) && inFrontmatter {
-			if strings.Compare(line, "---\n") == 0 {
-				inFrontmatter = false
+// String returns the name of the front matter format.
+func (t Type) String() string {
+	switch t {
+	case JSON:
+		return "json"
+	case PandocMetadata:
+		return "pandoc"
+	case YAML:
+		return "yaml"
+	case TOML:
+		return "toml"
+	}
+	return "unknown"
+}
+
+// FrontMatter is the metadata block found at the start of a document.
+type FrontMatter struct {
+	// Type is the format of the block
+	Type Type
+	// Source is the block as found, including its delimiters
+	Source []byte
+	// Body is the rest of the document
+	Body []byte
+}
+
+// MetadataBlock holds the Pandoc style Metadata block delimited
+// by start '%' at the being of the line in the start of a text file.
+type MetadataBlock struct {
+	Title   string   `json:"title"`
+	Authors []string `json:"authors"`
+	Date    string   `json:"date"`
+}
+
+func (block *MetadataBlock) String() string {
+	return fmt.Sprintf("%% %s\n%% %s\n%% %s", block.Title, strings.Join(block.Authors, "; "), block.Date)
+}
+
+func (block *MetadataBlock) Marshal() ([]byte, error) {
+	return json.Marshal(block)
+}
+
+// Unmarshal populates a MetadataBlock from the Pandoc metadata source.
+func (block *MetadataBlock) Unmarshal(src []byte) error {
+	lines := bytes.Split(src, []byte("\n"))
+	fieldCnt := 0
+	key := ""
+	block.Title = ""
+	block.Authors = []string{}
+	block.Date = ""
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		if bytes.HasPrefix(line, []byte("% ")) {
+			fieldCnt += 1
+			switch fieldCnt {
+			case 1:
+				key = "title"
+			case 2:
+				key = "authors"
+			case 3:
+				key = "date"
+			default:
+				key = ""
 			}
-			if inFrontmatter {
-				if _, err := out.Write([]byte(line)); err != nil {
-					return nil, fmt.Errorf("save buffer failed, %s\n", err)
+			line = bytes.TrimPrefix(line, []byte("% "))
+		}
+		if (len(key) > 0) && (fieldCnt <= 3) {
+			switch key {
+			case "title":
+				if len(block.Title) > 0 {
+					block.Title = fmt.Sprintf("%s\n%s", block.Title, bytes.TrimSpace(line))
+				} else {
+					block.Title = fmt.Sprintf("%s", bytes.TrimSpace(line))
+				}
+			case "authors":
+				if bytes.Contains(line, []byte(";")) {
+					parts := bytes.Split(line, []byte(";"))
+					for _, part := range parts {
+						block.Authors = append(block.Authors, fmt.Sprintf("%s", bytes.TrimSpace(part)))
+					}
+				} else {
+					block.Authors = append(block.Authors, fmt.Sprintf("%s", bytes.TrimSpace(line)))
 				}
+			case "date":
+				block.Date = fmt.Sprintf("%s", bytes.TrimSpace(line))
+				key = ""
 			}
-			line, err = buf.ReadString(LF)
 		}
 	}
-	return out.Bytes(), nil
+	if fieldCnt != 3 {
+		return fmt.Errorf("Missing or ill formed metablock, expecting title, author(s), date")
+	}
+	return nil
 }
 
-// hasFrontmatter returns true if frontmatter if found at
-// start of buffer, false otherwise.
-func hasFrontmatter(src []byte) bool {
-	// Make a copy of buffer contents, so we can see if
-	// we have frontmatter or not.
-	return bytes.HasPrefix(src, bMarker)
+// splitDelimited splits a block opened and closed by marker lines
+// from the rest of src. An unclosed block runs to the end of src.
+func splitDelimited(src []byte, marker string) ([]byte, []byte) {
+	closing := []byte("\n" + marker + "\n")
+	rest := src[len(marker):]
+	if i := bytes.Index(rest, closing); i >= 0 {
+		end := len(marker) + i + len(closing)
+		return src[:end], src[end:]
+	}
+	return src, []byte{}
 }
 
-// TrimFrontmatter returns the contents of an io buffer removing
-// any FrontMatter found.
-func TrimFrontmatter(buf io.Reader) ([]byte, error) {
-	src, err := io.ReadAll(buf)
-	// Check to see if we have frontmatter
-	if err == nil && hasFrontmatter(src) {
-		in := bytes.NewBuffer(src)
-		// Read rest of frontmatter until closing
-		inFrontmatter := true
-		// Skip our open frontmatter marker
-		line, err := in.ReadString(LF)
-		// Find the closing frontmatter marker
-		for (err != io.EOF) && inFrontmatter {
-			line, err = in.ReadString(LF)
-			if err == nil && strings.Compare(line, sMarker) == 0 {
-				inFrontmatter = false
+// Split detects the front matter at the start of src, YAML ("---"),
+// TOML ("+++"), JSON ("{") or a Pandoc title block ("%"), and
+// splits it from the body. If there is none Type is Unknown and Body
+// holds all of src.
+func Split(src []byte) *FrontMatter {
+	fm := &FrontMatter{Type: Unknown, Source: []byte{}, Body: src}
+	switch {
+	case bytes.HasPrefix(src, []byte(yamlMarker+"\n")):
+		fm.Type = YAML
+		fm.Source, fm.Body = splitDelimited(src, yamlMarker)
+	case bytes.HasPrefix(src, []byte(tomlMarker+"\n")):
+		fm.Type = TOML
+		fm.Source, fm.Body = splitDelimited(src, tomlMarker)
+	case bytes.HasPrefix(src, []byte("{")):
+		dec := json.NewDecoder(bytes.NewReader(src))
+		obj := json.RawMessage{}
+		if err := dec.Decode(&obj); err == nil {
+			end := int(dec.InputOffset())
+			if end < len(src) && src[end] == LF {
+				end++
+			}
+			fm.Type = JSON
+			fm.Source, fm.Body = src[:end], src[end:]
+		} else if bytes.HasPrefix(src, []byte("{\n")) {
+			// Keep the broken object so Unmarshal can report it.
+			fm.Type = JSON
+			if i := bytes.Index(src, []byte("\n}\n")); i >= 0 {
+				fm.Source, fm.Body = src[:i+3], src[i+3:]
+			} else {
+				fm.Source, fm.Body = src, []byte{}
 			}
 		}
-		// Now copy the rest of the document to out buffer
-		out := bytes.Buffer{}
-		for err != io.EOF {
-			line, err = in.ReadString(LF)
-			if err == nil {
-				out.Write([]byte(line))
+	case bytes.HasPrefix(src, []byte("% ")):
+		lines := bytes.SplitAfter(src, []byte("\n"))
+		fieldCnt, end := 0, 0
+		for i := 0; (i < len(lines)) && (fieldCnt < 3); i++ {
+			//NOTE: Dates can only one line, so we stop extra
+			// line consumption with authors.
+			if bytes.HasPrefix(lines[i], []byte("% ")) {
+				fieldCnt += 1
 			}
+			end += len(lines[i])
+		}
+		if fieldCnt == 3 {
+			fm.Type = PandocMetadata
+			fm.Source, fm.Body = src[:end], src[end:]
 		}
-		return out.Bytes(), nil
 	}
-	// No frontmatter found, return buf as slice of bytes
-	return src, err
+	return fm
 }
 
-// ReadFile reads a file an extracts front matter converting it to an
-// JSON document.
-func ReadFile(fName string) ([]byte, error) {
-	txt, err := os.ReadFile(fName)
-	if err != nil {
-		return nil, err
+// Unmarshal parses front matter source of type t into obj.
+func Unmarshal(t Type, src []byte, obj *map[string]interface{}) error {
+	switch t {
+	case PandocMetadata:
+		block := MetadataBlock{}
+		if err := block.Unmarshal(src); err != nil {
+			return err
+		}
+		txt, err := block.Marshal()
+		if err != nil {
+			return err
+		}
+		return json.Unmarshal(txt, obj)
+	case JSON:
+		return json.Unmarshal(src, obj)
+	case YAML:
+		return yaml.Unmarshal(src, obj)
+	case TOML:
+		src = bytes.TrimPrefix(src, []byte(tomlMarker+"\n"))
+		src = bytes.TrimSuffix(src, []byte(tomlMarker+"\n"))
+		m, err := parseTOML(src)
+		if err != nil {
+			return err
+		}
+		if *obj == nil {
+			*obj = map[string]interface{}{}
+		}
+		for k, v := range m {
+			(*obj)[k] = v
+		}
+		return nil
 	}
-	fmText, err := extractFrontmatter(txt)
+	return fmt.Errorf("Unsupported Front matter format")
+}
+
+// Map returns the front matter as a map[string]interface{}.
+func (fm *FrontMatter) Map() (map[string]interface{}, error) {
+	obj := map[string]interface{}{}
+	if err := Unmarshal(fm.Type, fm.Source, &obj); err != nil {
+		return nil, fmt.Errorf("%s front matter, %s", fm.Type, err)
+	}
+	return obj, nil
+}
+
+// toJSON converts the front matter of a document to a JSON document.
+// It returns nil if the document has no front matter.
+func toJSON(txt []byte) ([]byte, error) {
+	fm := Split(txt)
+	if fm.Type == Unknown {
+		return nil, nil
+	}
+	obj, err := fm.Map()
 	if err != nil {
 		return nil, err
 	}
-	// Convert fmText YAML to JSON
-	if len(fmText) > 0 {
-		obj := map[string]*interface{}{}
-		err := yaml.Unmarshal(fmText, &obj)
-		if err != nil {
-			return nil, err
-		}
-		return json.MarshalIndent(obj, "", "    ")
+	if len(obj) == 0 {
+		return nil, nil
 	}
-	return nil, nil
+	return json.MarshalIndent(obj, "", "    ")
 }
 
-// ReadAll reads a io buffer and extracts the frontmatter from it.
-func ReadAll(buf io.Reader) ([]byte, error) {
-	txt, err := io.ReadAll(buf)
+// TrimFrontmatter returns the contents of an io buffer removing
+// any FrontMatter found.
+func TrimFrontmatter(buf io.Reader) ([]byte, error) {
+	src, err := io.ReadAll(buf)
 	if err != nil {
 		return nil, err
 	}
-	fmText, err := extractFrontmatter(txt)
+	return Split(src).Body, nil
+}
+
+// ReadFile reads a file an extracts front matter converting it to an
+// JSON document.
+func ReadFile(fName string) ([]byte, error) {
+	txt, err := os.ReadFile(fName)
 	if err != nil {
 		return nil, err
 	}
-	// Convert fmText YAML to JSON
-	if len(fmText) > 0 {
-		obj := map[string]*interface{}{}
-		err := yaml.Unmarshal(fmText, &obj)
-		if err != nil {
-			return nil, err
-		}
-		return json.MarshalIndent(obj, "", "    ")
+	return toJSON(txt)
+}
+
+// ReadAll reads a io buffer and extracts the frontmatter from it
+// converting it to a JSON document.
+func ReadAll(buf io.Reader) ([]byte, error) {
+	txt, err := io.ReadAll(buf)
+	if err != nil {
+		return nil, err
 	}
-	return nil, nil
+	return toJSON(txt)
 }
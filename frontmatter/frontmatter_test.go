@@ -12,8 +12,10 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"reflect"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestFrontmatter(t *testing.T) {
@@ -83,3 +85,147 @@ Bye.
 		t.FailNow()
 	}
 }
+
+func TestSplit(t *testing.T) {
+	body := "\nA post\n======\n\nThis is a blog post body.\n"
+	docs := []struct {
+		fmType Type
+		src    string
+		title  string
+	}{
+		{YAML, "---\ntitle: A post\nbyline: R. S. Doiel\n---\n", "A post"},
+		{TOML, "+++\ntitle = \"A post\"\nbyline = 'R. S. Doiel'\n+++\n", "A post"},
+		{JSON, "{\n    \"title\": \"A post\",\n    \"byline\": \"R. S. Doiel\"\n}\n", "A post"},
+		{JSON, "{\"title\": \"A {braced} post\"}\n", "A {braced} post"},
+		{PandocMetadata, "% A post\n% R. S. Doiel\n% 2022-10-31\n", "A post"},
+		{Unknown, "", ""},
+	}
+	for _, doc := range docs {
+		fm := Split([]byte(doc.src + body))
+		if fm.Type != doc.fmType {
+			t.Errorf("expected %s, got %s for %q", doc.fmType, fm.Type, doc.src)
+			continue
+		}
+		if string(fm.Source) != doc.src {
+			t.Errorf("expected source %q, got %q", doc.src, fm.Source)
+		}
+		if string(fm.Body) != body {
+			t.Errorf("%s, expected body %q, got %q", doc.fmType, body, fm.Body)
+		}
+		src, err := ReadAll(strings.NewReader(doc.src + body))
+		if err != nil {
+			t.Errorf("%s, ReadAll error %s", doc.fmType, err)
+			continue
+		}
+		if doc.fmType == Unknown {
+			if src != nil {
+				t.Errorf("expected no front matter, got %s", src)
+			}
+			continue
+		}
+		obj := map[string]interface{}{}
+		if err := json.Unmarshal(src, &obj); err != nil {
+			t.Errorf("%s, ReadAll returned %s, %s", doc.fmType, src, err)
+			continue
+		}
+		if obj["title"] != doc.title {
+			t.Errorf("%s, expected title %q, got %v", doc.fmType, doc.title, obj["title"])
+		}
+	}
+
+	// A broken JSON object is still JSON front matter so the
+	// error is reported.
+	if _, err := ReadAll(strings.NewReader("{\n    \"title\": \"A post\n}\n" + body)); err == nil {
+		t.Errorf("expected an error for broken JSON front matter")
+	}
+}
+
+func TestTOML(t *testing.T) {
+	src := []byte(`+++
+# Hugo style front matter
+title = "A \"quoted\" title\u00e9"
+path = 'C:\posts'
+draft = false
+weight = 1_000
+ratio = 0.5
+date = 2022-10-31
+lastmod = 1979-05-27T07:32:00-08:00
+published = 1979-05-27 07:32:00
+at = 07:32:00
+tags = [
+    "go",  # a comment
+    "toml",
+]
+description = """
+Roses are red \
+    violets are blue"""
+author.name = "R. S. Doiel"
+point = { x = 1, y = 2 }
+
+[params]
+series = "Notes"
+"quoted key" = true
+
+[params.extra]
+level = 2
+
+[[links]]
+href = "https://example.org"
+
+[[links]]
+href = "https://example.net"
++++
+`)
+	fm := Split(src)
+	if fm.Type != TOML {
+		t.Fatalf("expected toml, got %s", fm.Type)
+	}
+	obj, err := fm.Map()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]interface{}{
+		"title":       "A \"quoted\" title\u00e9",
+		"path":        `C:\posts`,
+		"draft":       false,
+		"weight":      1000,
+		"ratio":       0.5,
+		"date":        time.Date(2022, 10, 31, 0, 0, 0, 0, time.UTC),
+		"published":   time.Date(1979, 5, 27, 7, 32, 0, 0, time.UTC),
+		"at":          "07:32:00",
+		"tags":        []interface{}{"go", "toml"},
+		"description": "Roses are red violets are blue",
+		"author":      map[string]interface{}{"name": "R. S. Doiel"},
+		"point":       map[string]interface{}{"x": 1, "y": 2},
+		"params": map[string]interface{}{
+			"series":     "Notes",
+			"quoted key": true,
+			"extra":      map[string]interface{}{"level": 2},
+		},
+		"links": []interface{}{
+			map[string]interface{}{"href": "https://example.org"},
+			map[string]interface{}{"href": "https://example.net"},
+		},
+	}
+	for k, v := range expected {
+		if !reflect.DeepEqual(obj[k], v) {
+			t.Errorf("%s, expected %#v, got %#v", k, v, obj[k])
+		}
+	}
+	if dt, ok := obj["lastmod"].(time.Time); !ok || dt.UTC() != time.Date(1979, 5, 27, 15, 32, 0, 0, time.UTC) {
+		t.Errorf("lastmod, expected 1979-05-27T07:32:00-08:00, got %v", obj["lastmod"])
+	}
+
+	for _, bad := range []string{
+		"title = \"unterminated\n",
+		"title = \"A\"\ntitle = \"B\"\n",
+		"[params]\n[params]\n",
+		"title \"no equals\"\n",
+		"tags = [\"a\" \"b\"]\n",
+		"weight = 12abc\n",
+	} {
+		if _, err := parseTOML([]byte(bad)); err == nil {
+			t.Errorf("expected an error parsing %q", bad)
+		}
+	}
+}
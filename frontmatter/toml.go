@@ -0,0 +1,535 @@
+// pttk is software for working with plain text content.
+// Copyright (C) 2022 R. S. Doiel
+//
+// This program is free software: you can redistribute it and/or modify it under the terms of the GNU Affero General Public License as published by the Free Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License along with this program. If not, see <https://www.gnu.org/licenses/>.
+package frontmatter
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// tomlParser is a small TOML v1.0 reader, enough for the front matter
+// of Hugo style posts. Tables become map[string]interface{}, arrays
+// []interface{}, integers int, floats float64, dates and date times
+// time.Time (like YAML's timestamps) and local times strings.
+type tomlParser struct {
+	src  string
+	pos  int
+	root map[string]interface{}
+	// current is the table set by the last [table] or [[table]] header
+	current map[string]interface{}
+	// headers records the tables defined by a header
+	headers map[string]bool
+}
+
+var (
+	tomlLocalDate = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`)
+	tomlLocalTime = regexp.MustCompile(`^\d{2}:\d{2}(:\d{2}(\.\d+)?)?$`)
+)
+
+// parseTOML parses TOML source returning its root table.
+func parseTOML(src []byte) (map[string]interface{}, error) {
+	p := &tomlParser{
+		src:     string(src),
+		root:    map[string]interface{}{},
+		headers: map[string]bool{},
+	}
+	p.current = p.root
+	for {
+		p.skipBlank(true)
+		if p.eof() {
+			break
+		}
+		var err error
+		if p.peek() == '[' {
+			err = p.parseHeader()
+		} else {
+			err = p.parseKeyValue(p.current)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err := p.endOfLine(); err != nil {
+			return nil, err
+		}
+	}
+	return p.root, nil
+}
+
+// errorf reports an error at the current line.
+func (p *tomlParser) errorf(format string, args ...interface{}) error {
+	line := strings.Count(p.src[:p.pos], "\n") + 1
+	return fmt.Errorf("TOML line %d, %s", line, fmt.Sprintf(format, args...))
+}
+
+func (p *tomlParser) eof() bool {
+	return p.pos >= len(p.src)
+}
+
+func (p *tomlParser) peek() byte {
+	if p.eof() {
+		return 0
+	}
+	return p.src[p.pos]
+}
+
+// skipBlank skips spaces, tabs and comments, with newlines
+// when multiline is true.
+func (p *tomlParser) skipBlank(multiline bool) {
+	for !p.eof() {
+		switch c := p.peek(); {
+		case c == ' ' || c == '\t':
+			p.pos++
+		case c == '#':
+			for !p.eof() && p.peek() != '\n' {
+				p.pos++
+			}
+		case multiline && (c == '\n' || c == '\r'):
+			p.pos++
+		default:
+			return
+		}
+	}
+}
+
+// endOfLine expects only blanks or a comment before the next line.
+func (p *tomlParser) endOfLine() error {
+	p.skipBlank(false)
+	if p.eof() {
+		return nil
+	}
+	if strings.HasPrefix(p.src[p.pos:], "\r\n") {
+		p.pos += 2
+		return nil
+	}
+	if p.peek() == '\n' {
+		p.pos++
+		return nil
+	}
+	return p.errorf("unexpected %q after value", p.peek())
+}
+
+// parseHeader reads a [table] or [[array of tables]] header.
+func (p *tomlParser) parseHeader() error {
+	isArray := strings.HasPrefix(p.src[p.pos:], "[[")
+	if isArray {
+		p.pos += 2
+	} else {
+		p.pos++
+	}
+	p.skipBlank(false)
+	keys, err := p.parseKey()
+	if err != nil {
+		return err
+	}
+	p.skipBlank(false)
+	closing := "]"
+	if isArray {
+		closing = "]]"
+	}
+	if !strings.HasPrefix(p.src[p.pos:], closing) {
+		return p.errorf("expected %q closing table %q", closing, strings.Join(keys, "."))
+	}
+	p.pos += len(closing)
+
+	tbl := p.root
+	for _, key := range keys[:len(keys)-1] {
+		if tbl, err = p.descend(tbl, key); err != nil {
+			return err
+		}
+	}
+	last := keys[len(keys)-1]
+	name := strings.Join(keys, ".")
+	if isArray {
+		var tables []interface{}
+		if val, ok := tbl[last]; ok {
+			if tables, ok = val.([]interface{}); !ok || !p.headers[name] {
+				return p.errorf("%q is already defined", name)
+			}
+		}
+		p.current = map[string]interface{}{}
+		tbl[last] = append(tables, p.current)
+		p.headers[name] = true
+		return nil
+	}
+	if p.headers[name] {
+		return p.errorf("table %q is already defined", name)
+	}
+	if tbl, err = p.descend(tbl, last); err != nil {
+		return err
+	}
+	p.current = tbl
+	p.headers[name] = true
+	return nil
+}
+
+// descend returns the table named key in tbl, creating it when
+// missing. For an array of tables the last table is returned.
+func (p *tomlParser) descend(tbl map[string]interface{}, key string) (map[string]interface{}, error) {
+	val, ok := tbl[key]
+	if !ok {
+		next := map[string]interface{}{}
+		tbl[key] = next
+		return next, nil
+	}
+	switch val := val.(type) {
+	case map[string]interface{}:
+		return val, nil
+	case []interface{}:
+		if len(val) > 0 {
+			if next, ok := val[len(val)-1].(map[string]interface{}); ok {
+				return next, nil
+			}
+		}
+	}
+	return nil, p.errorf("%q is not a table", key)
+}
+
+// parseKeyValue reads "key = value" into tbl.
+func (p *tomlParser) parseKeyValue(tbl map[string]interface{}) error {
+	keys, err := p.parseKey()
+	if err != nil {
+		return err
+	}
+	p.skipBlank(false)
+	if p.peek() != '=' {
+		return p.errorf("expected \"=\" after %q", strings.Join(keys, "."))
+	}
+	p.pos++
+	p.skipBlank(false)
+	val, err := p.parseValue()
+	if err != nil {
+		return err
+	}
+	for _, key := range keys[:len(keys)-1] {
+		if tbl, err = p.descend(tbl, key); err != nil {
+			return err
+		}
+	}
+	last := keys[len(keys)-1]
+	if _, ok := tbl[last]; ok {
+		return p.errorf("%q is already defined", strings.Join(keys, "."))
+	}
+	tbl[last] = val
+	return nil
+}
+
+// parseKey reads a bare, quoted or dotted key.
+func (p *tomlParser) parseKey() ([]string, error) {
+	keys := []string{}
+	for {
+		var (
+			key string
+			err error
+		)
+		switch c := p.peek(); {
+		case c == '"':
+			key, err = p.parseBasicString()
+		case c == '\'':
+			key, err = p.parseLiteralString()
+		default:
+			start := p.pos
+			for !p.eof() && isBareKeyChar(p.peek()) {
+				p.pos++
+			}
+			if start == p.pos {
+				return nil, p.errorf("expected a key, found %q", c)
+			}
+			key = p.src[start:p.pos]
+		}
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+		p.skipBlank(false)
+		if p.peek() != '.' {
+			return keys, nil
+		}
+		p.pos++
+		p.skipBlank(false)
+	}
+}
+
+func isBareKeyChar(c byte) bool {
+	return (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') ||
+		(c >= '0' && c <= '9') || c == '_' || c == '-'
+}
+
+// parseValue reads a string, number, boolean, date, array or
+// inline table.
+func (p *tomlParser) parseValue() (interface{}, error) {
+	rest := p.src[p.pos:]
+	switch {
+	case p.eof():
+		return nil, p.errorf("missing value")
+	case strings.HasPrefix(rest, `"""`):
+		return p.parseMultilineString(`"""`)
+	case strings.HasPrefix(rest, `'''`):
+		return p.parseMultilineString(`'''`)
+	case rest[0] == '"':
+		return p.parseBasicString()
+	case rest[0] == '\'':
+		return p.parseLiteralString()
+	case rest[0] == '[':
+		return p.parseArray()
+	case rest[0] == '{':
+		return p.parseInlineTable()
+	}
+	return p.parseScalar()
+}
+
+// parseBasicString reads a double quoted string.
+func (p *tomlParser) parseBasicString() (string, error) {
+	p.pos++
+	sb := strings.Builder{}
+	for !p.eof() {
+		c := p.peek()
+		switch c {
+		case '"':
+			p.pos++
+			return sb.String(), nil
+		case '\\':
+			if err := p.parseEscape(&sb); err != nil {
+				return "", err
+			}
+		case '\n':
+			return "", p.errorf("unterminated string")
+		default:
+			sb.WriteByte(c)
+			p.pos++
+		}
+	}
+	return "", p.errorf("unterminated string")
+}
+
+// parseLiteralString reads a single quoted string.
+func (p *tomlParser) parseLiteralString() (string, error) {
+	p.pos++
+	i := strings.IndexAny(p.src[p.pos:], "'\n")
+	if i < 0 || p.src[p.pos+i] != '\'' {
+		return "", p.errorf("unterminated string")
+	}
+	s := p.src[p.pos : p.pos+i]
+	p.pos += i + 1
+	return s, nil
+}
+
+// parseMultilineString reads a triple quoted, basic or literal, string.
+func (p *tomlParser) parseMultilineString(delim string) (string, error) {
+	p.pos += len(delim)
+	// A newline right after the opening delimiter is trimmed.
+	if strings.HasPrefix(p.src[p.pos:], "\r\n") {
+		p.pos += 2
+	} else if p.peek() == '\n' {
+		p.pos++
+	}
+	sb := strings.Builder{}
+	for !p.eof() {
+		if strings.HasPrefix(p.src[p.pos:], delim) {
+			p.pos += len(delim)
+			// Up to two quotes may sit against the closing delimiter.
+			for i := 0; i < 2 && !p.eof() && p.peek() == delim[0]; i++ {
+				sb.WriteByte(delim[0])
+				p.pos++
+			}
+			return sb.String(), nil
+		}
+		c := p.peek()
+		if c == '\\' && delim == `"""` {
+			// A line ending backslash trims the following whitespace.
+			j := p.pos + 1
+			for j < len(p.src) && (p.src[j] == ' ' || p.src[j] == '\t') {
+				j++
+			}
+			if j < len(p.src) && (p.src[j] == '\n' || p.src[j] == '\r') {
+				p.pos = j
+				for !p.eof() && strings.ContainsRune(" \t\r\n", rune(p.peek())) {
+					p.pos++
+				}
+				continue
+			}
+			if err := p.parseEscape(&sb); err != nil {
+				return "", err
+			}
+			continue
+		}
+		sb.WriteByte(c)
+		p.pos++
+	}
+	return "", p.errorf("unterminated string")
+}
+
+// parseEscape reads a backslash escape into sb.
+func (p *tomlParser) parseEscape(sb *strings.Builder) error {
+	p.pos++
+	if p.eof() {
+		return p.errorf("unterminated string")
+	}
+	c := p.peek()
+	p.pos++
+	switch c {
+	case 'b':
+		sb.WriteByte('\b')
+	case 't':
+		sb.WriteByte('\t')
+	case 'n':
+		sb.WriteByte('\n')
+	case 'f':
+		sb.WriteByte('\f')
+	case 'r':
+		sb.WriteByte('\r')
+	case 'e':
+		sb.WriteByte(0x1b)
+	case '"', '\\':
+		sb.WriteByte(c)
+	case 'u', 'U':
+		size := 4
+		if c == 'U' {
+			size = 8
+		}
+		if p.pos+size > len(p.src) {
+			return p.errorf("short unicode escape")
+		}
+		n, err := strconv.ParseUint(p.src[p.pos:p.pos+size], 16, 32)
+		if err != nil || !utf8.ValidRune(rune(n)) {
+			return p.errorf("invalid unicode escape %q", p.src[p.pos:p.pos+size])
+		}
+		sb.WriteRune(rune(n))
+		p.pos += size
+	default:
+		return p.errorf("invalid escape \\%c", c)
+	}
+	return nil
+}
+
+// parseArray reads an array, which may span lines.
+func (p *tomlParser) parseArray() ([]interface{}, error) {
+	p.pos++
+	arr := []interface{}{}
+	for {
+		p.skipBlank(true)
+		if p.peek() == ']' {
+			p.pos++
+			return arr, nil
+		}
+		val, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		arr = append(arr, val)
+		p.skipBlank(true)
+		switch p.peek() {
+		case ',':
+			p.pos++
+		case ']':
+			p.pos++
+			return arr, nil
+		default:
+			return nil, p.errorf("expected \",\" or \"]\" in array")
+		}
+	}
+}
+
+// parseInlineTable reads a { key = value, ... } table.
+func (p *tomlParser) parseInlineTable() (map[string]interface{}, error) {
+	p.pos++
+	tbl := map[string]interface{}{}
+	p.skipBlank(false)
+	if p.peek() == '}' {
+		p.pos++
+		return tbl, nil
+	}
+	for {
+		p.skipBlank(false)
+		if err := p.parseKeyValue(tbl); err != nil {
+			return nil, err
+		}
+		p.skipBlank(false)
+		switch p.peek() {
+		case ',':
+			p.pos++
+		case '}':
+			p.pos++
+			return tbl, nil
+		default:
+			return nil, p.errorf("expected \",\" or \"}\" in inline table")
+		}
+	}
+}
+
+// parseScalar reads a boolean, number, date or time.
+func (p *tomlParser) parseScalar() (interface{}, error) {
+	start := p.pos
+	for !p.eof() && strings.IndexByte("0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ+-_.:", p.peek()) >= 0 {
+		p.pos++
+	}
+	token := p.src[start:p.pos]
+	// A date and time may be separated by a space.
+	if tomlLocalDate.MatchString(token) && len(p.src) > p.pos+3 &&
+		p.src[p.pos] == ' ' && p.src[p.pos+3] == ':' {
+		p.pos++
+		for !p.eof() && strings.IndexByte("0123456789+-.:Z", p.peek()) >= 0 {
+			p.pos++
+		}
+		token = p.src[start:p.pos]
+	}
+	switch token {
+	case "":
+		return nil, p.errorf("unexpected %q", p.peek())
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "inf", "+inf":
+		return math.Inf(1), nil
+	case "-inf":
+		return math.Inf(-1), nil
+	case "nan", "+nan", "-nan":
+		return math.NaN(), nil
+	}
+	if tomlLocalTime.MatchString(token) {
+		return token, nil
+	}
+	if len(token) >= 10 && tomlLocalDate.MatchString(token[0:10]) {
+		return parseTOMLDate(token, p)
+	}
+	num := strings.ReplaceAll(token, "_", "")
+	if strings.HasPrefix(num, "0x") || strings.HasPrefix(num, "0o") || strings.HasPrefix(num, "0b") {
+		if i, err := strconv.ParseInt(num, 0, 64); err == nil {
+			return int(i), nil
+		}
+		return nil, p.errorf("invalid number %q", token)
+	}
+	if i, err := strconv.ParseInt(num, 10, 64); err == nil {
+		return int(i), nil
+	}
+	if f, err := strconv.ParseFloat(num, 64); err == nil && !strings.ContainsAny(num, "xXpP") {
+		return f, nil
+	}
+	return nil, p.errorf("invalid value %q", token)
+}
+
+// parseTOMLDate parses an offset date time, local date time or
+// local date. Local values are taken as UTC.
+func parseTOMLDate(token string, p *tomlParser) (time.Time, error) {
+	s := strings.Replace(token, " ", "T", 1)
+	if len(s) > 10 && (s[10] == 't') {
+		s = s[:10] + "T" + s[11:]
+	}
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05.999999999", "2006-01-02T15:04", "2006-01-02"} {
+		if dt, err := time.Parse(layout, strings.ToUpper(s)); err == nil {
+			return dt, nil
+		}
+	}
+	return time.Time{}, p.errorf("invalid date %q", token)
+}
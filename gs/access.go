@@ -0,0 +1,120 @@
+// pttk is software for working with plain text content.
+// Copyright (C) 2022 R. S. Doiel
+//
+// This program is free software: you can redistribute it and/or modify it under the terms of the GNU Affero General Public License as published by the Free Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License along with this program. If not, see <https://www.gnu.org/licenses/>.
+package gs
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// CommonLogFormat is the time layout used in access log entries
+	CommonLogFormat = "02/Jan/2006:15:04:05 -0700"
+)
+
+// AccessListener wraps a net.Listener so each connection is logged
+// in Common Log Format when it is closed. go-gopher's handlers do not
+// see the remote address so the logging is done at the connection.
+type AccessListener struct {
+	net.Listener
+	out io.Writer
+	mu  *sync.Mutex
+}
+
+// NewAccessListener returns an *AccessListener writing to out.
+func NewAccessListener(ln net.Listener, out io.Writer) *AccessListener {
+	return &AccessListener{
+		Listener: ln,
+		out:      out,
+		mu:       new(sync.Mutex),
+	}
+}
+
+// Accept implements net.Listener
+func (l *AccessListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return &accessConn{
+		Conn:     conn,
+		listener: l,
+		started:  time.Now(),
+	}, nil
+}
+
+// accessConn records the request line and the bytes written
+// to a connection.
+type accessConn struct {
+	net.Conn
+	listener *AccessListener
+	started  time.Time
+	request  bytes.Buffer
+	first    byte
+	written  int
+	once     sync.Once
+}
+
+func (c *accessConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	// Keep the request line, selectors are short.
+	if n > 0 && c.request.Len() < 1024 && !bytes.Contains(c.request.Bytes(), []byte("\n")) {
+		c.request.Write(b[:n])
+	}
+	return n, err
+}
+
+func (c *accessConn) Write(b []byte) (int, error) {
+	n, err := c.Conn.Write(b)
+	if c.written == 0 && n > 0 {
+		c.first = b[0]
+	}
+	c.written += n
+	return n, err
+}
+
+func (c *accessConn) Close() error {
+	c.once.Do(c.log)
+	return c.Conn.Close()
+}
+
+// log writes the Common Log Format entry for the connection.
+func (c *accessConn) log() {
+	host, _, err := net.SplitHostPort(c.RemoteAddr().String())
+	if err != nil {
+		host = c.RemoteAddr().String()
+	}
+	selector := strings.SplitN(c.request.String(), "\n", 2)[0]
+	selector = strings.TrimSuffix(selector, "\r")
+	c.listener.mu.Lock()
+	defer c.listener.mu.Unlock()
+	fmt.Fprintln(c.listener.out, FormatAccess(host, c.started, selector, accessStatus(c.first), c.written))
+}
+
+// accessStatus maps a response to an HTTP style status code for
+// the log. A response starting with an error item ("3") is
+// logged as 404, everything else as 200.
+func accessStatus(first byte) int {
+	if first == '3' {
+		return 404
+	}
+	return 200
+}
+
+// FormatAccess returns an access log entry in Common Log Format.
+// Gopher has no method or protocol version so the request is
+// logged as the selector.
+func FormatAccess(host string, t time.Time, selector string, status int, size int) string {
+	return fmt.Sprintf("%s - - [%s] %q %d %d", host, t.Format(CommonLogFormat), selector, status, size)
+}
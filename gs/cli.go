@@ -15,16 +15,36 @@ import (
 	"net/url"
 	"os"
 	"strings"
+
+	// 3rd Party packages
+	"git.mills.io/prologic/go-gopher"
 )
 
 var (
 	showHelp bool
 	// local app options
-	uri     string
-	docRoot string
+	uri        string
+	docRoot    string
+	search     string
+	gopherPlus bool
+	configName string
+	accessLog  string
+	certPEM    string
+	keyPEM     string
+	sortBy     string
+
+	// gopher client options
+	asJSON     bool
+	outName    string
+	crawlDepth int
+	verbose    bool
+
+	// finger options
+	phlogName  string
+	postsCount int
 )
 
-func usage(appName string, verb string, exitCode int) {
+func usage(appName string, verb string, helpText string, exitCode int) {
 	out := os.Stdout
 	if exitCode > 0 {
 		out = os.Stderr
@@ -49,13 +69,20 @@ func RunGS(appName string, verb string, vargs []string) error {
 	flagSet.BoolVar(&showHelp, "help", false, fmt.Sprintf("display help for %s", verb))
 	flagSet.StringVar(&docRoot, "htdoc", "", "set the document root")
 	flagSet.StringVar(&uri, "url", "", "set the URL to listen on")
+	flagSet.StringVar(&search, "search", "", "answer type 7 searches at this selector, e.g. /search")
+	flagSet.BoolVar(&gopherPlus, "gopher-plus", false, "answer Gopher+ attribute requests")
+	flagSet.StringVar(&configName, "config", "", "read the service configuration from a JSON or YAML file")
+	flagSet.StringVar(&accessLog, "access-log", "", "write the access log to this file")
+	flagSet.StringVar(&certPEM, "cert", "", "cert.pem file for a gophers:// (Gopher over TLS) URL")
+	flagSet.StringVar(&keyPEM, "key", "", "key.pem file for a gophers:// (Gopher over TLS) URL")
+	flagSet.StringVar(&sortBy, "sort", "", "sort generated menus by name, title, date or size")
 	flagSet.Parse(vargs)
 	args := flagSet.Args()
 
 	eout := os.Stderr
 
 	if showHelp {
-		usage(appName, verb, 0)
+		usage(appName, verb, helpText, 0)
 	}
 
 	if uri != "" {
@@ -64,7 +91,7 @@ func RunGS(appName string, verb string, vargs []string) error {
 	}
 	for _, arg := range args {
 		switch {
-		case uri == "" && strings.HasPrefix(arg, "gopher://"):
+		case uri == "" && (strings.HasPrefix(arg, "gopher://") || strings.HasPrefix(arg, "gophers://")):
 			u, err = url.Parse(arg)
 			exitOnError(eout, err, 1)
 		case docRoot == "":
@@ -72,19 +99,189 @@ func RunGS(appName string, verb string, vargs []string) error {
 		}
 	}
 	gs := DefaultGopherService()
+	if configName != "" {
+		gs, err = LoadGopherService(configName)
+		exitOnError(eout, err, 1)
+	}
 	if docRoot != "" {
 		if _, err := os.Stat(docRoot); err != nil {
 			exitOnError(eout, err, 1)
 		}
 		gs.DocRoot = docRoot
 	}
+	if search != "" {
+		gs.Search = search
+	}
+	if gopherPlus {
+		gs.GopherPlus = gopherPlus
+	}
+	if accessLog != "" {
+		gs.AccessLog = accessLog
+	}
+	if sortBy != "" {
+		gs.Sort = sortBy
+	}
 	if u != nil {
-		if u.Scheme != "gopher" {
+		s := new(Service)
+		switch u.Scheme {
+		case "gopher":
+		case "gophers":
+			s.CertPEM, s.KeyPEM = certPEM, keyPEM
+		default:
 			return fmt.Errorf("%q not supported by gopher service", u.Scheme)
 		}
-		gs.Gopher.Scheme = u.Scheme
-		gs.Gopher.Host = u.Hostname()
-		gs.Gopher.Port = u.Port()
+		s.Scheme = u.Scheme
+		s.Host = u.Hostname()
+		s.Port = u.Port()
+		if s.Scheme == "gophers" {
+			gs.Gopher, gs.Gophers = nil, s
+		} else {
+			gs.Gopher = s
+		}
 	}
 	return gs.Run()
 }
+
+// RunGopher implements the gopher client verb, "get", "ls" and "crawl".
+func RunGopher(appName string, verb string, vargs []string) error {
+	flagSet := flag.NewFlagSet(appName+":"+verb, flag.ExitOnError)
+	flagSet.BoolVar(&showHelp, "help", false, fmt.Sprintf("display help for %s", verb))
+	flagSet.BoolVar(&asJSON, "json", false, "render menus as JSON")
+	flagSet.StringVar(&outName, "o", "", "write the fetched content to this file")
+	flagSet.IntVar(&crawlDepth, "depth", 0, "limit how many menus deep crawl follows, 0 is unlimited")
+	flagSet.BoolVar(&verbose, "verbose", false, "log each selector crawled")
+	flagSet.Parse(vargs)
+	args := flagSet.Args()
+
+	if showHelp {
+		usage(appName, verb, helpTextGopher, 0)
+	}
+	if len(args) < 2 {
+		return fmt.Errorf("expected get, ls or crawl and a gopher URL, see %s %s -help", appName, verb)
+	}
+	action, uri := args[0], args[1]
+	item, err := ParseGopherURL(uri)
+	if err != nil {
+		return err
+	}
+
+	out := os.Stdout
+	if outName != "" && action != "crawl" {
+		fp, err := os.Create(outName)
+		if err != nil {
+			return err
+		}
+		defer fp.Close()
+		out = fp
+	}
+
+	switch action {
+	case "get":
+		if !isMenu(item.Type) {
+			rd, err := item.FetchFile()
+			if err != nil {
+				return err
+			}
+			defer rd.Close()
+			_, err = io.Copy(out, rd)
+			return err
+		}
+		dir, err := item.FetchDirectory()
+		if err != nil {
+			return err
+		}
+		if asJSON {
+			src, err := dir.ToJSON()
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(out, "%s\n", src)
+			return nil
+		}
+		src, err := dir.ToText()
+		if err != nil {
+			return err
+		}
+		out.Write(src)
+	case "ls":
+		if !isMenu(item.Type) {
+			item.Type = gopher.DIRECTORY
+		}
+		dir, err := item.FetchDirectory()
+		if err != nil {
+			return err
+		}
+		if asJSON {
+			src, err := dir.ToJSON()
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(out, "%s\n", src)
+			return nil
+		}
+		RenderMenu(out, dir.Items)
+	case "crawl":
+		if item.Type != gopher.DIRECTORY {
+			return fmt.Errorf("%q is not a menu", uri)
+		}
+		crawler := new(Crawler)
+		crawler.Root = item.Host
+		if len(args) > 2 {
+			crawler.Root = args[2]
+		}
+		crawler.Depth = crawlDepth
+		crawler.Verbose = verbose
+		return crawler.Crawl(item)
+	default:
+		return fmt.Errorf("%q unknown, expected get, ls or crawl", action)
+	}
+	return nil
+}
+
+// RunFinger implements the finger verb, a Finger (RFC 1288) service.
+func RunFinger(appName string, verb string, vargs []string) error {
+	var (
+		u   *url.URL
+		err error
+	)
+	flagSet := flag.NewFlagSet(appName+":"+verb, flag.ExitOnError)
+	flagSet.BoolVar(&showHelp, "help", false, fmt.Sprintf("display help for %s", verb))
+	flagSet.StringVar(&docRoot, "htdoc", "", "set the directory holding the user directories")
+	flagSet.StringVar(&uri, "url", "", "set the URL to listen on, e.g. finger://localhost:7079")
+	flagSet.StringVar(&phlogName, "phlog", "", "append the latest posts from this phlog.json")
+	flagSet.IntVar(&postsCount, "posts", 5, "number of phlog posts to list")
+	flagSet.StringVar(&accessLog, "access-log", "", "write the access log to this file")
+	flagSet.Parse(vargs)
+	args := flagSet.Args()
+
+	if showHelp {
+		usage(appName, verb, helpTextFinger, 0)
+	}
+	fs := DefaultFingerService()
+	if len(args) > 0 && docRoot == "" {
+		docRoot = args[0]
+	}
+	if docRoot != "" {
+		if _, err := os.Stat(docRoot); err != nil {
+			return err
+		}
+		fs.DocRoot = docRoot
+	}
+	if uri != "" {
+		u, err = url.Parse(uri)
+		if err != nil {
+			return err
+		}
+		if u.Scheme != "finger" {
+			return fmt.Errorf("%q not supported by finger service", u.Scheme)
+		}
+		fs.Finger.Host = u.Hostname()
+		if u.Port() != "" {
+			fs.Finger.Port = u.Port()
+		}
+	}
+	fs.Phlog = phlogName
+	fs.Posts = postsCount
+	fs.AccessLog = accessLog
+	return fs.Run()
+}
@@ -21,11 +21,121 @@ const (
 
 {app_name} {verb} [HTDOC_PATH] [OPTIONS]
 
+{app_name} {verb} -config CONFIG_FILE
+
 # DESCRIPTION
 
 {app_name} {verb} provides a simple static gopher server for
 testing the content you're Gopher content.
 
+Directories are served using their "gophermap" file when present.
+Gophermaps are read the way Gophernicus reads them,
+
+- lines without a tab are shown as informational lines
+- lines starting with "#" are comments and are not shown
+- "!TITLE" shows TITLE as an informational line
+- "-NAME" hides NAME from a "*" listing
+- "=FILE" includes FILE (or a directory's gophermap) in place, FILE
+  must be inside the document root
+- "*" appends a listing of the directory and stops reading
+- "." stops reading the gophermap
+
+Menu lines are "TYPE DISPLAY_NAME TAB SELECTOR TAB HOST TAB PORT". If
+the selector is empty the display name is used. If the host is missing
+the server's host and port are filled in and a selector not starting
+with "/" is taken as relative to the gophermap's directory. A host
+without a port defaults to port 70.
+
+Directories without a gophermap get a generated menu listing their
+files and sub directories. Each entry is followed by an informational
+line with its size and modification date. Markdown files are listed
+by the title in their front matter with the file name added to the
+informational line. The menu is sorted by name unless "-sort" (or
+"sort" in a configuration file) sets "title", "date" (newest first)
+or "size" (largest first). Dot files are neither listed nor served.
+
+A directory holding a "phlog.json" or "blog.json" (see phlogit and
+blogit) is served as a phlog. Its year, month and day directories get
+menus generated from the metadata when requested, listing post titles
+and descriptions, so posts show up without refreshing the static
+gophermaps. The phlog directory's own gophermap, if there is one, is
+still used as its landing page.
+
+With "-search SELECTOR" a full text index of the text documents
+(".md", ".txt" and files without an extension) in the document root is
+built when the server starts. Type 7 requests to SELECTOR are answered
+with a menu of the matching documents, those containing all the words
+searched for. Add a line like the following to a gophermap so
+Gopher clients can find it.
+
+~~~
+7Search this hole	/search
+~~~
+
+With "-gopher-plus" Gopher+ attribute requests (a selector followed by
+a tab and "!") are answered with "+INFO", "+ADMIN" and "+ABSTRACT"
+blocks. The title and abstract (or description) are taken from the
+document's front matter.
+
+A configuration file, JSON or YAML, can describe more than one
+listener. "gopher" is a plain Gopher service, "gophers" is Gopher over
+TLS and needs a "cert_pem" and "key_pem". "listeners" lists any
+additional services, each may set its own "htdocs". Requests are logged
+in Common Log Format to "access_log" or to standard out when it is
+not set.
+
+~~~yaml
+htdocs: htdocs
+access_log: gs-access.log
+search: /search
+gopher_plus: true
+sort: date
+gopher:
+  host: localhost
+  port: "7000"
+gophers:
+  host: localhost
+  port: "7443"
+  cert_pem: etc/cert.pem
+  key_pem: etc/key.pem
+listeners:
+  - host: localhost
+    port: "7070"
+    htdocs: drafts
+~~~
+
+# OPTIONS
+
+-access-log FILENAME
+: write the access log to FILENAME
+
+-cert FILENAME
+: the cert.pem file used with a "gophers://" URL
+
+-config FILENAME
+: read the service configuration from a JSON or YAML file
+
+-gopher-plus
+: answer Gopher+ attribute requests
+
+-help
+: display help
+
+-htdoc PATH
+: set the document root
+
+-key FILENAME
+: the key.pem file used with a "gophers://" URL
+
+-search SELECTOR
+: answer type 7 searches at SELECTOR, e.g. "/search"
+
+-sort ORDER
+: sort generated menus by "name", "title", "date" or "size"
+
+-url URL
+: set the URL to listen on, e.g. "gopher://localhost:7000" or "gophers://localhost:7443"
+
 # EXAMPLE
 
 In the example the htdoc directory is called "myblog"
@@ -33,5 +143,160 @@ and you can view the result at gopher://localhost:7000.
 
   {app_name} {verb} $HOME/Sites/myblog
 
+Serving the same directory with search and Gopher+ attributes.
+
+  {app_name} {verb} -search /search -gopher-plus $HOME/Sites/myblog
+
+Running the services described in a configuration file.
+
+  {app_name} {verb} -config gs.yaml
+
+`
+	helpTextGopher = `% {app_name}-{verb}(1) {app_name}-{verb} user manual
+% R. S. Doiel
+% October 19, 2026
+
+# NAME
+
+{app_name} {verb}
+
+# SYNOPSIS
+
+{app_name} {verb} [OPTIONS] get GOPHER_URL
+
+{app_name} {verb} [OPTIONS] ls GOPHER_URL
+
+{app_name} {verb} [OPTIONS] crawl GOPHER_URL [MIRROR_DIRECTORY]
+
+# DESCRIPTION
+
+{app_name} {verb} is a small Gopher client for reading phlogs and
+testing your own Gopher hole (e.g. one served by "{app_name} gs").
+
+GOPHER_URL has the form "gopher://HOST:PORT/TYPESELECTOR", if PORT is
+left out 70 is used and if TYPE is left out the selector is read as a
+menu.
+
+get
+: fetches the selector writing what is returned. Menus are written as
+the server sent them (or as JSON with "-json").
+
+ls
+: fetches a menu and renders it for reading, each link is shown with its
+item type and URL. Use "-json" for a JSON version.
+
+crawl
+: fetches a menu and everything it links to on the same server below its
+selector writing a mirror to MIRROR_DIRECTORY (defaults to the host name).
+Menus are saved as gophermap files with links to the crawled server
+made local so the mirror can be served with "{app_name} gs".
+
+# OPTIONS
+
+-depth N
+: limit how many menus deep crawl follows, 0 (default) is unlimited
+
+-help
+: display help
+
+-json
+: render menus as JSON
+
+-o FILENAME
+: write the result of get or ls to FILENAME
+
+-verbose
+: log each selector as it is crawled
+
+# EXAMPLES
+
+Listing a phlog's menu.
+
+~~~
+  {app_name} {verb} ls gopher://localhost:7000/1/phlog
+~~~
+
+Reading a post.
+
+~~~
+  {app_name} {verb} get gopher://localhost:7000/0/phlog/2022/07/15/post.md
+~~~
+
+Mirroring a phlog to "archive/phlog" then previewing the mirror.
+
+~~~
+  {app_name} {verb} crawl gopher://localhost:7000/1/phlog archive
+  {app_name} gs archive
+~~~
+
+`
+
+	helpTextFinger = `% {app_name}-{verb}(1) {app_name}-{verb} user manual
+% R. S. Doiel
+% October 19, 2026
+
+# NAME
+
+{app_name} {verb}
+
+# SYNOPSIS
+
+{app_name} {verb} [OPTIONS] [HTDOC_PATH]
+
+# DESCRIPTION
+
+{app_name} {verb} runs a Finger service (RFC 1288) so people can
+"finger USER@HOST" to read your plan and see what you've posted.
+
+Each user is a directory in HTDOC_PATH (defaults to the current
+directory) holding a ".plan" file and optionally a ".project" file.
+A query for USER answers with the login name, the ".project" and the
+".plan". An empty query lists the users. Queries to forward to
+another host ("USER@HOST") are refused.
+
+If "-phlog" is given (or the user's directory holds a "phlog.json")
+the latest posts are listed after the plan with the post's date, title
+and, when the phlog.json has a "url", a link to the post.
+
+The service listens on localhost port 7079 by default. The standard
+finger port is 79 which usually requires extra privileges.
+
+# OPTIONS
+
+-access-log FILENAME
+: write the access log (Common Log Format) to FILENAME, defaults to
+standard out
+
+-help
+: display help
+
+-htdoc HTDOC_PATH
+: set the directory holding the user directories
+
+-phlog PHLOG_JSON
+: append the latest posts from PHLOG_JSON
+
+-posts N
+: list N posts, defaults to 5
+
+-url URL
+: set the URL to listen on, e.g. "finger://localhost:7079"
+
+# EXAMPLES
+
+Serving "finger/rsdoiel/.plan" with the latest posts
+of "htdocs/phlog/phlog.json".
+
+~~~
+  {app_name} {verb} -phlog htdocs/phlog/phlog.json finger
+~~~
+
+Most finger clients only use port 79, netcat can query
+other ports.
+
+~~~
+  echo rsdoiel | nc localhost 7079
+~~~
+
 `
 )
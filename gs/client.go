@@ -0,0 +1,207 @@
+// pttk is software for working with plain text content.
+// Copyright (C) 2022 R. S. Doiel
+//
+// This program is free software: you can redistribute it and/or modify it under the terms of the GNU Affero General Public License as published by the Free Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License along with this program. If not, see <https://www.gnu.org/licenses/>.
+package gs
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	// 3rd Party packages
+	"git.mills.io/prologic/go-gopher"
+)
+
+// ParseGopherURL converts a gopher:// URL into a *gopher.Item
+// following RFC 4266, gopher://HOST:PORT/TSELECTOR. If the item
+// type is missing a directory is assumed.
+func ParseGopherURL(uri string) (*gopher.Item, error) {
+	if !strings.Contains(uri, "://") {
+		uri = "gopher://" + uri
+	}
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "gopher" {
+		return nil, fmt.Errorf("%q, unsupported scheme %q", uri, u.Scheme)
+	}
+	item := new(gopher.Item)
+	item.Host = u.Hostname()
+	item.Port = 70
+	if u.Port() != "" {
+		if item.Port, err = strconv.Atoi(u.Port()); err != nil {
+			return nil, fmt.Errorf("%q, bad port %s", uri, err)
+		}
+	}
+	p := strings.TrimPrefix(u.Path, "/")
+	item.Type = gopher.DIRECTORY
+	if len(p) > 0 {
+		item.Type = gopher.ItemType(p[0])
+		item.Selector = p[1:]
+	}
+	if u.RawQuery != "" {
+		item.Selector += "\t" + u.RawQuery
+	}
+	return item, nil
+}
+
+// ItemURL renders an item as a gopher:// URL.
+func ItemURL(item *gopher.Item) string {
+	return fmt.Sprintf("gopher://%s:%d/%c%s", item.Host, item.Port, item.Type, item.Selector)
+}
+
+// isMenu reports if an item's type is answered with a menu.
+func isMenu(t gopher.ItemType) bool {
+	return t == gopher.DIRECTORY || t == gopher.INDEXSEARCH
+}
+
+// RenderMenu writes a menu for a person to read. Informational
+// lines are indented, other items are followed by their URL.
+func RenderMenu(out io.Writer, items []*gopher.Item) {
+	for _, item := range items {
+		switch item.Type {
+		case gopher.INFO:
+			fmt.Fprintf(out, "      %s\n", item.Description)
+		case gopher.ERROR:
+			fmt.Fprintf(out, "ERR   %s\n", item.Description)
+		default:
+			if strings.HasPrefix(item.Selector, "URL:") {
+				fmt.Fprintf(out, "(%c)   %s <%s>\n", item.Type, item.Description, strings.TrimPrefix(item.Selector, "URL:"))
+			} else {
+				fmt.Fprintf(out, "(%c)   %s <%s>\n", item.Type, item.Description, ItemURL(item))
+			}
+		}
+	}
+}
+
+// mirrorLine renders a menu item as a gophermap line. Items on the
+// crawled server drop their host and port so the mirror's server
+// fills in its own.
+func mirrorLine(item *gopher.Item, host string, port int) string {
+	if item.Type == gopher.INFO {
+		// Keep lines Gophernicus would treat as directives informational.
+		if item.Description != "" && strings.ContainsRune("#!-=*.", rune(item.Description[0])) {
+			return fmt.Sprintf("i%s\t\terror.host\t1", item.Description)
+		}
+		return item.Description
+	}
+	if item.Host == host && item.Port == port {
+		return fmt.Sprintf("%c%s\t%s", item.Type, item.Description, item.Selector)
+	}
+	return fmt.Sprintf("%c%s\t%s\t%s\t%d", item.Type, item.Description, item.Selector, item.Host, item.Port)
+}
+
+// Crawler mirrors a gopher hole to a local directory.
+type Crawler struct {
+	// Root is the directory the mirror is written to.
+	Root string
+	// Depth is how many menus deep to follow, zero is unlimited.
+	Depth int
+	// Verbose logs each selector as it is fetched.
+	Verbose bool
+
+	seen map[string]bool
+}
+
+// mirrorPath maps a selector to a path in the mirror. It returns
+// an empty string for selectors that can't be safely written.
+func (c *Crawler) mirrorPath(selector string) string {
+	if strings.ContainsAny(selector, "\t\x00") || strings.HasPrefix(selector, "URL:") {
+		return ""
+	}
+	return filepath.Join(c.Root, filepath.FromSlash(path.Clean("/"+selector)))
+}
+
+// inScope checks an item is on the crawled server and below the
+// starting selector.
+func inScope(item *gopher.Item, start *gopher.Item) bool {
+	if item.Host != start.Host || item.Port != start.Port {
+		return false
+	}
+	prefix := strings.TrimSuffix(path.Clean("/"+start.Selector), "/")
+	selector := path.Clean("/" + item.Selector)
+	return prefix == "" || selector == prefix || strings.HasPrefix(selector, prefix+"/")
+}
+
+// Crawl fetches the menu start and everything it links to on the same
+// server below its selector. Menus are written as gophermaps and
+// other items as files.
+func (c *Crawler) Crawl(start *gopher.Item) error {
+	c.seen = map[string]bool{}
+	return c.crawl(start, start, 0)
+}
+
+func (c *Crawler) crawl(item *gopher.Item, start *gopher.Item, depth int) error {
+	key := path.Clean("/" + item.Selector)
+	if c.seen[key] {
+		return nil
+	}
+	c.seen[key] = true
+	target := c.mirrorPath(item.Selector)
+	if target == "" {
+		return nil
+	}
+	if c.Verbose {
+		log.Printf("fetching %s", ItemURL(item))
+	}
+	if item.Type == gopher.DIRECTORY {
+		dir, err := item.FetchDirectory()
+		if err != nil {
+			return fmt.Errorf("%s, %s", ItemURL(item), err)
+		}
+		if err := os.MkdirAll(target, 0775); err != nil {
+			return err
+		}
+		lines := []string{}
+		for _, child := range dir.Items {
+			lines = append(lines, mirrorLine(child, start.Host, start.Port))
+		}
+		src := []byte(strings.Join(lines, "\r\n") + "\r\n")
+		if err := os.WriteFile(filepath.Join(target, GophermapName), src, 0664); err != nil {
+			return err
+		}
+		if c.Depth > 0 && depth >= c.Depth {
+			return nil
+		}
+		for _, child := range dir.Items {
+			switch child.Type {
+			case gopher.INFO, gopher.ERROR, gopher.INDEXSEARCH, gopher.TELNET, gopher.TN3270, gopher.PHONEBOOK:
+				continue
+			}
+			if !inScope(child, start) {
+				continue
+			}
+			if err := c.crawl(child, start, depth+1); err != nil {
+				log.Printf("%s", err)
+			}
+		}
+		return nil
+	}
+	rd, err := item.FetchFile()
+	if err != nil {
+		return fmt.Errorf("%s, %s", ItemURL(item), err)
+	}
+	defer rd.Close()
+	if err := os.MkdirAll(filepath.Dir(target), 0775); err != nil {
+		return err
+	}
+	out, err := os.Create(target)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	_, err = io.Copy(out, rd)
+	return err
+}
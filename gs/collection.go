@@ -0,0 +1,207 @@
+// pttk is software for working with plain text content.
+// Copyright (C) 2022 R. S. Doiel
+//
+// This program is free software: you can redistribute it and/or modify it under the terms of the GNU Affero General Public License as published by the Free Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License along with this program. If not, see <https://www.gnu.org/licenses/>.
+package gs
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	// My packages
+	"github.com/rsdoiel/pttk/blogit"
+	"github.com/rsdoiel/pttk/collection"
+	"github.com/rsdoiel/pttk/phlogit"
+
+	// 3rd Party packages
+	"git.mills.io/prologic/go-gopher"
+)
+
+// collectionMeta holds the parts of a phlog.json or blog.json
+// used to render menus.
+type collectionMeta struct {
+	meta     *collection.Meta
+	masthead string
+}
+
+// loadCollection reads phlog.json or blog.json from dName. It
+// returns nil if neither is found.
+func loadCollection(dName string) (*collectionMeta, error) {
+	fName := filepath.Join(dName, "phlog.json")
+	if _, err := os.Stat(fName); err == nil {
+		phlog := new(phlogit.PhlogMeta)
+		if err := phlogit.LoadPhlogMeta(fName, phlog); err != nil {
+			return nil, err
+		}
+		return &collectionMeta{meta: &phlog.Meta, masthead: phlog.Masthead}, nil
+	}
+	fName = filepath.Join(dName, "blog.json")
+	if _, err := os.Stat(fName); err == nil {
+		blog := new(blogit.BlogMeta)
+		if err := blogit.LoadBlogMeta(fName, blog); err != nil {
+			return nil, err
+		}
+		return &collectionMeta{meta: &blog.Meta}, nil
+	}
+	return nil, nil
+}
+
+// isDatePart checks that s is a number of n digits
+func isDatePart(s string, n int) bool {
+	if len(s) != n {
+		return false
+	}
+	_, err := strconv.Atoi(s)
+	return err == nil
+}
+
+// findCollection looks in the directory of selector and up to three
+// parents (YYYY/MM/DD) for a phlog.json or blog.json. It returns the
+// collection's selector and the date parts following it.
+func (h *GopherHandler) findCollection(selector string) (*collectionMeta, string, []string, error) {
+	parts := []string{}
+	dirSelector := selector
+	for i := 0; i <= 3; i++ {
+		cm, err := loadCollection(h.fsPath(dirSelector))
+		if err != nil {
+			return nil, "", nil, err
+		}
+		if cm != nil {
+			// Only YYYY, MM and DD directories sit below a collection.
+			for j, part := range parts {
+				if (j == 0 && !isDatePart(part, 4)) || (j > 0 && !isDatePart(part, 2)) {
+					return nil, "", nil, nil
+				}
+			}
+			return cm, dirSelector, parts, nil
+		}
+		if dirSelector == "/" {
+			break
+		}
+		part := path.Base(dirSelector)
+		if !isDatePart(part, 4) && !isDatePart(part, 2) {
+			break
+		}
+		parts = append([]string{part}, parts...)
+		dirSelector = path.Dir(dirSelector)
+	}
+	return nil, "", nil, nil
+}
+
+// monthTitle renders "July 2022" from "07" and "2022"
+func monthTitle(month string, year string) string {
+	if m, err := strconv.Atoi(month); err == nil && m >= 1 && m <= 12 {
+		return fmt.Sprintf("%s %s", time.Month(m).String(), year)
+	}
+	return fmt.Sprintf("%s %s", month, year)
+}
+
+// postItems renders a post as a menu item followed by its
+// description as informational lines.
+func (h *GopherHandler) postItems(post *collection.PostObj, dirSelector string, ymd []string, host string, port int) []*gopher.Item {
+	name := path.Base(post.Document)
+	selector := path.Join(dirSelector, ymd[0], ymd[1], ymd[2], name)
+	title := post.Title
+	if title == "" {
+		title = post.Slug
+	}
+	item := new(gopher.Item)
+	item.Type = gopher.FILE
+	if info, err := os.Stat(h.fsPath(selector)); err == nil {
+		item.Type = itemType(h.fsPath(selector), info)
+	}
+	item.Description = title
+	item.Selector = selector
+	item.Host = host
+	item.Port = port
+	items := []*gopher.Item{item}
+	description := post.Description
+	if description == "" {
+		description = post.Abstract
+	}
+	if description != "" {
+		for _, line := range strings.Split(strings.TrimSpace(description), "\n") {
+			items = append(items, infoItem("  "+line))
+		}
+	}
+	return items
+}
+
+// collectionMenu synthesizes the menu for a collection's directory,
+// its years, months or days. dateParts holds the YYYY, MM and DD
+// of the request below the collection.
+func (h *GopherHandler) collectionMenu(cm *collectionMeta, dirSelector string, dateParts []string, host string, port int) ([]*gopher.Item, error) {
+	items := []*gopher.Item{}
+	dirItem := func(title string, selector string) {
+		items = append(items, &gopher.Item{
+			Type:        gopher.DIRECTORY,
+			Description: title,
+			Selector:    selector,
+			Host:        host,
+			Port:        port,
+		})
+	}
+	if len(dateParts) == 0 {
+		if cm.masthead != "" {
+			for _, line := range strings.Split(strings.TrimSuffix(cm.masthead, "\n"), "\n") {
+				items = append(items, infoItem(line))
+			}
+		} else if cm.meta.Name != "" {
+			items = append(items, infoItem(cm.meta.Name))
+		}
+		if cm.meta.Quip != "" {
+			items = append(items, infoItem(cm.meta.Quip))
+		}
+		if cm.meta.Description != "" {
+			items = append(items, infoItem(""))
+			for _, line := range strings.Split(strings.TrimSpace(cm.meta.Description), "\n") {
+				items = append(items, infoItem(line))
+			}
+		}
+		items = append(items, infoItem(""))
+		for _, yr := range cm.meta.Years {
+			dirItem(yr.Year, path.Join(dirSelector, yr.Year))
+		}
+		return items, nil
+	}
+	yr := cm.meta.FindYear(dateParts[0])
+	if yr == nil {
+		return nil, fmt.Errorf("no posts for %s", dateParts[0])
+	}
+	if len(dateParts) == 1 {
+		items = append(items, infoItem(fmt.Sprintf("Posts for %s", yr.Year)), infoItem(""))
+		for _, mn := range yr.Months {
+			dirItem(monthTitle(mn.Month, yr.Year), path.Join(dirSelector, yr.Year, mn.Month))
+		}
+		return items, nil
+	}
+	for _, mn := range yr.Months {
+		if mn.Month != dateParts[1] {
+			continue
+		}
+		items = append(items, infoItem(monthTitle(mn.Month, yr.Year)))
+		for _, dy := range mn.Days {
+			if len(dateParts) > 2 && dy.Day != dateParts[2] {
+				continue
+			}
+			items = append(items, infoItem(""))
+			if len(dateParts) == 2 {
+				dirItem(fmt.Sprintf("%s-%s-%s", yr.Year, mn.Month, dy.Day), path.Join(dirSelector, yr.Year, mn.Month, dy.Day))
+			}
+			for _, post := range dy.Posts {
+				items = append(items, h.postItems(post, dirSelector, []string{yr.Year, mn.Month, dy.Day}, host, port)...)
+			}
+		}
+		return items, nil
+	}
+	return nil, fmt.Errorf("no posts for %s", strings.Join(dateParts, "-"))
+}
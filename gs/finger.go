@@ -0,0 +1,237 @@
+// pttk is software for working with plain text content.
+// Copyright (C) 2022 R. S. Doiel
+//
+// This program is free software: you can redistribute it and/or modify it under the terms of the GNU Affero General Public License as published by the Free Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License along with this program. If not, see <https://www.gnu.org/licenses/>.
+package gs
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	// My packages
+	"github.com/rsdoiel/pttk/collection"
+	"github.com/rsdoiel/pttk/phlogit"
+)
+
+var (
+	// fingerUserExp describes the user names a finger request may ask for
+	fingerUserExp = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9._-]*$`)
+)
+
+// FingerService describes a Finger (RFC 1288) service. Each user
+// is a directory in DocRoot holding a ".plan" and optionally a
+// ".project" file.
+type FingerService struct {
+	// DocRoot holds a directory for each user
+	DocRoot string `json:"htdocs,omitempty" yaml:"htdocs,omitempty"`
+	// Finger describes the host and port to listen on
+	Finger *Service `json:"finger,omitempty" yaml:"finger,omitempty"`
+	// Phlog is the path to a phlog.json. If set the latest posts
+	// are appended to each user's plan. If not set a "phlog.json"
+	// in the user's directory is used when found.
+	Phlog string `json:"phlog,omitempty" yaml:"phlog,omitempty"`
+	// Posts is the number of recent posts listed, defaults to 5
+	Posts int `json:"posts,omitempty" yaml:"posts,omitempty"`
+	// AccessLog is the file requests are logged to in Common
+	// Log Format. If empty requests are logged to standard out.
+	AccessLog string `json:"access_log,omitempty" yaml:"access_log,omitempty"`
+}
+
+// DefaultFingerService is finger, port 7079 on localhost.
+func DefaultFingerService() *FingerService {
+	fs := new(FingerService)
+	fs.DocRoot = "."
+	fs.Finger = &Service{
+		Scheme: "finger",
+		Host:   "localhost",
+		Port:   "7079",
+	}
+	fs.Posts = 5
+	return fs
+}
+
+// latestPosts returns up to n of the most recent, non-draft posts
+// with the YYYY-MM-DD they were posted.
+func latestPosts(meta *collection.Meta, n int) ([]*collection.PostObj, []string) {
+	posts, dates := []*collection.PostObj{}, []string{}
+	years := append([]*collection.YearObj{}, meta.Years...)
+	sort.SliceStable(years, func(i, j int) bool { return years[i].Year > years[j].Year })
+	for _, yr := range years {
+		months := append([]*collection.MonthObj{}, yr.Months...)
+		sort.SliceStable(months, func(i, j int) bool { return months[i].Month > months[j].Month })
+		for _, mn := range months {
+			days := append([]*collection.DayObj{}, mn.Days...)
+			sort.SliceStable(days, func(i, j int) bool { return days[i].Day > days[j].Day })
+			for _, dy := range days {
+				for _, post := range dy.Posts {
+					if post.Draft {
+						continue
+					}
+					if len(posts) >= n {
+						return posts, dates
+					}
+					posts = append(posts, post)
+					dates = append(dates, strings.Join([]string{yr.Year, mn.Month, dy.Day}, "-"))
+				}
+			}
+		}
+	}
+	return posts, dates
+}
+
+// users lists the user directories holding a ".plan".
+func (fs *FingerService) users() []string {
+	users := []string{}
+	entries, err := os.ReadDir(fs.DocRoot)
+	if err != nil {
+		return users
+	}
+	for _, entry := range entries {
+		if entry.IsDir() && fingerUserExp.MatchString(entry.Name()) {
+			if _, err := os.Stat(filepath.Join(fs.DocRoot, entry.Name(), ".plan")); err == nil {
+				users = append(users, entry.Name())
+			}
+		}
+	}
+	sort.Strings(users)
+	return users
+}
+
+// Respond returns the response to a finger query (the request
+// line without its CRLF).
+func (fs *FingerService) Respond(query string) string {
+	lines := []string{}
+	query = strings.TrimSpace(query)
+	// "/W" asks for verbose output, we only have one form.
+	query = strings.TrimSpace(strings.TrimPrefix(query, "/W"))
+	switch {
+	case query == "":
+		users := fs.users()
+		if len(users) == 0 {
+			lines = append(lines, "No one is here.")
+		}
+		for _, user := range users {
+			lines = append(lines, user)
+		}
+	case strings.Contains(query, "@"):
+		lines = append(lines, "Finger forwarding service denied.")
+	case !fingerUserExp.MatchString(query):
+		lines = append(lines, fmt.Sprintf("%s: no such user.", query))
+	default:
+		dName := filepath.Join(fs.DocRoot, query)
+		plan, err := os.ReadFile(filepath.Join(dName, ".plan"))
+		if err != nil {
+			lines = append(lines, fmt.Sprintf("%s: no such user.", query))
+			break
+		}
+		lines = append(lines, fmt.Sprintf("Login: %s", query))
+		if project, err := os.ReadFile(filepath.Join(dName, ".project")); err == nil {
+			lines = append(lines, "Project:")
+			lines = append(lines, textLines(project)...)
+		}
+		lines = append(lines, "Plan:")
+		lines = append(lines, textLines(plan)...)
+		lines = append(lines, fs.phlogLines(dName)...)
+	}
+	return strings.Join(lines, "\r\n") + "\r\n"
+}
+
+// textLines splits a text file into lines.
+func textLines(src []byte) []string {
+	txt := strings.TrimRight(strings.ReplaceAll(string(src), "\r\n", "\n"), "\n")
+	return strings.Split(txt, "\n")
+}
+
+// phlogLines lists the latest phlog posts for a user directory.
+func (fs *FingerService) phlogLines(dName string) []string {
+	fName := fs.Phlog
+	if fName == "" {
+		fName = filepath.Join(dName, "phlog.json")
+	}
+	if _, err := os.Stat(fName); err != nil {
+		return nil
+	}
+	meta := new(phlogit.PhlogMeta)
+	if err := phlogit.LoadPhlogMeta(fName, meta); err != nil {
+		log.Printf("%s", err)
+		return nil
+	}
+	n := fs.Posts
+	if n <= 0 {
+		n = 5
+	}
+	posts, dates := latestPosts(&meta.Meta, n)
+	if len(posts) == 0 {
+		return nil
+	}
+	lines := []string{"", "Latest posts:"}
+	for i, post := range posts {
+		title := post.Title
+		if title == "" {
+			title = post.Slug
+		}
+		lines = append(lines, fmt.Sprintf("  %s %s", dates[i], title))
+		if meta.BaseURL != "" {
+			ymd := strings.ReplaceAll(dates[i], "-", "/")
+			lines = append(lines, fmt.Sprintf("    %s", strings.TrimSuffix(meta.BaseURL, "/")+"/"+path.Join(ymd, path.Base(post.Document))))
+		}
+	}
+	return lines
+}
+
+// serve answers a single finger connection.
+func (fs *FingerService) serve(conn net.Conn) {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(30 * time.Second))
+	reader := bufio.NewReader(io.LimitReader(conn, 1024))
+	query, err := reader.ReadString('\n')
+	if err != nil && err != io.EOF {
+		return
+	}
+	io.WriteString(conn, fs.Respond(strings.TrimRight(query, "\r\n")))
+}
+
+// Run starts the finger service.
+func (fs *FingerService) Run() error {
+	if fs.Finger == nil {
+		fs.Finger = DefaultFingerService().Finger
+	}
+	var accessLog io.Writer = os.Stdout
+	if fs.AccessLog != "" {
+		fp, err := os.OpenFile(fs.AccessLog, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0664)
+		if err != nil {
+			return err
+		}
+		defer fp.Close()
+		accessLog = fp
+	}
+	ln, err := net.Listen("tcp", fs.Finger.Hostname())
+	if err != nil {
+		return err
+	}
+	log.Printf("Document root %s", fs.DocRoot)
+	log.Printf("Listening for %s", fs.Finger.String())
+	al := NewAccessListener(ln, accessLog)
+	defer al.Close()
+	for {
+		conn, err := al.Accept()
+		if err != nil {
+			return err
+		}
+		go fs.serve(conn)
+	}
+}
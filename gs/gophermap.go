@@ -0,0 +1,343 @@
+// pttk is software for working with plain text content.
+// Copyright (C) 2022 R. S. Doiel
+//
+// This program is free software: you can redistribute it and/or modify it under the terms of the GNU Affero General Public License as published by the Free Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License along with this program. If not, see <https://www.gnu.org/licenses/>.
+package gs
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	// My packages
+	"github.com/rsdoiel/pttk/ws"
+
+	// 3rd Party packages
+	"git.mills.io/prologic/go-gopher"
+)
+
+const (
+	// GophermapName is the file read for a directory's menu.
+	GophermapName = "gophermap"
+
+	// maxIncludeDepth limits how deeply "=file" includes are followed.
+	maxIncludeDepth = 8
+)
+
+// GopherHandler serves the files and directories of a document
+// root. Gophermaps are parsed the way Gophernicus parses them so
+// a preview matches what a production server would send.
+type GopherHandler struct {
+	// DocRoot is the directory holding the Gopher hole.
+	DocRoot string
+	// SearchSelector is the type 7 selector answered from Index,
+	// e.g. "/search". If empty search is not available.
+	SearchSelector string
+	// Index holds the full text index used for searches.
+	Index *Index
+	// GopherPlus enables Gopher+ attribute requests ("!").
+	GopherPlus bool
+	// SortBy orders the menus generated for directories without a
+	// gophermap, "name" (default), "title", "date" or "size".
+	SortBy string
+}
+
+// NewGopherHandler returns a *GopherHandler for docRoot.
+func NewGopherHandler(docRoot string) *GopherHandler {
+	h := new(GopherHandler)
+	h.DocRoot = docRoot
+	return h
+}
+
+// menu accumulates the items of a gophermap as it is parsed.
+type menu struct {
+	items []*gopher.Item
+	// hidden holds the names listed with "-" in the gophermap,
+	// they are left out of "*" listings.
+	hidden map[string]bool
+	// stop is set by a "." line or a "*" listing.
+	stop bool
+}
+
+// fsPath maps a selector to a path in the document root.
+func (h *GopherHandler) fsPath(selector string) string {
+	docRoot := h.DocRoot
+	if docRoot == "" {
+		docRoot = "."
+	}
+	return filepath.Join(docRoot, filepath.FromSlash(path.Clean("/"+selector)))
+}
+
+// inDocRoot checks that the path p is inside the document root.
+func (h *GopherHandler) inDocRoot(p string) bool {
+	docRoot := h.DocRoot
+	if docRoot == "" {
+		docRoot = "."
+	}
+	rel, err := filepath.Rel(docRoot, filepath.Clean(p))
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// infoItem returns an informational menu line.
+func infoItem(msg string) *gopher.Item {
+	return &gopher.Item{
+		Type:        gopher.INFO,
+		Description: msg,
+		Selector:    "",
+		Host:        "error.host",
+		Port:        1,
+	}
+}
+
+// itemType returns the Gopher item type for a file. Markdown and
+// plain text are always text to a Gopher client.
+func itemType(fName string, info os.FileInfo) gopher.ItemType {
+	if info.IsDir() {
+		return gopher.DIRECTORY
+	}
+	if ext := path.Ext(info.Name()); ext == ".md" || ext == ".txt" || ext == "" {
+		return gopher.FILE
+	}
+	return gopher.GetItemType(fName)
+}
+
+// parseLine converts a single gophermap line into a menu item.
+// dirSelector is the selector of the directory holding the gophermap,
+// host and port describe this server.
+func parseLine(line string, dirSelector string, host string, port int) *gopher.Item {
+	if !strings.Contains(line, "\t") {
+		return infoItem(line)
+	}
+	parts := strings.Split(line, "\t")
+	if len(parts[0]) == 0 {
+		return infoItem("")
+	}
+	item := new(gopher.Item)
+	item.Type = gopher.ItemType(parts[0][0])
+	item.Description = parts[0][1:]
+	if len(parts) > 1 {
+		item.Selector = parts[1]
+	}
+	if len(parts) > 2 {
+		item.Host = parts[2]
+	}
+	if len(parts) > 3 {
+		item.Port, _ = strconv.Atoi(parts[3])
+	}
+	if len(parts) > 4 {
+		item.Extras = parts[4:]
+	}
+	// An empty selector refers to the display name
+	if item.Selector == "" {
+		item.Selector = item.Description
+	}
+	if item.Host == "" {
+		// Relative selectors are resolved against the directory
+		// of the gophermap when the item is on this server.
+		if !strings.HasPrefix(item.Selector, "/") && !strings.HasPrefix(item.Selector, "URL:") {
+			item.Selector = path.Join(dirSelector, item.Selector)
+			if !strings.HasPrefix(item.Selector, "/") {
+				item.Selector = "/" + item.Selector
+			}
+		}
+		item.Host = host
+		if item.Port == 0 {
+			item.Port = port
+		}
+	} else if item.Port == 0 {
+		item.Port = 70
+	}
+	return item
+}
+
+// parseGophermap reads fName adding its items to m. Supported lines
+// follow Gophernicus,
+//
+//	# a comment, not shown
+//	!title shown as an informational line
+//	-name hides name from a "*" listing
+//	=file includes another gophermap (or a directory's gophermap)
+//	* appends a listing of the directory and stops
+//	. stops reading the gophermap
+//
+// Lines without a tab are informational, lines with a tab are
+// menu items with host and port filled in when missing.
+func (h *GopherHandler) parseGophermap(m *menu, fName string, dirSelector string, host string, port int, depth int) error {
+	src, err := os.ReadFile(fName)
+	if err != nil {
+		return err
+	}
+	txt := strings.ReplaceAll(string(src), "\r\n", "\n")
+	lines := strings.Split(strings.TrimSuffix(txt, "\n"), "\n")
+	for _, line := range lines {
+		if m.stop {
+			break
+		}
+		switch {
+		case strings.HasPrefix(line, "#"):
+			// Comment
+		case strings.HasPrefix(line, "!"):
+			m.items = append(m.items, infoItem(strings.TrimPrefix(line, "!")))
+		case strings.HasPrefix(line, "-") && !strings.Contains(line, "\t"):
+			m.hidden[strings.TrimPrefix(line, "-")] = true
+		case strings.HasPrefix(line, "=") && !strings.Contains(line, "\t"):
+			if depth >= maxIncludeDepth {
+				return fmt.Errorf("%q, too many nested includes", fName)
+			}
+			target := strings.TrimSpace(strings.TrimPrefix(line, "="))
+			incName := filepath.Join(filepath.Dir(fName), filepath.FromSlash(target))
+			if strings.HasPrefix(target, "/") {
+				incName = h.fsPath(target)
+			}
+			// A relative include can't leave the document root
+			if !h.inDocRoot(incName) {
+				return fmt.Errorf("%q, include %q is outside the document root", fName, target)
+			}
+			if info, err := os.Stat(incName); err == nil && info.IsDir() {
+				incName = filepath.Join(incName, GophermapName)
+			}
+			if err := h.parseGophermap(m, incName, dirSelector, host, port, depth+1); err != nil {
+				return err
+			}
+		case line == "*":
+			if err := h.dirListing(m, dirSelector, host, port); err != nil {
+				return err
+			}
+			m.stop = true
+		case line == ".":
+			m.stop = true
+		default:
+			m.items = append(m.items, parseLine(line, dirSelector, host, port))
+		}
+	}
+	return nil
+}
+
+// dirListing appends an item for each entry of the directory named
+// by dirSelector. Dot files, gophermaps and hidden names are skipped.
+func (h *GopherHandler) dirListing(m *menu, dirSelector string, host string, port int) error {
+	dName := h.fsPath(dirSelector)
+	entries, err := os.ReadDir(dName)
+	if err != nil {
+		return err
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+	for _, entry := range entries {
+		name := entry.Name()
+		if strings.HasPrefix(name, ".") || name == GophermapName || m.hidden[name] {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		item := new(gopher.Item)
+		item.Type = itemType(filepath.Join(dName, name), info)
+		item.Description = name
+		item.Selector = path.Join("/", dirSelector, name)
+		item.Host = host
+		item.Port = port
+		m.items = append(m.items, item)
+	}
+	return nil
+}
+
+// Menu returns the menu items for a directory selector. The year,
+// month and day directories of a phlog or blog (a directory holding
+// phlog.json or blog.json) get menus generated from its metadata.
+// Otherwise if the directory has a gophermap it is parsed or a
+// listing of the directory is generated (see generatedListing).
+func (h *GopherHandler) Menu(selector string, host string, port int) ([]*gopher.Item, error) {
+	selector = path.Clean("/" + selector)
+	m := new(menu)
+	m.hidden = map[string]bool{}
+	gophermap := filepath.Join(h.fsPath(selector), GophermapName)
+	_, err := os.Stat(gophermap)
+	hasGophermap := (err == nil)
+	cm, dirSelector, dateParts, err := h.findCollection(selector)
+	if err != nil {
+		return nil, err
+	}
+	// A collection's own gophermap is kept as its landing page.
+	if cm != nil && (len(dateParts) > 0 || !hasGophermap) {
+		return h.collectionMenu(cm, dirSelector, dateParts, host, port)
+	}
+	if hasGophermap {
+		if err := h.parseGophermap(m, gophermap, selector, host, port, 0); err != nil {
+			return nil, err
+		}
+		return m.items, nil
+	}
+	return h.generatedListing(selector, host, port)
+}
+
+// ServeGopher implements gopher.Handler. A selector may be followed
+// by a tab and a search query (type 7) or, with Gopher+ enabled, a
+// tab and "!" to request the attributes of the selector.
+func (h *GopherHandler) ServeGopher(w gopher.ResponseWriter, r *gopher.Request) {
+	parts := strings.Split(r.Selector, "\t")
+	selector := parts[0]
+	// Dot files are not served, like ws does for HTTP.
+	if ws.IsDotPath(selector) {
+		gopher.NotFound(w, r)
+		return
+	}
+	if h.Index != nil && h.SearchSelector != "" && path.Clean(selector) == path.Clean(h.SearchSelector) {
+		query := ""
+		if len(parts) > 1 {
+			query = parts[1]
+		}
+		for _, item := range h.searchMenu(query, r.LocalHost, r.LocalPort) {
+			w.WriteItem(item)
+		}
+		return
+	}
+	if h.GopherPlus && len(parts) > 1 && strings.HasPrefix(parts[1], "!") {
+		names := strings.Fields(strings.TrimPrefix(parts[1], "!"))
+		block, err := h.attributes(selector, names, r.LocalHost, r.LocalPort)
+		if err != nil {
+			w.Write([]byte("--2" + gopher.CRLF + err.Error() + gopher.CRLF))
+			return
+		}
+		w.Write([]byte("+-2" + gopher.CRLF + block))
+		return
+	}
+	fName := h.fsPath(selector)
+	info, err := os.Stat(fName)
+	if err != nil {
+		gopher.NotFound(w, r)
+		return
+	}
+	if info.IsDir() {
+		items, err := h.Menu(selector, r.LocalHost, r.LocalPort)
+		if err != nil {
+			gopher.Error(w, err.Error())
+			return
+		}
+		for _, item := range items {
+			w.WriteItem(item)
+		}
+		return
+	}
+	fp, err := os.Open(fName)
+	if err != nil {
+		gopher.Error(w, err.Error())
+		return
+	}
+	defer fp.Close()
+	io.Copy(w, fp)
+}
@@ -9,26 +9,38 @@
 package gs
 
 import (
+	"crypto/tls"
 	"encoding/json"
 	"fmt"
+	"io"
 	"log"
+	"net"
 	"os"
 	"strings"
 
 	// 3rd Party packages
 	"git.mills.io/prologic/go-gopher"
+	"gopkg.in/yaml.v3"
 )
 
 // Service holds the description needed to startup a service
-// e.g. https, http.
+// e.g. gopher, gophers (Gopher over TLS).
 type Service struct {
 	// Scheme holds the protocol to use, defaults to gopher if not set.
-	Scheme string `json:"scheme,omitempty" `
+	// Use "gophers" for Gopher over TLS.
+	Scheme string `json:"scheme,omitempty" yaml:"scheme,omitempty"`
 	// Host is the hostname to use, if empty "localhost" is assumed"
-	Host string `json:"host,omitempty" `
+	Host string `json:"host,omitempty" yaml:"host,omitempty"`
 	// Port is a string holding the port number to listen on
 	// An empty strings defaults port to 7000
-	Port string `json:"port,omitempty" `
+	Port string `json:"port,omitempty" yaml:"port,omitempty"`
+	// CertPEM describes the location of cert.pem used for TLS support
+	CertPEM string `json:"cert_pem,omitempty" yaml:"cert_pem,omitempty"`
+	// KeyPEM describes the location of the key.pem used for TLS support
+	KeyPEM string `json:"key_pem,omitempty" yaml:"key_pem,omitempty"`
+	// DocRoot overrides the GopherService's document root for
+	// this listener.
+	DocRoot string `json:"htdocs,omitempty" yaml:"htdocs,omitempty"`
 }
 
 func DefaultService() *Service {
@@ -66,9 +78,24 @@ func (s *Service) Hostname() string {
 type GopherService struct {
 	// This is the document root for static file services
 	// If an empty string then assume current working directory.
-	DocRoot string `json:"htdocs,omitempty"`
+	DocRoot string `json:"htdocs,omitempty" yaml:"htdocs,omitempty"`
 	// Gopher holds the service description points a *Service
-	Gopher *Service `json:"gopher,omitempty"`
+	Gopher *Service `json:"gopher,omitempty" yaml:"gopher,omitempty"`
+	// Gophers describes a Gopher over TLS service
+	Gophers *Service `json:"gophers,omitempty" yaml:"gophers,omitempty"`
+	// Listeners describes any additional services to run
+	Listeners []*Service `json:"listeners,omitempty" yaml:"listeners,omitempty"`
+	// AccessLog is the file requests are logged to in Common
+	// Log Format. If empty requests are logged to standard out.
+	AccessLog string `json:"access_log,omitempty" yaml:"access_log,omitempty"`
+	// Search is the type 7 selector answered from a full text
+	// index of DocRoot, e.g. "/search". Empty disables search.
+	Search string `json:"search,omitempty" yaml:"search,omitempty"`
+	// GopherPlus enables Gopher+ attribute requests.
+	GopherPlus bool `json:"gopher_plus,omitempty" yaml:"gopher_plus,omitempty"`
+	// Sort orders the menus generated for directories without a
+	// gophermap, "name" (default), "title", "date" or "size".
+	Sort string `json:"sort,omitempty" yaml:"sort,omitempty"`
 }
 
 // DefaultGopherService is gopher, port 7000 on localhost.
@@ -79,37 +106,38 @@ func DefaultGopherService() *GopherService {
 	return gs
 }
 
-// LoadGopherService loads a configuration file of *Service
+// LoadGopherService loads a configuration file of *GopherService,
+// the file may be JSON or YAML.
 func LoadGopherService(setup string) (*GopherService, error) {
-	var (
-		gs  *GopherService
-		err error
-	)
-
+	src, err := os.ReadFile(setup)
+	if err != nil {
+		return nil, err
+	}
+	gs := new(GopherService)
 	switch {
 	case strings.HasSuffix(setup, ".json"):
-		gs, err = loadGopherServiceJSON(setup)
+		err = json.Unmarshal(src, &gs)
+	case strings.HasSuffix(setup, ".yaml") || strings.HasSuffix(setup, ".yml"):
+		err = yaml.Unmarshal(src, &gs)
 	default:
 		err = fmt.Errorf("%q, unknown format.", setup)
 	}
 	if err != nil {
 		return nil, err
 	}
-	return gs, err
-}
-
-// loadGopherServiceJSON loads a *GopherService from a JSON file.
-func loadGopherServiceJSON(setup string) (*GopherService, error) {
-	src, err := os.ReadFile(setup)
-	if err != nil {
-		return nil, err
+	if gs.Gopher == nil && gs.Gophers == nil && len(gs.Listeners) == 0 {
+		gs.Gopher = DefaultService()
 	}
-	gs := new(GopherService)
-	if err := json.Unmarshal(src, &gs); err != nil {
-		return nil, err
+	if gs.Gopher != nil && gs.Gopher.Scheme == "" {
+		gs.Gopher.Scheme = "gopher"
 	}
-	if gs.Gopher == nil {
-		gs.Gopher = DefaultService()
+	if gs.Gophers != nil {
+		gs.Gophers.Scheme = "gophers"
+	}
+	for _, s := range gs.Listeners {
+		if s.Scheme == "" {
+			s.Scheme = "gopher"
+		}
 	}
 	if gs.DocRoot == "" {
 		gs.DocRoot = "./"
@@ -117,7 +145,35 @@ func loadGopherServiceJSON(setup string) (*GopherService, error) {
 	return gs, nil
 }
 
-// Run() starts a web service(s) described in the *GopherService struct.
+// Services returns the list of services to run.
+func (gs *GopherService) Services() []*Service {
+	services := []*Service{}
+	if gs.Gopher != nil {
+		services = append(services, gs.Gopher)
+	}
+	if gs.Gophers != nil {
+		services = append(services, gs.Gophers)
+	}
+	return append(services, gs.Listeners...)
+}
+
+// listen opens the listener for a service, wrapping it with TLS
+// for "gophers".
+func listen(s *Service) (net.Listener, error) {
+	if s.Scheme != "gophers" {
+		return net.Listen("tcp", s.Hostname())
+	}
+	if s.CertPEM == "" || s.KeyPEM == "" {
+		return nil, fmt.Errorf("%s requires cert_pem and key_pem", s.String())
+	}
+	cert, err := tls.LoadX509KeyPair(s.CertPEM, s.KeyPEM)
+	if err != nil {
+		return nil, err
+	}
+	return tls.Listen("tcp", s.Hostname(), &tls.Config{Certificates: []tls.Certificate{cert}})
+}
+
+// Run() starts the gopher service(s) described in the *GopherService struct.
 func (gs *GopherService) Run() error {
 	var err error
 	if gs.DocRoot == "" {
@@ -127,9 +183,61 @@ func (gs *GopherService) Run() error {
 		}
 	}
 	log.Printf("Document root %s", gs.DocRoot)
-	if gs.Gopher != nil {
-		log.Printf("Listening for %s", gs.Gopher.String())
+	if gs.Sort != "" && !IsSortOrder(gs.Sort) {
+		return fmt.Errorf("%q is not a sort order, expected name, title, date or size", gs.Sort)
+	}
+
+	var accessLog io.Writer = os.Stdout
+	if gs.AccessLog != "" {
+		fp, err := os.OpenFile(gs.AccessLog, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0664)
+		if err != nil {
+			return err
+		}
+		defer fp.Close()
+		accessLog = fp
+	}
+
+	// Each document root gets one handler (and index).
+	handlers := map[string]*GopherHandler{}
+	services := gs.Services()
+	errCh := make(chan error, len(services))
+	for _, s := range services {
+		docRoot := gs.DocRoot
+		if s.DocRoot != "" {
+			docRoot = s.DocRoot
+		}
+		handler, ok := handlers[docRoot]
+		if !ok {
+			handler = NewGopherHandler(docRoot)
+			handler.GopherPlus = gs.GopherPlus
+			handler.SortBy = gs.Sort
+			if gs.Search != "" {
+				log.Printf("Indexing %s", docRoot)
+				handler.Index, err = BuildIndex(docRoot)
+				if err != nil {
+					return err
+				}
+				handler.SearchSelector = gs.Search
+				log.Printf("Search %d documents at %s", len(handler.Index.Docs), gs.Search)
+			}
+			handlers[docRoot] = handler
+		}
+		ln, err := listen(s)
+		if err != nil {
+			return err
+		}
+		log.Printf("Listening for %s (%s)", s.String(), docRoot)
+		server := &gopher.Server{
+			Addr:     s.Hostname(),
+			Handler:  handler,
+			Hostname: s.Host,
+		}
+		go func() {
+			errCh <- server.Serve(NewAccessListener(ln, accessLog))
+		}()
+	}
+	if len(services) == 0 {
+		return fmt.Errorf("no gopher services to run")
 	}
-	gopher.Handle("/", gopher.FileServer(gopher.Dir(gs.DocRoot)))
-	return gopher.ListenAndServe(gs.Gopher.Hostname(), nil)
+	return <-errCh
 }
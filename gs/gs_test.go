@@ -0,0 +1,537 @@
+// pttk is software for working with plain text content.
+// Copyright (C) 2022 R. S. Doiel
+//
+// This program is free software: you can redistribute it and/or modify it under the terms of the GNU Affero General Public License as published by the Free Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License along with this program. If not, see <https://www.gnu.org/licenses/>.
+package gs
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	// My packages
+	"github.com/rsdoiel/pttk/phlogit"
+
+	// 3rd Party packages
+	"git.mills.io/prologic/go-gopher"
+)
+
+func menuText(t *testing.T, items []*gopher.Item) string {
+	d := gopher.Directory{Items: items}
+	src, err := d.ToText()
+	if err != nil {
+		t.Errorf("ToText() failed, %s", err)
+	}
+	return string(src)
+}
+
+func TestGophermap(t *testing.T) {
+	root := t.TempDir()
+	os.MkdirAll(path.Join(root, "phlog"), 0775)
+	os.MkdirAll(path.Join(root, "auto"), 0775)
+	os.WriteFile(path.Join(root, "about.txt"), []byte("about\n"), 0664)
+	os.WriteFile(path.Join(root, "secret.txt"), []byte("secret\n"), 0664)
+	os.WriteFile(path.Join(root, ".hidden"), []byte("hidden\n"), 0664)
+	os.WriteFile(path.Join(root, "banner"), []byte("Welcome\n"), 0664)
+	os.WriteFile(path.Join(root, "auto", "b.md"), []byte("b\n"), 0664)
+	os.WriteFile(path.Join(root, "auto", "a.txt"), []byte("a\n"), 0664)
+	os.WriteFile(path.Join(root, "phlog", "gophermap"), []byte("1Phlog\t\r\n0First post\tfirst.txt\r\n"), 0664)
+	os.WriteFile(path.Join(root, "gophermap"), []byte(strings.Join([]string{
+		"# not shown",
+		"=banner",
+		"!My Hole",
+		"0About\tabout.txt",
+		"1Elsewhere\t/\texample.org",
+		"hMy site\tURL:https://example.org",
+		"-secret.txt",
+		"-banner",
+		"*",
+		"0Never shown\tnever.txt",
+	}, "\n")), 0664)
+
+	h := NewGopherHandler(root)
+	items, err := h.Menu("/", "localhost", 7000)
+	if err != nil {
+		t.Errorf("expected nil, got %s", err)
+		t.FailNow()
+	}
+	expected := strings.Join([]string{
+		"iWelcome\t\terror.host\t1",
+		"iMy Hole\t\terror.host\t1",
+		"0About\t/about.txt\tlocalhost\t7000",
+		"1Elsewhere\t/\texample.org\t70",
+		"hMy site\tURL:https://example.org\tlocalhost\t7000",
+		"0about.txt\t/about.txt\tlocalhost\t7000",
+		"1auto\t/auto\tlocalhost\t7000",
+		"1phlog\t/phlog\tlocalhost\t7000",
+		"",
+	}, "\r\n")
+	if got := menuText(t, items); got != expected {
+		t.Errorf("expected\n%q\ngot\n%q", expected, got)
+	}
+
+	// Relative selectors in a sub directory
+	items, err = h.Menu("/phlog", "localhost", 7000)
+	if err != nil {
+		t.Errorf("expected nil, got %s", err)
+		t.FailNow()
+	}
+	expected = strings.Join([]string{
+		"1Phlog\t/phlog/Phlog\tlocalhost\t7000",
+		"0First post\t/phlog/first.txt\tlocalhost\t7000",
+		"",
+	}, "\r\n")
+	if got := menuText(t, items); got != expected {
+		t.Errorf("expected\n%q\ngot\n%q", expected, got)
+	}
+
+	// Includes can't leave the document root
+	outside := path.Join(path.Dir(root), path.Base(root)+"-outside")
+	os.WriteFile(outside, []byte("Not for you\n"), 0664)
+	defer os.Remove(outside)
+	os.MkdirAll(path.Join(root, "escape"), 0775)
+	for _, target := range []string{"../../" + path.Base(outside), "../escape/../../" + path.Base(outside)} {
+		os.WriteFile(path.Join(root, "escape", "gophermap"), []byte("="+target+"\n"), 0664)
+		if items, err = h.Menu("/escape", "localhost", 7000); err == nil {
+			t.Errorf("expected an error including %q, got %q", target, menuText(t, items))
+		}
+	}
+
+	// A directory without a gophermap gets a generated menu
+	modTime := time.Date(2022, time.July, 15, 9, 30, 0, 0, time.Local)
+	os.Chtimes(path.Join(root, "auto", "a.txt"), modTime, modTime)
+	os.Chtimes(path.Join(root, "auto", "b.md"), modTime, modTime)
+	items, err = h.Menu("/auto", "localhost", 7000)
+	if err != nil {
+		t.Errorf("expected nil, got %s", err)
+		t.FailNow()
+	}
+	expected = strings.Join([]string{
+		"0a.txt\t/auto/a.txt\tlocalhost\t7000",
+		"i  2 B, 2022-07-15 09:30\t\terror.host\t1",
+		"0b.md\t/auto/b.md\tlocalhost\t7000",
+		"i  2 B, 2022-07-15 09:30\t\terror.host\t1",
+		"",
+	}, "\r\n")
+	if got := menuText(t, items); got != expected {
+		t.Errorf("expected\n%q\ngot\n%q", expected, got)
+	}
+}
+
+func TestGeneratedListing(t *testing.T) {
+	root := t.TempDir()
+	os.WriteFile(path.Join(root, "old.txt"), []byte(strings.Repeat("o", 4096)), 0664)
+	os.WriteFile(path.Join(root, "new.md"), []byte("---\ntitle: A New Post\n---\n\n"+strings.Repeat("x", 2048)), 0664)
+	os.WriteFile(path.Join(root, ".plan"), []byte("hidden\n"), 0664)
+	os.Chtimes(path.Join(root, "old.txt"), time.Date(2021, time.January, 2, 3, 4, 0, 0, time.Local), time.Date(2021, time.January, 2, 3, 4, 0, 0, time.Local))
+	os.Chtimes(path.Join(root, "new.md"), time.Date(2022, time.July, 15, 9, 30, 0, 0, time.Local), time.Date(2022, time.July, 15, 9, 30, 0, 0, time.Local))
+
+	h := NewGopherHandler(root)
+	for _, sortBy := range []string{SortByName, SortByTitle, SortByDate, SortBySize} {
+		h.SortBy = sortBy
+		items, err := h.Menu("/", "localhost", 7000)
+		if err != nil {
+			t.Errorf("%s, expected nil, got %s", sortBy, err)
+			continue
+		}
+		newItems := []string{
+			"0A New Post\t/new.md\tlocalhost\t7000",
+			"i  new.md, 2.0 KB, 2022-07-15 09:30\t\terror.host\t1",
+		}
+		oldItems := []string{
+			"0old.txt\t/old.txt\tlocalhost\t7000",
+			"i  4.0 KB, 2021-01-02 03:04\t\terror.host\t1",
+		}
+		expected := strings.Join(append(append(newItems, oldItems...), ""), "\r\n")
+		if sortBy == SortBySize {
+			expected = strings.Join(append(append(oldItems, newItems...), ""), "\r\n")
+		}
+		if got := menuText(t, items); got != expected {
+			t.Errorf("%s, expected\n%q\ngot\n%q", sortBy, expected, got)
+		}
+	}
+
+	for size, expected := range map[int64]string{
+		0:               "0 B",
+		1023:            "1023 B",
+		1536:            "1.5 KB",
+		3 * 1024 * 1024: "3.0 MB",
+	} {
+		if got := HumanSize(size); got != expected {
+			t.Errorf("HumanSize(%d), expected %q, got %q", size, expected, got)
+		}
+	}
+
+	// Dot files are not served
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("can't listen, %s", err)
+	}
+	defer ln.Close()
+	server := &gopher.Server{Handler: h}
+	go server.Serve(ln)
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Errorf("can't connect, %s", err)
+		t.FailNow()
+	}
+	conn.Write([]byte("/.plan\r\n"))
+	src, _ := io.ReadAll(conn)
+	conn.Close()
+	if !strings.HasPrefix(string(src), "3") {
+		t.Errorf("expected an error item for a dot file, got %q", src)
+	}
+}
+
+func TestSearch(t *testing.T) {
+	root := t.TempDir()
+	os.MkdirAll(path.Join(root, "phlog", "2022"), 0775)
+	os.MkdirAll(path.Join(root, ".git"), 0775)
+	os.WriteFile(path.Join(root, "phlog", "2022", "oberon.md"), []byte("---\ntitle: Turbo Oberon\nabstract: A dream about a language\n---\n\nOberon, Oberon and Go.\n"), 0664)
+	os.WriteFile(path.Join(root, "go.txt"), []byte("Notes on Go and Oberon.\n"), 0664)
+	os.WriteFile(path.Join(root, "pascal.txt"), []byte("Turbo Pascal\n"), 0664)
+	os.WriteFile(path.Join(root, ".git", "oberon"), []byte("oberon\n"), 0664)
+
+	idx, err := BuildIndex(root)
+	if err != nil {
+		t.Errorf("expected nil, got %s", err)
+		t.FailNow()
+	}
+	if len(idx.Docs) != 3 {
+		t.Errorf("expected 3 documents indexed, got %d", len(idx.Docs))
+	}
+	results := idx.Search("oberon GO")
+	if len(results) != 2 {
+		t.Errorf("expected 2 results, got %d", len(results))
+		t.FailNow()
+	}
+	if results[0].Selector != "/phlog/2022/oberon.md" || results[0].Title != "Turbo Oberon" {
+		t.Errorf("expected oberon.md to rank first, got %+v", results[0])
+	}
+	if results := idx.Search("smalltalk"); len(results) != 0 {
+		t.Errorf("expected no results, got %+v", results)
+	}
+
+	h := NewGopherHandler(root)
+	h.Index = idx
+	h.SearchSelector = "/search"
+	items := h.searchMenu("pascal", "localhost", 7000)
+	expected := strings.Join([]string{
+		"i1 document(s) found for \"pascal\"\t\terror.host\t1",
+		"0pascal.txt\t/pascal.txt\tlocalhost\t7000",
+		"",
+	}, "\r\n")
+	if got := menuText(t, items); got != expected {
+		t.Errorf("expected\n%q\ngot\n%q", expected, got)
+	}
+
+	block, err := h.attributes("/phlog/2022/oberon.md", []string{"+INFO", "+ABSTRACT"}, "localhost", 7000)
+	if err != nil {
+		t.Errorf("expected nil, got %s", err)
+		t.FailNow()
+	}
+	expected = strings.Join([]string{
+		"+INFO: 0Turbo Oberon\t/phlog/2022/oberon.md\tlocalhost\t7000\t+",
+		"+ABSTRACT:",
+		" A dream about a language",
+		"",
+	}, "\r\n")
+	if block != expected {
+		t.Errorf("expected\n%q\ngot\n%q", expected, block)
+	}
+}
+
+func TestLoadGopherService(t *testing.T) {
+	dName := t.TempDir()
+	fName := path.Join(dName, "gs.yaml")
+	os.WriteFile(fName, []byte(`htdocs: htdocs
+access_log: access.log
+gophers:
+  host: localhost
+  port: "7443"
+  cert_pem: cert.pem
+  key_pem: key.pem
+listeners:
+  - host: localhost
+    port: "7070"
+    htdocs: preview
+`), 0664)
+	gs, err := LoadGopherService(fName)
+	if err != nil {
+		t.Errorf("expected nil, got %s", err)
+		t.FailNow()
+	}
+	if gs.Gopher != nil {
+		t.Errorf("expected no default gopher service, got %+v", gs.Gopher)
+	}
+	services := gs.Services()
+	if len(services) != 2 {
+		t.Errorf("expected two services, got %d", len(services))
+		t.FailNow()
+	}
+	if s := services[0].String(); s != "gophers://localhost:7443" {
+		t.Errorf("expected gophers://localhost:7443, got %q", s)
+	}
+	if s := services[1]; s.String() != "gopher://localhost:7070" || s.DocRoot != "preview" {
+		t.Errorf("expected gopher://localhost:7070 serving preview, got %+v", s)
+	}
+	if gs.AccessLog != "access.log" {
+		t.Errorf("expected access.log, got %q", gs.AccessLog)
+	}
+	if _, err := listen(services[0]); err == nil {
+		t.Errorf("expected an error for missing cert and key")
+	}
+}
+
+// syncBuffer is a bytes.Buffer safe to share with the server.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestAccessLog(t *testing.T) {
+	root := t.TempDir()
+	os.WriteFile(path.Join(root, "hello.txt"), []byte("Hello World!\n"), 0664)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("can't listen, %s", err)
+	}
+	out := new(syncBuffer)
+	server := &gopher.Server{Handler: NewGopherHandler(root)}
+	go server.Serve(NewAccessListener(ln, out))
+
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Errorf("can't connect, %s", err)
+		t.FailNow()
+	}
+	conn.Write([]byte("/hello.txt\r\n"))
+	src, _ := io.ReadAll(conn)
+	conn.Close()
+	if string(src) != "Hello World!\n" {
+		t.Errorf("expected Hello World!, got %q", src)
+	}
+	for i := 0; i < 50 && out.String() == ""; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	entry := out.String()
+	if !strings.HasPrefix(entry, "127.0.0.1 - - [") || !strings.HasSuffix(entry, "] \"/hello.txt\" 200 13\n") {
+		t.Errorf("unexpected access log entry %q", entry)
+	}
+	ln.Close()
+}
+
+func TestCollectionMenu(t *testing.T) {
+	root := t.TempDir()
+	phlogDir := path.Join(root, "phlog")
+	os.MkdirAll(phlogDir, 0775)
+	// A stale static gophermap should be ignored
+	os.MkdirAll(path.Join(phlogDir, "2022", "07"), 0775)
+	os.WriteFile(path.Join(phlogDir, "2022", "gophermap"), []byte("stale\n"), 0664)
+	for _, day := range []string{"04", "15"} {
+		fName := path.Join(root, "post-"+day+".md")
+		os.WriteFile(fName, []byte("---\ntitle: Post "+day+"\ndescription: About day "+day+"\n---\n\nHello\n"), 0664)
+	}
+	meta := new(phlogit.PhlogMeta)
+	meta.Name = "My Phlog"
+	for _, day := range []string{"04", "15"} {
+		if err := meta.PhlogIt(phlogDir, path.Join(root, "post-"+day+".md"), "2022-07-"+day); err != nil {
+			t.Errorf("PhlogIt() failed, %s", err)
+			t.FailNow()
+		}
+	}
+	if err := meta.Save(path.Join(phlogDir, "phlog.json")); err != nil {
+		t.Errorf("Save() failed, %s", err)
+		t.FailNow()
+	}
+
+	h := NewGopherHandler(root)
+	for _, test := range []struct {
+		selector string
+		expected []string
+	}{
+		{"/phlog", []string{
+			"iMy Phlog\t\terror.host\t1",
+			"i\t\terror.host\t1",
+			"12022\t/phlog/2022\tlocalhost\t7000",
+		}},
+		{"/phlog/2022", []string{
+			"iPosts for 2022\t\terror.host\t1",
+			"i\t\terror.host\t1",
+			"1July 2022\t/phlog/2022/07\tlocalhost\t7000",
+		}},
+		{"/phlog/2022/07/15", []string{
+			"iJuly 2022\t\terror.host\t1",
+			"i\t\terror.host\t1",
+			"0Post 15\t/phlog/2022/07/15/post-15.md\tlocalhost\t7000",
+			"i  About day 15\t\terror.host\t1",
+		}},
+	} {
+		items, err := h.Menu(test.selector, "localhost", 7000)
+		if err != nil {
+			t.Errorf("%s, expected nil, got %s", test.selector, err)
+			continue
+		}
+		expected := strings.Join(append(test.expected, ""), "\r\n")
+		if got := menuText(t, items); got != expected {
+			t.Errorf("%s, expected\n%q\ngot\n%q", test.selector, expected, got)
+		}
+	}
+}
+
+func TestGopherClient(t *testing.T) {
+	item, err := ParseGopherURL("gopher://example.org/0/phlog/post.txt")
+	if err != nil {
+		t.Errorf("expected nil, got %s", err)
+		t.FailNow()
+	}
+	if item.Type != gopher.FILE || item.Host != "example.org" || item.Port != 70 || item.Selector != "/phlog/post.txt" {
+		t.Errorf("unexpected item %+v", item)
+	}
+	if u := ItemURL(item); u != "gopher://example.org:70/0/phlog/post.txt" {
+		t.Errorf("unexpected URL %q", u)
+	}
+	if item, _ = ParseGopherURL("localhost:7000"); item.Type != gopher.DIRECTORY || item.Port != 7000 {
+		t.Errorf("expected a menu on port 7000, got %+v", item)
+	}
+
+	// Crawl a hole served by GopherHandler
+	root := t.TempDir()
+	os.MkdirAll(path.Join(root, "phlog"), 0775)
+	os.MkdirAll(path.Join(root, "other"), 0775)
+	os.WriteFile(path.Join(root, "phlog", "gophermap"), []byte("!My phlog\n0A post\tpost.txt\n1Elsewhere\t/\texample.org\n"), 0664)
+	os.WriteFile(path.Join(root, "phlog", "post.txt"), []byte("Hello World!\n"), 0664)
+	os.WriteFile(path.Join(root, "other", "skipped.txt"), []byte("not crawled\n"), 0664)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("can't listen, %s", err)
+	}
+	defer ln.Close()
+	server := &gopher.Server{Handler: NewGopherHandler(root)}
+	go server.Serve(ln)
+
+	start, err := ParseGopherURL("gopher://" + ln.Addr().String() + "/1/phlog")
+	if err != nil {
+		t.Errorf("expected nil, got %s", err)
+		t.FailNow()
+	}
+	mirror := t.TempDir()
+	crawler := &Crawler{Root: mirror}
+	if err := crawler.Crawl(start); err != nil {
+		t.Errorf("expected nil, got %s", err)
+		t.FailNow()
+	}
+	src, err := os.ReadFile(path.Join(mirror, "phlog", "gophermap"))
+	if err != nil {
+		t.Errorf("expected a gophermap, %s", err)
+		t.FailNow()
+	}
+	expected := "My phlog\r\n0A post\t/phlog/post.txt\r\n1Elsewhere\t/\texample.org\t70\r\n"
+	if string(src) != expected {
+		t.Errorf("expected %q, got %q", expected, src)
+	}
+	if src, err := os.ReadFile(path.Join(mirror, "phlog", "post.txt")); err != nil || string(src) != "Hello World!\n" {
+		t.Errorf("expected post.txt in the mirror, %q, %v", src, err)
+	}
+	if _, err := os.Stat(path.Join(mirror, "other")); err == nil {
+		t.Errorf("did not expect other to be crawled")
+	}
+}
+
+func TestFinger(t *testing.T) {
+	root := t.TempDir()
+	userDir := path.Join(root, "jane")
+	phlogDir := path.Join(root, "phlog")
+	os.MkdirAll(userDir, 0775)
+	os.MkdirAll(phlogDir, 0775)
+	os.WriteFile(path.Join(userDir, ".plan"), []byte("Writing more phlog posts.\n"), 0664)
+	os.WriteFile(path.Join(userDir, ".project"), []byte("pttk\n"), 0664)
+	for _, day := range []string{"04", "15"} {
+		fName := path.Join(root, "post-"+day+".md")
+		os.WriteFile(fName, []byte("---\ntitle: Post "+day+"\n---\n\nHello\n"), 0664)
+	}
+	meta := new(phlogit.PhlogMeta)
+	meta.BaseURL = "gopher://localhost:7000/0/phlog"
+	for _, day := range []string{"04", "15"} {
+		if err := meta.PhlogIt(phlogDir, path.Join(root, "post-"+day+".md"), "2022-07-"+day); err != nil {
+			t.Errorf("PhlogIt() failed, %s", err)
+			t.FailNow()
+		}
+	}
+	phlogName := path.Join(phlogDir, "phlog.json")
+	if err := meta.Save(phlogName); err != nil {
+		t.Errorf("Save() failed, %s", err)
+		t.FailNow()
+	}
+
+	fs := DefaultFingerService()
+	fs.DocRoot = root
+	fs.Phlog = phlogName
+	fs.Posts = 1
+	for query, expected := range map[string]string{
+		"":            "jane\r\n",
+		"jane@remote": "Finger forwarding service denied.\r\n",
+		"../jane":     "../jane: no such user.\r\n",
+		"bob":         "bob: no such user.\r\n",
+		"/W jane": strings.Join([]string{
+			"Login: jane",
+			"Project:",
+			"pttk",
+			"Plan:",
+			"Writing more phlog posts.",
+			"",
+			"Latest posts:",
+			"  2022-07-15 Post 15",
+			"    gopher://localhost:7000/0/phlog/2022/07/15/post-15.md",
+		}, "\r\n") + "\r\n",
+	} {
+		if got := fs.Respond(query); got != expected {
+			t.Errorf("query %q, expected %q, got %q", query, expected, got)
+		}
+	}
+
+	// Check a query over the wire
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("can't listen, %s", err)
+	}
+	defer ln.Close()
+	go func() {
+		if conn, err := ln.Accept(); err == nil {
+			fs.serve(conn)
+		}
+	}()
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Errorf("can't connect, %s", err)
+		t.FailNow()
+	}
+	conn.Write([]byte("\r\n"))
+	src, _ := io.ReadAll(conn)
+	conn.Close()
+	if string(src) != "jane\r\n" {
+		t.Errorf("expected user list, got %q", src)
+	}
+}
@@ -0,0 +1,150 @@
+// pttk is software for working with plain text content.
+// Copyright (C) 2022 R. S. Doiel
+//
+// This program is free software: you can redistribute it and/or modify it under the terms of the GNU Affero General Public License as published by the Free Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License along with this program. If not, see <https://www.gnu.org/licenses/>.
+package gs
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	// 3rd Party packages
+	"git.mills.io/prologic/go-gopher"
+)
+
+const (
+	// SortByName sorts a generated listing by file name (the default)
+	SortByName = "name"
+	// SortByTitle sorts a generated listing by title, front matter
+	// titles are used for Markdown files
+	SortByTitle = "title"
+	// SortByDate sorts a generated listing newest first
+	SortByDate = "date"
+	// SortBySize sorts a generated listing largest first
+	SortBySize = "size"
+
+	// listingDateFmt is the layout of modification dates in listings
+	listingDateFmt = "2006-01-02 15:04"
+)
+
+// listingEntry holds what is shown for a file in a generated listing.
+type listingEntry struct {
+	name  string
+	title string
+	info  os.FileInfo
+}
+
+// IsSortOrder checks if s is a sort order for generated listings.
+func IsSortOrder(s string) bool {
+	switch s {
+	case SortByName, SortByTitle, SortByDate, SortBySize:
+		return true
+	}
+	return false
+}
+
+// HumanSize renders a size in bytes as "512 B", "1.5 KB", "2.0 MB", etc.
+func HumanSize(size int64) string {
+	if size < 1024 {
+		return fmt.Sprintf("%d B", size)
+	}
+	units := []string{"KB", "MB", "GB", "TB"}
+	val := float64(size) / 1024
+	i := 0
+	for val >= 1024 && i < len(units)-1 {
+		val = val / 1024
+		i++
+	}
+	return fmt.Sprintf("%.1f %s", val, units[i])
+}
+
+// entryTitle returns the front matter title of a Markdown file or
+// the file's name.
+func entryTitle(fName string, info os.FileInfo) string {
+	if !info.IsDir() && path.Ext(info.Name()) == ".md" {
+		if title := metaString(docMetadata(fName), "title"); title != "" {
+			return title
+		}
+	}
+	return info.Name()
+}
+
+// sortEntries orders a listing by sortBy, ties are ordered by name.
+func sortEntries(entries []*listingEntry, sortBy string) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		switch sortBy {
+		case SortByTitle:
+			if ta, tb := strings.ToLower(a.title), strings.ToLower(b.title); ta != tb {
+				return ta < tb
+			}
+		case SortByDate:
+			if !a.info.ModTime().Equal(b.info.ModTime()) {
+				return a.info.ModTime().After(b.info.ModTime())
+			}
+		case SortBySize:
+			if a.info.Size() != b.info.Size() {
+				return a.info.Size() > b.info.Size()
+			}
+		}
+		return a.name < b.name
+	})
+}
+
+// generatedListing returns the menu of a directory without a
+// gophermap. Each entry is followed by an informational line with
+// its size and modification date. Markdown files are listed by
+// their front matter title with the file name shown in the
+// informational line.
+func (h *GopherHandler) generatedListing(dirSelector string, host string, port int) ([]*gopher.Item, error) {
+	dName := h.fsPath(dirSelector)
+	dirEntries, err := os.ReadDir(dName)
+	if err != nil {
+		return nil, err
+	}
+	entries := []*listingEntry{}
+	for _, entry := range dirEntries {
+		name := entry.Name()
+		if strings.HasPrefix(name, ".") || name == GophermapName {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		entries = append(entries, &listingEntry{
+			name:  name,
+			title: entryTitle(filepath.Join(dName, name), info),
+			info:  info,
+		})
+	}
+	sortEntries(entries, h.SortBy)
+	items := []*gopher.Item{}
+	for _, entry := range entries {
+		item := new(gopher.Item)
+		item.Type = itemType(filepath.Join(dName, entry.name), entry.info)
+		item.Description = entry.title
+		item.Selector = path.Join("/", dirSelector, entry.name)
+		item.Host = host
+		item.Port = port
+		items = append(items, item)
+		details := []string{}
+		if entry.title != entry.name {
+			details = append(details, entry.name)
+		}
+		if !entry.info.IsDir() {
+			details = append(details, HumanSize(entry.info.Size()))
+		}
+		details = append(details, entry.info.ModTime().Format(listingDateFmt))
+		items = append(items, infoItem("  "+strings.Join(details, ", ")))
+	}
+	return items, nil
+}
@@ -0,0 +1,271 @@
+// pttk is software for working with plain text content.
+// Copyright (C) 2022 R. S. Doiel
+//
+// This program is free software: you can redistribute it and/or modify it under the terms of the GNU Affero General Public License as published by the Free Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License along with this program. If not, see <https://www.gnu.org/licenses/>.
+package gs
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	// My packages
+	"github.com/rsdoiel/pttk/frontmatter"
+
+	// 3rd Party packages
+	"git.mills.io/prologic/go-gopher"
+)
+
+// IndexDoc describes a document held in an *Index.
+type IndexDoc struct {
+	// Selector is the document's selector relative to the document root
+	Selector string `json:"selector"`
+	// Title is the front matter title or the file name
+	Title string `json:"title"`
+	// Abstract is the front matter abstract or description
+	Abstract string `json:"abstract,omitempty"`
+	// Author is the front matter author
+	Author string `json:"author,omitempty"`
+	// Updated is the modification time of the file
+	Updated time.Time `json:"updated"`
+	// terms holds the term frequencies for the document
+	terms map[string]int
+}
+
+// Index is a simple in memory full text index of the text
+// documents (.md, .txt and files without an extension) in a
+// document root.
+type Index struct {
+	Docs []*IndexDoc `json:"docs"`
+	// postings maps a term to the positions of the documents in Docs
+	postings map[string][]int
+}
+
+// tokenize splits text into lower case terms of letters and digits.
+func tokenize(txt string) []string {
+	return strings.FieldsFunc(strings.ToLower(txt), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+// isTextDoc reports if a file name is indexed.
+func isTextDoc(name string) bool {
+	ext := path.Ext(name)
+	return (ext == ".md" || ext == ".txt" || ext == "") && name != GophermapName
+}
+
+// docMetadata reads the front matter of fName returning it as a map.
+func docMetadata(fName string) map[string]interface{} {
+	obj := map[string]interface{}{}
+	src, err := frontmatter.ReadFile(fName)
+	if err != nil || len(src) == 0 {
+		return obj
+	}
+	json.Unmarshal(src, &obj)
+	return obj
+}
+
+// metaString returns the string value of key from a front matter map.
+func metaString(obj map[string]interface{}, key string) string {
+	if val, ok := obj[key]; ok {
+		if s, ok := val.(string); ok {
+			return strings.TrimSpace(s)
+		}
+	}
+	return ""
+}
+
+// BuildIndex walks docRoot and indexes the text documents found.
+// Dot files and dot directories are skipped.
+func BuildIndex(docRoot string) (*Index, error) {
+	idx := new(Index)
+	idx.postings = map[string][]int{}
+	err := filepath.WalkDir(docRoot, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if p != docRoot && strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() || !isTextDoc(d.Name()) {
+			return nil
+		}
+		src, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		relPath, err := filepath.Rel(docRoot, p)
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		doc := new(IndexDoc)
+		doc.Selector = "/" + filepath.ToSlash(relPath)
+		doc.Title = d.Name()
+		doc.Updated = info.ModTime()
+		obj := docMetadata(p)
+		if title := metaString(obj, "title"); title != "" {
+			doc.Title = title
+		}
+		doc.Abstract = metaString(obj, "abstract")
+		if doc.Abstract == "" {
+			doc.Abstract = metaString(obj, "description")
+		}
+		doc.Author = metaString(obj, "author")
+		doc.terms = map[string]int{}
+		for _, term := range tokenize(string(src)) {
+			doc.terms[term]++
+		}
+		i := len(idx.Docs)
+		idx.Docs = append(idx.Docs, doc)
+		for term := range doc.terms {
+			idx.postings[term] = append(idx.postings[term], i)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return idx, nil
+}
+
+// Search returns the documents containing all the terms of
+// query. Results are ordered by how often the terms occur.
+func (idx *Index) Search(query string) []*IndexDoc {
+	terms := tokenize(query)
+	if len(terms) == 0 {
+		return nil
+	}
+	scores := map[int]int{}
+	for i, term := range terms {
+		matched := map[int]int{}
+		for _, pos := range idx.postings[term] {
+			if _, ok := scores[pos]; ok || i == 0 {
+				matched[pos] = scores[pos] + idx.Docs[pos].terms[term]
+			}
+		}
+		scores = matched
+	}
+	positions := []int{}
+	for pos := range scores {
+		positions = append(positions, pos)
+	}
+	sort.Slice(positions, func(i, j int) bool {
+		a, b := positions[i], positions[j]
+		if scores[a] != scores[b] {
+			return scores[a] > scores[b]
+		}
+		return idx.Docs[a].Selector < idx.Docs[b].Selector
+	})
+	results := []*IndexDoc{}
+	for _, pos := range positions {
+		results = append(results, idx.Docs[pos])
+	}
+	return results
+}
+
+// Lookup returns the indexed document for a selector or nil.
+func (idx *Index) Lookup(selector string) *IndexDoc {
+	selector = path.Clean("/" + selector)
+	for _, doc := range idx.Docs {
+		if doc.Selector == selector {
+			return doc
+		}
+	}
+	return nil
+}
+
+// searchMenu renders the results of a search as menu items.
+func (h *GopherHandler) searchMenu(query string, host string, port int) []*gopher.Item {
+	results := h.Index.Search(query)
+	items := []*gopher.Item{}
+	items = append(items, infoItem(fmt.Sprintf("%d document(s) found for %q", len(results), query)))
+	for _, doc := range results {
+		item := new(gopher.Item)
+		item.Type = gopher.FILE
+		item.Description = doc.Title
+		item.Selector = doc.Selector
+		item.Host = host
+		item.Port = port
+		items = append(items, item)
+	}
+	return items
+}
+
+// attributes renders a Gopher+ attribute block for a selector. If
+// names is not empty only the named attributes (e.g. "+ABSTRACT")
+// are included.
+func (h *GopherHandler) attributes(selector string, names []string, host string, port int) (string, error) {
+	fName := h.fsPath(selector)
+	info, err := os.Stat(fName)
+	if err != nil {
+		return "", err
+	}
+	selector = path.Clean("/" + selector)
+	item := new(gopher.Item)
+	item.Type = itemType(fName, info)
+	item.Description = path.Base(selector)
+	item.Selector = selector
+	item.Host = host
+	item.Port = port
+	item.Extras = []string{"+"}
+	abstract := ""
+	if h.Index != nil {
+		if doc := h.Index.Lookup(selector); doc != nil {
+			item.Description = doc.Title
+			abstract = doc.Abstract
+		}
+	} else if !info.IsDir() {
+		obj := docMetadata(fName)
+		if title := metaString(obj, "title"); title != "" {
+			item.Description = title
+		}
+		abstract = metaString(obj, "abstract")
+		if abstract == "" {
+			abstract = metaString(obj, "description")
+		}
+	}
+	want := func(name string) bool {
+		if len(names) == 0 {
+			return true
+		}
+		for _, n := range names {
+			if strings.EqualFold(n, name) {
+				return true
+			}
+		}
+		return false
+	}
+	lines := []string{}
+	if want("+INFO") {
+		src, _ := item.MarshalText()
+		lines = append(lines, "+INFO: "+strings.TrimSuffix(string(src), gopher.CRLF))
+	}
+	if want("+ADMIN") {
+		lines = append(lines, "+ADMIN:", " Admin: "+host, " Mod-Date: "+info.ModTime().Format("<20060102150405>"))
+	}
+	if want("+ABSTRACT") && abstract != "" {
+		lines = append(lines, "+ABSTRACT:")
+		for _, line := range strings.Split(abstract, "\n") {
+			lines = append(lines, " "+line)
+		}
+	}
+	return strings.Join(lines, gopher.CRLF) + gopher.CRLF, nil
+}
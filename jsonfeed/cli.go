@@ -0,0 +1,182 @@
+// pttk is software for working with plain text content.
+// Copyright (C) 2022 R. S. Doiel
+//
+// This program is free software: you can redistribute it and/or modify it under the terms of the GNU Affero General Public License as published by the Free Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License along with this program. If not, see <https://www.gnu.org/licenses/>.
+package jsonfeed
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	// My packages
+	"github.com/rsdoiel/pttk/blogit"
+	"github.com/rsdoiel/pttk/feed"
+	"github.com/rsdoiel/pttk/help"
+	"github.com/rsdoiel/pttk/websub"
+)
+
+var (
+	// Standard options
+	showHelp bool
+
+	// App specific options
+	excludeList     string
+	baseURL         string
+	feedURL         string
+	feedTitle       string
+	feedDescription string
+	homePageURL     string
+	feedLanguage    string
+	feedAuthor      string
+	feedIcon        string
+	feedFavicon     string
+	pageSize        int
+	outName         string
+	hubList         string
+	pingHubURL      string
+)
+
+func usage(appName string, verb string, exitCode int) {
+	out := os.Stdout
+	if exitCode > 0 {
+		out = os.Stderr
+	}
+	fmt.Fprint(out, help.Render(appName, verb, helpText))
+	os.Exit(exitCode)
+}
+
+// RunJSONFeed implements the jsonfeed verb. It returns the JSON Feed
+// document or, when the feed is paginated, writes the pages to files
+// named from "-o" and returns nil.
+func RunJSONFeed(appName string, verb string, options []string) ([]byte, error) {
+	flagSet := flag.NewFlagSet(appName+":"+verb, flag.ExitOnError)
+
+	// Standard options
+	flagSet.BoolVar(&showHelp, "help", false, "display help")
+
+	// App specific options
+	flagSet.StringVar(&baseURL, "base-url", "", "set site base url for links")
+	flagSet.StringVar(&feedURL, "feed-url", "", "set the URL of the feed itself")
+	flagSet.StringVar(&excludeList, "e", "", "A colon delimited list of path exclusions")
+	flagSet.StringVar(&feedTitle, "title", "", "Title of feed")
+	flagSet.StringVar(&feedDescription, "description", "", "Description of feed")
+	flagSet.StringVar(&homePageURL, "home-page-url", "", "URL of the site the feed describes")
+	flagSet.StringVar(&feedLanguage, "language", "", "Language, e.g. en-ca")
+	flagSet.StringVar(&feedAuthor, "author", "", "Name of the feed's author")
+	flagSet.StringVar(&feedIcon, "icon", "", "URL of the feed's icon")
+	flagSet.StringVar(&feedFavicon, "favicon", "", "URL of the feed's favicon")
+	flagSet.IntVar(&pageSize, "page-size", 0, "number of items per page, 0 is unlimited")
+	flagSet.StringVar(&outName, "o", "", "write the feed to this file")
+	flagSet.StringVar(&hubList, "hub", "", "comma separated WebSub hub URLs the feed declares")
+	flagSet.StringVar(&pingHubURL, "ping", "", "send a WebSub publish notification to this hub once the feed is written")
+
+	flagSet.Parse(options)
+	args := flagSet.Args()
+
+	if showHelp {
+		usage(appName, verb, 0)
+	}
+
+	if len(feedTitle) == 0 {
+		feedTitle = `A website`
+	}
+	if len(homePageURL) == 0 {
+		homePageURL = baseURL
+	}
+
+	jf := new(Feed)
+	jf.Version = Version
+	jf.Title = feedTitle
+	jf.Description = feedDescription
+	jf.HomePageURL = homePageURL
+	jf.Language = feedLanguage
+	jf.Icon = feedIcon
+	jf.Favicon = feedFavicon
+	if feedAuthor != "" {
+		jf.Authors = append(jf.Authors, &Author{Name: feedAuthor, URL: homePageURL})
+	}
+
+	htdocs := "."
+	if len(args) > 0 {
+		htdocs = args[0]
+	}
+	var err error
+	blogJSON := path.Join(htdocs, "blog.json")
+	if _, err := os.Stat(blogJSON); os.IsNotExist(err) {
+		err = WalkFeed(jf, htdocs, baseURL, excludeList)
+	} else {
+		blog := new(blogit.BlogMeta)
+		if err := blogit.LoadBlogMeta(blogJSON, blog); err != nil {
+			return nil, fmt.Errorf("Reading %q, %s", blogJSON, err)
+		}
+		if blog.BaseURL == "" {
+			blog.BaseURL = baseURL
+		}
+		err = BlogMetaToFeed(blog, htdocs, jf)
+		// Command line options override blog.json
+		if feedTitle != `A website` {
+			jf.Title = feedTitle
+		}
+		if feedDescription != "" {
+			jf.Description = feedDescription
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if feedURL != "" {
+		jf.FeedURL = feedURL
+	}
+	for _, hub := range strings.Split(hubList, ",") {
+		if hub = strings.TrimSpace(hub); hub != "" {
+			jf.Hubs = append(jf.Hubs, &Hub{Type: websub.HubType, URL: hub})
+		}
+	}
+	if hubList == "" && pingHubURL != "" {
+		jf.Hubs = append(jf.Hubs, &Hub{Type: websub.HubType, URL: pingHubURL})
+	}
+	if pingHubURL != "" {
+		// The hub fetches the feed when pinged so it must be written first
+		switch {
+		case outName == "":
+			return nil, fmt.Errorf("-ping requires -o, the feed is written before the hub is notified")
+		case jf.FeedURL == "":
+			return nil, fmt.Errorf("-ping requires -feed-url, the feed URL sent to the hub")
+		}
+	}
+
+	pages := jf.Paginate(pageSize)
+	if len(pages) > 1 {
+		if outName == "" {
+			return nil, fmt.Errorf("-page-size requires -o to name the feed's files")
+		}
+		if jf.FeedURL == "" {
+			return nil, fmt.Errorf("-page-size requires -feed-url to link the feed's pages")
+		}
+	}
+	for i, page := range pages {
+		src, err := json.MarshalIndent(page, "", "    ")
+		if err != nil {
+			return nil, err
+		}
+		if outName == "" {
+			return src, nil
+		}
+		fName := feed.PageName(outName, i+1)
+		if err := os.WriteFile(fName, append(src, '\n'), 0664); err != nil {
+			return nil, fmt.Errorf("Writing %q, %s", fName, err)
+		}
+	}
+	if pingHubURL != "" {
+		return nil, websub.Ping(pingHubURL, jf.FeedURL)
+	}
+	return nil, nil
+}
@@ -0,0 +1,129 @@
+// pttk is software for working with plain text content.
+// Copyright (C) 2022 R. S. Doiel
+//
+// This program is free software: you can redistribute it and/or modify it under the terms of the GNU Affero General Public License as published by the Free Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License along with this program. If not, see <https://www.gnu.org/licenses/>.
+package jsonfeed
+
+const (
+	helpText = `% {app_name}-{verb}(1) {app_name}-{verb} user manual
+% R. S. Doiel
+% October 19, 2026
+
+# NAME
+
+{app_name} {verb}
+
+# SYNOPSIS
+
+{app_name} {verb} [OPTIONS] PATH_TO_SITE
+
+# DESCRIPTION
+
+{app_name} {verb} renders a JSON Feed 1.1 document
+(see <https://jsonfeed.org/version/1.1>) from the content found in the
+directory tree provided. It works like "{app_name} rss". If it finds
+a "blog.json" file (see blogit) it uses it to generate the feed's items
+otherwise it walks the directory tree for Markdown documents in
+directories in the form of /YYYY/MM/DD/ where YYYY/MM/DD (Year, Month,
+Day) is the publication date of the document.
+
+Each item's "content_text" is the Markdown document without its front
+matter. If the document has been rendered to HTML (e.g. "post.md" and
+"post.html") the HTML page's article (or body) is used for
+"content_html". The "summary" is the front matter's description (or
+abstract) or the opening paragraph. Front matter "keywords" (or "tags")
+become the item's tags, "author" its author and "attachments" (a list
+of URLs, paths relative to the document or objects with "url",
+"mime_type", "title", "size_in_bytes" and "duration_in_seconds") its
+attachments. Items are listed newest first.
+
+With "-page-size" the feed is split into pages, the first page is
+written to the file named by "-o" and the following pages to files
+numbered from two (e.g. "feed.json", "feed-2.json", "feed-3.json").
+Each page links to the next with "next_url" derived from "-feed-url".
+
+With "-hub" the feed lists the WebSub hubs given (comma separated)
+in "hubs". With "-ping HUB" the hub is sent a WebSub publish
+notification for "-feed-url" once the feed is written with "-o",
+HUB is listed in "hubs" when "-hub" isn't given.
+
+# OPTIONS
+
+-author string
+: Name of the feed's author
+
+-base-url string
+: set site base url for links
+
+-description string
+: Description of feed
+
+-e string
+: A colon delimited list of path exclusions
+
+-favicon string
+: URL of the feed's favicon
+
+-feed-url string
+: set the URL of the feed itself
+
+-help
+: display help
+
+-home-page-url string
+: URL of the site the feed describes, defaults to -base-url
+
+-hub string
+: comma separated WebSub hub URLs the feed declares
+
+-icon string
+: URL of the feed's icon
+
+-language string
+: Language, e.g. en-ca
+
+-o string
+: write the feed to this file
+
+-page-size int
+: number of items per page, 0 (default) is unlimited
+
+-ping string
+: send a WebSub publish notification to this hub once the feed is written
+
+-title string
+: Title of feed
+
+# EXAMPLE
+
+Generating a JSON Feed for a blog managed with "blogit" in
+a directory called blog.
+
+~~~
+  {app_name} {verb} -base-url="https://blog.example.org" \
+      -feed-url="https://blog.example.org/feed.json" \
+      blog >blog/feed.json
+~~~
+
+Generating a feed with twenty items per page.
+
+~~~
+  {app_name} {verb} -base-url="https://blog.example.org" \
+      -feed-url="https://blog.example.org/feed.json" \
+      -page-size=20 -o blog/feed.json blog
+~~~
+
+Regenerating the feed and telling its WebSub hub about it.
+
+~~~
+  {app_name} {verb} -base-url="https://blog.example.org" \
+      -feed-url="https://blog.example.org/feed.json" \
+      -ping https://pubsubhubbub.appspot.com/ -o blog/feed.json blog
+~~~
+
+`
+)
@@ -0,0 +1,155 @@
+// pttk is software for working with plain text content.
+// Copyright (C) 2022 R. S. Doiel
+//
+// This program is free software: you can redistribute it and/or modify it under the terms of the GNU Affero General Public License as published by the Free Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License along with this program. If not, see <https://www.gnu.org/licenses/>.
+package jsonfeed
+
+import (
+	"encoding/json"
+	"strings"
+
+	// My packages
+	"github.com/rsdoiel/pttk/feed"
+	"github.com/rsdoiel/pttk/websub"
+)
+
+// init registers JSON Feed 1.1 as a feed format
+func init() {
+	feed.RegisterFormat(feed.JSONFeed, func(src []byte) (*feed.Feed, error) {
+		jf, err := Parse(src)
+		if err != nil {
+			return nil, err
+		}
+		return jf.ToFeed(), nil
+	}, func(f *feed.Feed) ([]byte, error) {
+		return json.MarshalIndent(FromFeed(f), "", "    ")
+	})
+}
+
+// fromAuthors reads JSON Feed authors
+func fromAuthors(authors []*Author) []*feed.Person {
+	var persons []*feed.Person
+	for _, author := range authors {
+		person := &feed.Person{Name: author.Name, URL: author.URL, Avatar: author.Avatar}
+		if strings.HasPrefix(person.URL, "mailto:") {
+			person.Email, person.URL = strings.TrimPrefix(person.URL, "mailto:"), ""
+		}
+		persons = append(persons, person)
+	}
+	return persons
+}
+
+// toAuthors writes JSON Feed authors, JSON Feed has no email so a
+// mailto: URL is used when a person has no URL.
+func toAuthors(persons []*feed.Person) []*Author {
+	var authors []*Author
+	for _, person := range persons {
+		author := &Author{Name: person.Name, URL: person.URL, Avatar: person.Avatar}
+		if author.URL == "" && person.Email != "" {
+			author.URL = "mailto:" + person.Email
+		}
+		authors = append(authors, author)
+	}
+	return authors
+}
+
+// ToFeed reads the JSON Feed into the common feed model, an
+// author's "mailto:" URL is read as their email.
+func (jf *Feed) ToFeed() *feed.Feed {
+	f := &feed.Feed{
+		Title:       jf.Title,
+		Description: jf.Description,
+		Link:        jf.HomePageURL,
+		FeedURL:     jf.FeedURL,
+		Language:    jf.Language,
+		Icon:        jf.Icon,
+		Favicon:     jf.Favicon,
+		Authors:     fromAuthors(jf.Authors),
+	}
+	for _, hub := range jf.Hubs {
+		f.Hubs = append(f.Hubs, hub.URL)
+	}
+	for _, item := range jf.Items {
+		entry := &feed.Entry{
+			ID:          item.ID,
+			Link:        item.URL,
+			Title:       item.Title,
+			Summary:     item.Summary,
+			ContentHTML: item.ContentHTML,
+			ContentText: item.ContentText,
+			Image:       item.Image,
+			Published:   feed.NormalizeDate(item.DatePublished),
+			Updated:     feed.NormalizeDate(item.DateModified),
+			Authors:     fromAuthors(item.Authors),
+			Tags:        item.Tags,
+		}
+		for _, attachment := range item.Attachments {
+			entry.Enclosures = append(entry.Enclosures, &feed.Enclosure{
+				URL:      attachment.URL,
+				Type:     attachment.MimeType,
+				Length:   attachment.SizeInBytes,
+				Title:    attachment.Title,
+				Duration: attachment.DurationInSeconds,
+			})
+		}
+		f.Entries = append(f.Entries, entry)
+	}
+	return f
+}
+
+// FromFeed writes a feed as a JSON Feed 1.1 document. An entry
+// without content uses its summary as its HTML content, JSON Feed
+// requires one.
+func FromFeed(f *feed.Feed) *Feed {
+	jf := &Feed{
+		Version:     Version,
+		Title:       f.Title,
+		HomePageURL: f.Link,
+		FeedURL:     f.FeedURL,
+		Description: f.Description,
+		Icon:        f.Icon,
+		Favicon:     f.Favicon,
+		Authors:     toAuthors(f.Authors),
+		Language:    f.Language,
+		Items:       []*Item{},
+	}
+	for _, hub := range f.Hubs {
+		jf.Hubs = append(jf.Hubs, &Hub{Type: websub.HubType, URL: hub})
+	}
+	for _, entry := range f.Entries {
+		item := &Item{
+			ID:            entry.ID,
+			URL:           entry.Link,
+			Title:         entry.Title,
+			ContentHTML:   entry.ContentHTML,
+			ContentText:   entry.ContentText,
+			Summary:       entry.Summary,
+			Image:         entry.Image,
+			DatePublished: entry.Published,
+			DateModified:  entry.Updated,
+			Authors:       toAuthors(entry.Authors),
+			Tags:          entry.Tags,
+		}
+		if item.ID == "" {
+			item.ID = entry.Link
+		}
+		if item.ContentHTML == "" && item.ContentText == "" {
+			item.ContentHTML, item.Summary = entry.Summary, ""
+		}
+		for _, enclosure := range entry.Enclosures {
+			item.Attachments = append(item.Attachments, &Attachment{
+				URL:               enclosure.URL,
+				MimeType:          enclosure.Type,
+				Title:             enclosure.Title,
+				SizeInBytes:       enclosure.Length,
+				DurationInSeconds: enclosure.Duration,
+			})
+		}
+		jf.Items = append(jf.Items, item)
+	}
+	return jf
+}
@@ -0,0 +1,348 @@
+// pttk is software for working with plain text content.
+// Copyright (C) 2022 R. S. Doiel
+//
+// This program is free software: you can redistribute it and/or modify it under the terms of the GNU Affero General Public License as published by the Free Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License along with this program. If not, see <https://www.gnu.org/licenses/>.
+package jsonfeed
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	// My packages
+	"github.com/rsdoiel/pttk/blogit"
+	"github.com/rsdoiel/pttk/feed"
+	"github.com/rsdoiel/pttk/frontmatter"
+)
+
+var (
+	// validBlogPath matches the YYYY/MM/DD directories of a blog
+	validBlogPath = regexp.MustCompile(`(^|/)([0-9][0-9][0-9][0-9])/([0-9][0-9])/([0-9][0-9])/`)
+)
+
+// readDocument returns the front matter and the text (with the front
+// matter removed) of a Markdown document.
+func readDocument(fName string) (map[string]interface{}, string, error) {
+	buf, err := os.ReadFile(fName)
+	if err != nil {
+		return nil, "", err
+	}
+	fMatter := map[string]interface{}{}
+	fSrc, err := frontmatter.ReadAll(bytes.NewBuffer(buf))
+	if err != nil {
+		return nil, "", err
+	}
+	tSrc, err := frontmatter.TrimFrontmatter(bytes.NewBuffer(buf))
+	if err != nil {
+		return nil, "", err
+	}
+	if len(fSrc) > 0 {
+		if err := json.Unmarshal(fSrc, &fMatter); err != nil {
+			fMatter = map[string]interface{}{}
+		}
+	}
+	return fMatter, strings.TrimSpace(string(tSrc)), nil
+}
+
+// metaString returns a front matter value as a string.
+func metaString(fMatter map[string]interface{}, keys ...string) string {
+	for _, key := range keys {
+		if val, ok := fMatter[key]; ok {
+			switch v := val.(type) {
+			case string:
+				if s := strings.TrimSpace(v); s != "" {
+					return s
+				}
+			case float64, int, bool:
+				return fmt.Sprintf("%v", v)
+			}
+		}
+	}
+	return ""
+}
+
+// metaList returns a front matter value as a list of strings, a
+// string value is split on commas.
+func metaList(fMatter map[string]interface{}, keys ...string) []string {
+	l := []string{}
+	for _, key := range keys {
+		switch v := fMatter[key].(type) {
+		case string:
+			for _, s := range strings.Split(v, ",") {
+				if s = strings.TrimSpace(s); s != "" {
+					l = append(l, s)
+				}
+			}
+		case []interface{}:
+			for _, val := range v {
+				if s, ok := val.(string); ok && strings.TrimSpace(s) != "" {
+					l = append(l, strings.TrimSpace(s))
+				}
+			}
+		}
+		if len(l) > 0 {
+			return l
+		}
+	}
+	return l
+}
+
+// openingParagraphs returns the first cnt paragraphs of Markdown text
+// skipping headings.
+func openingParagraphs(src string, cnt int) string {
+	txt := []string{}
+	for _, block := range strings.Split(strings.ReplaceAll(src, "\r", ""), "\n\n") {
+		block = strings.TrimSpace(block)
+		if block == "" || strings.HasPrefix(block, "#") || strings.HasPrefix(block, "[!") ||
+			strings.HasSuffix(block, "====") || strings.HasSuffix(block, "----") {
+			continue
+		}
+		txt = append(txt, block)
+		if len(txt) >= cnt {
+			break
+		}
+	}
+	return strings.Join(txt, "\n\n")
+}
+
+// joinURL joins a base URL and a relative path
+func joinURL(baseURL string, p string) string {
+	if baseURL == "" {
+		return "/" + strings.TrimPrefix(p, "/")
+	}
+	return strings.TrimSuffix(baseURL, "/") + "/" + strings.TrimPrefix(p, "/")
+}
+
+// attachments reads the "attachments" (a list of URLs, paths or
+// objects) and "enclosure" front matter of a document. Relative
+// paths are resolved from the document's directory so their size
+// can be included.
+func attachments(fMatter map[string]interface{}, fName string, dirURL string) []*Attachment {
+	vals := []interface{}{}
+	if l, ok := fMatter["attachments"].([]interface{}); ok {
+		vals = append(vals, l...)
+	}
+	if enclosure, ok := fMatter["enclosure"]; ok {
+		vals = append(vals, enclosure)
+	}
+	l := []*Attachment{}
+	for _, val := range vals {
+		attachment := new(Attachment)
+		switch v := val.(type) {
+		case string:
+			attachment.URL = v
+		case map[string]interface{}:
+			attachment.URL = metaString(v, "url", "href")
+			attachment.MimeType = metaString(v, "mime_type", "type")
+			attachment.Title = metaString(v, "title")
+			if n, ok := v["size_in_bytes"].(float64); ok {
+				attachment.SizeInBytes = int64(n)
+			}
+			if n, ok := v["duration_in_seconds"].(float64); ok {
+				attachment.DurationInSeconds = int(n)
+			}
+		}
+		if attachment.URL == "" {
+			continue
+		}
+		if !strings.Contains(attachment.URL, "://") {
+			local := filepath.Join(filepath.Dir(fName), filepath.FromSlash(attachment.URL))
+			if info, err := os.Stat(local); err == nil && attachment.SizeInBytes == 0 {
+				attachment.SizeInBytes = info.Size()
+			}
+			attachment.URL = joinURL(dirURL, attachment.URL)
+		}
+		if attachment.MimeType == "" {
+			attachment.MimeType = feed.MediaType(attachment.URL)
+		}
+		l = append(l, attachment)
+	}
+	return l
+}
+
+// DocumentToItem builds a feed item from a Markdown document. relPath
+// is the document's path relative to the site root, baseURL the
+// site's URL and published the YYYY-MM-DD it was posted, if known.
+// The content_text is the Markdown (without front matter) and the
+// content_html is read from the document's rendered HTML page when
+// there is one.
+func DocumentToItem(fName string, relPath string, baseURL string, published string) (*Item, error) {
+	fMatter, txt, err := readDocument(fName)
+	if err != nil {
+		return nil, err
+	}
+	linkPath := relPath
+	if ext := path.Ext(relPath); ext == ".md" || ext == ".markdown" {
+		linkPath = strings.TrimSuffix(relPath, ext) + ".html"
+	}
+	item := new(Item)
+	item.URL = joinURL(baseURL, linkPath)
+	item.ID = item.URL
+	item.Title = metaString(fMatter, "title")
+	item.ContentText = txt
+	item.ContentHTML = feed.RenderedHTML(fName)
+	item.Summary = metaString(fMatter, "description", "abstract")
+	if item.Summary == "" {
+		item.Summary = openingParagraphs(txt, 1)
+	}
+	if s := feed.NormalizeDate(metaString(fMatter, "datePublished", "pubDate", "date")); s != "" {
+		item.DatePublished = s
+	} else if s := feed.NormalizeDate(published); s != "" {
+		item.DatePublished = s
+	}
+	item.DateModified = feed.NormalizeDate(metaString(fMatter, "dateModified", "updated"))
+	if author := metaString(fMatter, "author", "creator"); author != "" {
+		item.Authors = append(item.Authors, &Author{Name: author})
+	}
+	item.Tags = metaList(fMatter, "tags", "keywords")
+	if len(item.Tags) == 0 {
+		item.Tags = nil
+	}
+	item.Language = metaString(fMatter, "lang", "language")
+	item.Image = metaString(fMatter, "image")
+	item.Attachments = attachments(fMatter, fName, joinURL(baseURL, path.Dir(relPath)))
+	if len(item.Attachments) == 0 {
+		item.Attachments = nil
+	}
+	return item, nil
+}
+
+// sortItems orders items newest first as feed readers expect.
+func sortItems(items []*Item) {
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].DatePublished > items[j].DatePublished
+	})
+}
+
+// BlogMetaToFeed generates the items of a feed from a blog.json.
+// htdocs is the directory holding the blog.json, posts are read from
+// there when their document path isn't found.
+func BlogMetaToFeed(blog *blogit.BlogMeta, htdocs string, jf *Feed) error {
+	if blog.Name != "" {
+		jf.Title = blog.Name
+	}
+	if blog.BaseURL != "" {
+		jf.HomePageURL = blog.BaseURL
+	}
+	description := []string{}
+	if blog.Quip != "" {
+		description = append(description, blog.Quip)
+	}
+	if blog.Description != "" {
+		description = append(description, blog.Description)
+	}
+	if len(description) > 0 {
+		jf.Description = strings.Join(description, "\n\n")
+	}
+	if blog.Language != "" {
+		jf.Language = blog.Language
+	}
+	for _, yr := range blog.Years {
+		for _, mn := range yr.Months {
+			for _, dy := range mn.Days {
+				for _, post := range dy.Posts {
+					if post.Draft {
+						continue
+					}
+					ymd := path.Join(yr.Year, mn.Month, dy.Day)
+					relPath := path.Join(ymd, path.Base(post.Document))
+					fName := post.Document
+					if _, err := os.Stat(fName); err != nil {
+						fName = filepath.Join(htdocs, filepath.FromSlash(relPath))
+					}
+					item, err := DocumentToItem(fName, relPath, jf.HomePageURL, strings.ReplaceAll(ymd, "/", "-"))
+					if err != nil {
+						return err
+					}
+					// blog.json's metadata takes precedence over
+					// the document's front matter.
+					if post.Title != "" {
+						item.Title = strings.TrimSpace(post.Title)
+					}
+					if post.Description != "" {
+						item.Summary = post.Description
+					} else if post.Abstract != "" {
+						item.Summary = post.Abstract
+					}
+					if s := feed.NormalizeDate(post.Updated); s != "" {
+						item.DateModified = s
+					}
+					if len(post.Keywords) > 0 {
+						item.Tags = post.Keywords
+					}
+					if len(post.Creators) > 0 {
+						item.Authors = nil
+						for _, creator := range post.Creators {
+							item.Authors = append(item.Authors, &Author{Name: creator.Name})
+						}
+					} else if post.Author != "" {
+						item.Authors = []*Author{{Name: post.Author}}
+					}
+					if post.Lang != "" {
+						item.Language = post.Lang
+					}
+					jf.Items = append(jf.Items, item)
+				}
+			}
+		}
+	}
+	sortItems(jf.Items)
+	return nil
+}
+
+// WalkFeed generates the items of a feed by walking htdocs for
+// Markdown documents in YYYY/MM/DD directories. excludeList is a
+// colon delimited list of paths to skip.
+func WalkFeed(feed *Feed, htdocs string, baseURL string, excludeList string) error {
+	excludes := []string{}
+	for _, p := range strings.Split(excludeList, ":") {
+		if p = strings.Trim(filepath.ToSlash(p), "/"); p != "" {
+			excludes = append(excludes, p)
+		}
+	}
+	err := filepath.Walk(htdocs, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		relPath, _ := filepath.Rel(htdocs, p)
+		relPath = filepath.ToSlash(relPath)
+		for _, exclude := range excludes {
+			if relPath == exclude || strings.HasPrefix(relPath, exclude+"/") {
+				if info.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+		}
+		if info.IsDir() && relPath != "." && strings.HasPrefix(info.Name(), ".") {
+			return filepath.SkipDir
+		}
+		if info.IsDir() || path.Ext(p) != ".md" {
+			return nil
+		}
+		m := validBlogPath.FindStringSubmatch(relPath)
+		if m == nil {
+			return nil
+		}
+		item, err := DocumentToItem(p, relPath, baseURL, fmt.Sprintf("%s-%s-%s", m[2], m[3], m[4]))
+		if err != nil {
+			return err
+		}
+		if item.Title != "" || item.ContentText != "" {
+			feed.Items = append(feed.Items, item)
+		}
+		return nil
+	})
+	sortItems(feed.Items)
+	return err
+}
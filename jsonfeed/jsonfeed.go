@@ -1,5 +1,26 @@
+// pttk is software for working with plain text content.
+// Copyright (C) 2022 R. S. Doiel
+//
+// This program is free software: you can redistribute it and/or modify it under the terms of the GNU Affero General Public License as published by the Free Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License along with this program. If not, see <https://www.gnu.org/licenses/>.
 package jsonfeed
 
+import (
+	"encoding/json"
+
+	// My packages
+	"github.com/rsdoiel/pttk/feed"
+)
+
+const (
+	// Version is the JSON Feed version URL generated
+	Version = "https://jsonfeed.org/version/1.1"
+)
+
+// Feed is a JSON Feed 1.1 document, see https://jsonfeed.org/version/1.1
 type Feed struct {
 	Version     string    `json:"version,required"`
 	Title       string    `json:"title,required"`
@@ -12,37 +33,91 @@ type Feed struct {
 	Favicon     string    `json:"favicon,omitempty"`
 	Authors     []*Author `json:"authors,omitempty"`
 	Language    string    `json:"language,omitempty"`
-	Expired     string    `json:"expired,omitempty"`
-	Hubs        string    `json:"hubs,omitempty"`
+	Expired     bool      `json:"expired,omitempty"`
+	Hubs        []*Hub    `json:"hubs,omitempty"`
+	Items       []*Item   `json:"items"`
 }
 
+// Author describes the author of a feed or item
 type Author struct {
 	Name   string `json:"name,omitempty"`
 	URL    string `json:"url,omitempty"`
 	Avatar string `json:"avatar,omitempty"`
 }
 
-type Items struct {
+// Hub describes an endpoint used to subscribe to real time
+// notifications of changes to the feed
+type Hub struct {
+	Type string `json:"type,required"`
+	URL  string `json:"url,required"`
+}
+
+// Item is an entry in a feed
+type Item struct {
 	ID            string        `json:"id,required"`
 	URL           string        `json:"url,omitempty"`
 	ExternalURL   string        `json:"external_url,omitempty"`
 	Title         string        `json:"title,omitempty"`
 	ContentHTML   string        `json:"content_html,omitempty"`
 	ContentText   string        `json:"content_text,omitempty"`
+	Summary       string        `json:"summary,omitempty"`
 	Image         string        `json:"image,omitempty"`
 	BannerImage   string        `json:"banner_image,omitempty"`
 	DatePublished string        `json:"date_published,omitempty"`
 	DateModified  string        `json:"date_modified,omitempty"`
-	Authors       []*Author     `json:"authors,omitemtpy"`
+	Authors       []*Author     `json:"authors,omitempty"`
 	Tags          []string      `json:"tags,omitempty"`
 	Language      string        `json:"language,omitempty"`
 	Attachments   []*Attachment `json:"attachments,omitempty"`
 }
 
+// Items is the name Item was first declared with.
+type Items = Item
+
+// Attachment describes a related resource, e.g. a podcast's audio file
 type Attachment struct {
 	URL               string `json:"url,required"`
 	MimeType          string `json:"mime_type,required"`
 	Title             string `json:"title,omitempty"`
-	SizeInBytes       int    `json:"size_in_bytes,omitempty"`
+	SizeInBytes       int64  `json:"size_in_bytes,omitempty"`
 	DurationInSeconds int    `json:"duration_in_seconds,omitempty"`
 }
+
+// Parse returns a JSON Feed document as a *Feed
+func Parse(src []byte) (*Feed, error) {
+	jf := new(Feed)
+	if err := json.Unmarshal(src, &jf); err != nil {
+		return nil, err
+	}
+	return jf, nil
+}
+
+// Paginate splits a feed into pages of size items. Each page but the
+// last has a next_url pointing to the following page, named from
+// the feed's feed_url with feed.PageName. If size is less than one
+// or the items fit on one page the feed is returned as is.
+func (jf *Feed) Paginate(size int) []*Feed {
+	if size < 1 || len(jf.Items) <= size {
+		return []*Feed{jf}
+	}
+	pages := []*Feed{}
+	for i := 0; i < len(jf.Items); i += size {
+		page := new(Feed)
+		*page = *jf
+		end := i + size
+		if end > len(jf.Items) {
+			end = len(jf.Items)
+		}
+		page.Items = jf.Items[i:end]
+		n := len(pages) + 1
+		page.NextURL = ""
+		if jf.FeedURL != "" {
+			page.FeedURL = feed.PageName(jf.FeedURL, n)
+			if end < len(jf.Items) {
+				page.NextURL = feed.PageName(jf.FeedURL, n+1)
+			}
+		}
+		pages = append(pages, page)
+	}
+	return pages
+}
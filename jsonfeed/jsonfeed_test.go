@@ -2,7 +2,14 @@ package jsonfeed
 
 import (
 	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"strings"
 	"testing"
+
+	// My packages
+	"github.com/rsdoiel/pttk/blogit"
 )
 
 func TestBasics(t *testing.T) {
@@ -84,3 +91,137 @@ func TestBasics(t *testing.T) {
 		}
 	}
 }
+
+func TestWalkFeed(t *testing.T) {
+	htdocs := t.TempDir()
+	dName := path.Join(htdocs, "2022", "07", "15")
+	os.MkdirAll(dName, 0775)
+	os.MkdirAll(path.Join(htdocs, "drafts", "2022", "07", "16"), 0775)
+	os.WriteFile(path.Join(dName, "post.md"), []byte(`---
+title: Hello World
+description: A first post
+keywords: [pttk, jsonfeed]
+author: Jane Doe
+attachments:
+  - episode.mp3
+---
+
+# Hello World
+
+This is the first paragraph.
+`), 0664)
+	os.WriteFile(path.Join(dName, "post.html"), []byte("<html><body><nav>menu</nav><article><p>This is the first paragraph.</p></article></body></html>"), 0664)
+	os.WriteFile(path.Join(dName, "episode.mp3"), []byte("0123456789"), 0664)
+	os.WriteFile(path.Join(htdocs, "drafts", "2022", "07", "16", "draft.md"), []byte("# Draft\n\nNot yet.\n"), 0664)
+	os.WriteFile(path.Join(htdocs, "about.md"), []byte("# About\n"), 0664)
+
+	feed := new(Feed)
+	if err := WalkFeed(feed, htdocs, "https://example.org", "drafts"); err != nil {
+		t.Errorf("WalkFeed() failed, %s", err)
+		t.FailNow()
+	}
+	if len(feed.Items) != 1 {
+		t.Errorf("expected 1 item, got %d", len(feed.Items))
+		t.FailNow()
+	}
+	item := feed.Items[0]
+	for label, test := range map[string][2]string{
+		"id":             {"https://example.org/2022/07/15/post.html", item.ID},
+		"title":          {"Hello World", item.Title},
+		"summary":        {"A first post", item.Summary},
+		"content_html":   {"<p>This is the first paragraph.</p>", item.ContentHTML},
+		"date_published": {"2022-07-15T00:00:00Z", item.DatePublished},
+		"tags":           {"pttk, jsonfeed", strings.Join(item.Tags, ", ")},
+	} {
+		if test[0] != test[1] {
+			t.Errorf("%s, expected %q, got %q", label, test[0], test[1])
+		}
+	}
+	if !strings.HasPrefix(item.ContentText, "# Hello World") {
+		t.Errorf("expected Markdown content_text, got %q", item.ContentText)
+	}
+	if len(item.Authors) != 1 || item.Authors[0].Name != "Jane Doe" {
+		t.Errorf("expected author Jane Doe, got %+v", item.Authors)
+	}
+	if len(item.Attachments) != 1 {
+		t.Errorf("expected one attachment, got %d", len(item.Attachments))
+		t.FailNow()
+	}
+	attachment := item.Attachments[0]
+	if attachment.URL != "https://example.org/2022/07/15/episode.mp3" || attachment.MimeType != "audio/mpeg" || attachment.SizeInBytes != 10 {
+		t.Errorf("unexpected attachment %+v", attachment)
+	}
+}
+
+func TestBlogMetaToFeed(t *testing.T) {
+	htdocs := t.TempDir()
+	for _, day := range []string{"04", "15"} {
+		fName := path.Join(htdocs, "post-"+day+".md")
+		os.WriteFile(fName, []byte("---\ntitle: Post "+day+"\n---\n\nDay "+day+".\n"), 0664)
+	}
+	blog := new(blogit.BlogMeta)
+	blog.Name = "My Blog"
+	blog.BaseURL = "https://example.org/blog"
+	for _, day := range []string{"04", "15"} {
+		if err := blog.BlogIt(htdocs, path.Join(htdocs, "post-"+day+".md"), "2022-07-"+day); err != nil {
+			t.Errorf("BlogIt() failed, %s", err)
+			t.FailNow()
+		}
+	}
+	feed := new(Feed)
+	feed.Version = Version
+	if err := BlogMetaToFeed(blog, htdocs, feed); err != nil {
+		t.Errorf("BlogMetaToFeed() failed, %s", err)
+		t.FailNow()
+	}
+	if feed.Title != "My Blog" || feed.HomePageURL != "https://example.org/blog" {
+		t.Errorf("unexpected feed title or home page, %q, %q", feed.Title, feed.HomePageURL)
+	}
+	if len(feed.Items) != 2 {
+		t.Errorf("expected 2 items, got %d", len(feed.Items))
+		t.FailNow()
+	}
+	// Newest first
+	for i, day := range []string{"15", "04"} {
+		expected := fmt.Sprintf("https://example.org/blog/2022/07/%s/post-%s.html", day, day)
+		if feed.Items[i].URL != expected {
+			t.Errorf("item %d, expected %q, got %q", i, expected, feed.Items[i].URL)
+		}
+		if feed.Items[i].Summary != "Day "+day+"." {
+			t.Errorf("item %d, expected summary %q, got %q", i, "Day "+day+".", feed.Items[i].Summary)
+		}
+	}
+	src, err := json.Marshal(feed)
+	if err != nil {
+		t.Errorf("Marshal() failed, %s", err)
+	}
+	if _, err := Parse(src); err != nil {
+		t.Errorf("Parse() failed, %s", err)
+	}
+}
+
+func TestPaginate(t *testing.T) {
+	feed := new(Feed)
+	feed.Version = Version
+	feed.Title = "Pages"
+	feed.FeedURL = "https://example.org/feed.json"
+	for i := 0; i < 5; i++ {
+		feed.Items = append(feed.Items, &Item{ID: fmt.Sprintf("%d", i)})
+	}
+	pages := feed.Paginate(2)
+	if len(pages) != 3 {
+		t.Errorf("expected 3 pages, got %d", len(pages))
+		t.FailNow()
+	}
+	for i, expected := range []string{"https://example.org/feed-2.json", "https://example.org/feed-3.json", ""} {
+		if pages[i].NextURL != expected {
+			t.Errorf("page %d, expected next_url %q, got %q", i+1, expected, pages[i].NextURL)
+		}
+	}
+	if pages[1].FeedURL != "https://example.org/feed-2.json" || len(pages[2].Items) != 1 {
+		t.Errorf("unexpected page 2 or 3, %+v, %+v", pages[1], pages[2])
+	}
+	if len(feed.Paginate(0)) != 1 {
+		t.Errorf("expected a single page when size is zero")
+	}
+}
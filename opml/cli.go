@@ -0,0 +1,115 @@
+// pttk is software for working with plain text content.
+// Copyright (C) 2022 R. S. Doiel
+//
+// This program is free software: you can redistribute it and/or modify it under the terms of the GNU Affero General Public License as published by the Free Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License along with this program. If not, see <https://www.gnu.org/licenses/>.
+package opml
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	// My packages
+	"github.com/rsdoiel/pttk/help"
+)
+
+var (
+	// Standard options
+	showHelp bool
+
+	// App specific options
+	outFormat string
+	title     string
+	outName   string
+)
+
+func usage(appName string, verb string, exitCode int) {
+	out := os.Stdout
+	if exitCode > 0 {
+		out = os.Stderr
+	}
+	fmt.Fprint(out, help.Render(appName, verb, helpText))
+	os.Exit(exitCode)
+}
+
+// ReadAny reads an OPML document, a YAML blogroll (a file ending in
+// ".yaml" or ".yml") or a newsboat style "urls" file as OPML.
+func ReadAny(fName string, title string) (*OPML, error) {
+	src, err := os.ReadFile(fName)
+	if err != nil {
+		return nil, fmt.Errorf("Reading %q, %s", fName, err)
+	}
+	var doc *OPML
+	switch ext := strings.ToLower(path.Ext(fName)); {
+	case bytes.HasPrefix(bytes.TrimSpace(src), []byte("<")):
+		doc, err = Parse(src)
+	case ext == ".yaml" || ext == ".yml":
+		var blogroll *Blogroll
+		if blogroll, err = ParseBlogroll(src); err == nil {
+			doc = blogroll.ToOPML()
+		}
+	default:
+		doc = New("", ParseURLs(src))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Parsing %q, %s", fName, err)
+	}
+	if title != "" {
+		doc.Head.Title = title
+	}
+	return doc, nil
+}
+
+// RunOPML implements the opml verb
+func RunOPML(appName string, verb string, options []string) ([]byte, error) {
+	flagSet := flag.NewFlagSet(appName+":"+verb, flag.ExitOnError)
+
+	// Standard options
+	flagSet.BoolVar(&showHelp, "help", false, "display help")
+
+	// App specific options
+	flagSet.StringVar(&outFormat, "to", "opml", "format to write, opml, urls or markdown")
+	flagSet.StringVar(&title, "title", "", "set the title of the OPML document")
+	flagSet.StringVar(&outName, "o", "", "write to this file")
+
+	flagSet.Parse(options)
+	args := flagSet.Args()
+
+	if showHelp {
+		usage(appName, verb, 0)
+	}
+	if len(args) != 1 {
+		return nil, fmt.Errorf("expected an OPML, YAML blogroll or urls file")
+	}
+	doc, err := ReadAny(args[0], title)
+	if err != nil {
+		return nil, err
+	}
+	var src []byte
+	switch outFormat {
+	case "opml":
+		if src, err = doc.Marshal(); err != nil {
+			return nil, err
+		}
+	case "urls":
+		src = bytes.TrimSuffix(URLs(doc.Feeds()), []byte("\n"))
+	case "markdown", "md":
+		src = []byte(strings.TrimSuffix(doc.ToMarkdown(), "\n"))
+	default:
+		return nil, fmt.Errorf("-to %q not supported, expected opml, urls or markdown", outFormat)
+	}
+	if outName != "" {
+		if err := os.WriteFile(outName, append(src, '\n'), 0664); err != nil {
+			return nil, fmt.Errorf("Writing %q, %s", outName, err)
+		}
+		return nil, nil
+	}
+	return src, nil
+}
@@ -0,0 +1,90 @@
+// pttk is software for working with plain text content.
+// Copyright (C) 2022 R. S. Doiel
+//
+// This program is free software: you can redistribute it and/or modify it under the terms of the GNU Affero General Public License as published by the Free Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License along with this program. If not, see <https://www.gnu.org/licenses/>.
+package opml
+
+const (
+	helpText = `% {app_name}-{verb}(1) {app_name}-{verb} user manual
+% R. S. Doiel
+% October 19, 2026
+
+# NAME
+
+{app_name} {verb}
+
+# SYNOPSIS
+
+{app_name} {verb} [OPTIONS] INPUT
+
+# DESCRIPTION
+
+{app_name} {verb} reads and writes OPML 2.0 documents. It is used to
+share a list of feeds (e.g. on feedland), to convert between OPML and
+the "urls" file used by newsboat, to generate a blogroll from a YAML
+list of sites and to render a blogroll page in Markdown.
+
+INPUT is read as OPML if it starts with "<", as a YAML blogroll if
+its name ends in ".yaml" or ".yml" and otherwise as a newsboat style
+"urls" file.
+
+In a "urls" file each line holds a feed's URL followed by its tags,
+a tag starting with "~" names the feed. When written as OPML feeds
+are grouped in an outline named for their first tag.
+
+A YAML blogroll has a title, owner_name, owner_email and a list of
+sites, each with a title, url (the site), feed, description and
+tags. The list of sites can also be given on its own.
+
+~~~
+title: My Blogroll
+owner_name: Jane Doe
+sites:
+  - title: Example
+    url: https://example.org/
+    feed: https://example.org/rss.xml
+    description: An example site
+    tags: [ friends ]
+~~~
+
+# OPTIONS
+
+-help
+: display help
+
+-o string
+: write to this file
+
+-title string
+: set the title of the OPML document
+
+-to string
+: format to write, opml, urls or markdown (default "opml")
+
+# EXAMPLES
+
+Sharing newsboat's subscriptions as OPML
+
+~~~
+	{app_name} {verb} -title "My Feeds" -o feeds.opml $HOME/.newsboat/urls
+~~~
+
+Converting an OPML file to a newsboat urls file
+
+~~~
+	{app_name} {verb} -to urls feeds.opml
+~~~
+
+Generating a blogroll and its Markdown page
+
+~~~
+	{app_name} {verb} -o blogroll.opml blogroll.yaml
+	{app_name} {verb} -to markdown blogroll.opml >blogroll.md
+~~~
+
+`
+)
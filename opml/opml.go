@@ -0,0 +1,392 @@
+// pttk is software for working with plain text content.
+// Copyright (C) 2022 R. S. Doiel
+//
+// This program is free software: you can redistribute it and/or modify it under the terms of the GNU Affero General Public License as published by the Free Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License along with this program. If not, see <https://www.gnu.org/licenses/>.
+package opml
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	// 3rd Party packages
+	"gopkg.in/yaml.v3"
+)
+
+// OPML is an OPML 2.0 document, see http://opml.org/spec2.opml
+type OPML struct {
+	XMLName xml.Name `xml:"opml" json:"-"`
+	Version string   `xml:"version,attr" json:"version"`
+	Head    *Head    `xml:"head" json:"head"`
+	Body    *Body    `xml:"body" json:"body"`
+}
+
+// Head holds the OPML document's metadata
+type Head struct {
+	Title           string `xml:"title,omitempty" json:"title,omitempty"`
+	DateCreated     string `xml:"dateCreated,omitempty" json:"dateCreated,omitempty"`
+	DateModified    string `xml:"dateModified,omitempty" json:"dateModified,omitempty"`
+	OwnerName       string `xml:"ownerName,omitempty" json:"ownerName,omitempty"`
+	OwnerEmail      string `xml:"ownerEmail,omitempty" json:"ownerEmail,omitempty"`
+	OwnerID         string `xml:"ownerId,omitempty" json:"ownerId,omitempty"`
+	Docs            string `xml:"docs,omitempty" json:"docs,omitempty"`
+	ExpansionState  string `xml:"expansionState,omitempty" json:"expansionState,omitempty"`
+	VertScrollState string `xml:"vertScrollState,omitempty" json:"vertScrollState,omitempty"`
+	WindowTop       string `xml:"windowTop,omitempty" json:"windowTop,omitempty"`
+	WindowLeft      string `xml:"windowLeft,omitempty" json:"windowLeft,omitempty"`
+	WindowBottom    string `xml:"windowBottom,omitempty" json:"windowBottom,omitempty"`
+	WindowRight     string `xml:"windowRight,omitempty" json:"windowRight,omitempty"`
+}
+
+// Body holds the OPML document's outlines
+type Body struct {
+	Outlines []*Outline `xml:"outline" json:"outlines,omitempty"`
+}
+
+// Outline is an OPML outline element. Outlines of type "rss" are
+// feeds, xmlUrl is the feed and htmlUrl the site publishing it.
+type Outline struct {
+	Text         string     `xml:"text,attr" json:"text"`
+	Type         string     `xml:"type,attr,omitempty" json:"type,omitempty"`
+	Title        string     `xml:"title,attr,omitempty" json:"title,omitempty"`
+	XMLURL       string     `xml:"xmlUrl,attr,omitempty" json:"xmlUrl,omitempty"`
+	HTMLURL      string     `xml:"htmlUrl,attr,omitempty" json:"htmlUrl,omitempty"`
+	URL          string     `xml:"url,attr,omitempty" json:"url,omitempty"`
+	Description  string     `xml:"description,attr,omitempty" json:"description,omitempty"`
+	Language     string     `xml:"language,attr,omitempty" json:"language,omitempty"`
+	Version      string     `xml:"version,attr,omitempty" json:"version,omitempty"`
+	Created      string     `xml:"created,attr,omitempty" json:"created,omitempty"`
+	Category     string     `xml:"category,attr,omitempty" json:"category,omitempty"`
+	IsComment    string     `xml:"isComment,attr,omitempty" json:"isComment,omitempty"`
+	IsBreakpoint string     `xml:"isBreakpoint,attr,omitempty" json:"isBreakpoint,omitempty"`
+	Outlines     []*Outline `xml:"outline" json:"outlines,omitempty"`
+}
+
+// Feed is a subscription listed by an OPML document or a newsboat
+// style "urls" file.
+type Feed struct {
+	// Title is the name given to the feed, it may be empty
+	Title string `json:"title,omitempty" yaml:"title,omitempty"`
+	// URL of the feed
+	URL string `json:"url" yaml:"feed"`
+	// HTMLURL is the site publishing the feed
+	HTMLURL string `json:"html_url,omitempty" yaml:"url,omitempty"`
+	// Description of the feed or site
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+	// Tags are the newsboat tags or the outlines holding the feed
+	Tags []string `json:"tags,omitempty" yaml:"tags,omitempty"`
+}
+
+// Blogroll is the YAML list of sites used to generate a blogroll
+type Blogroll struct {
+	Title      string  `yaml:"title,omitempty"`
+	OwnerName  string  `yaml:"owner_name,omitempty"`
+	OwnerEmail string  `yaml:"owner_email,omitempty"`
+	Sites      []*Feed `yaml:"sites"`
+}
+
+// Parse reads an OPML document
+func Parse(src []byte) (*OPML, error) {
+	doc := new(OPML)
+	if err := xml.Unmarshal(src, doc); err != nil {
+		return nil, err
+	}
+	if doc.Head == nil {
+		doc.Head = new(Head)
+	}
+	if doc.Body == nil {
+		doc.Body = new(Body)
+	}
+	return doc, nil
+}
+
+// ReadFile reads an OPML document from a file
+func ReadFile(fName string) (*OPML, error) {
+	src, err := os.ReadFile(fName)
+	if err != nil {
+		return nil, fmt.Errorf("Reading %q, %s", fName, err)
+	}
+	doc, err := Parse(src)
+	if err != nil {
+		return nil, fmt.Errorf("Parsing %q, %s", fName, err)
+	}
+	return doc, nil
+}
+
+// Marshal renders the OPML document as XML
+func (doc *OPML) Marshal() ([]byte, error) {
+	if doc.Version == "" {
+		doc.Version = "2.0"
+	}
+	src, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	txt := strings.ReplaceAll(fmt.Sprintf("%s%s", xml.Header, src), "></outline>", "/>")
+	return []byte(txt), nil
+}
+
+// categoryTags returns the tags of an OPML category attribute, a
+// comma separated list of slash delimited categories, e.g. "/news,/go".
+func categoryTags(category string) []string {
+	tags := []string{}
+	for _, s := range strings.Split(category, ",") {
+		if s = strings.Trim(strings.TrimSpace(s), "/"); s != "" {
+			tags = append(tags, s)
+		}
+	}
+	return tags
+}
+
+// addTag appends tag to tags if it isn't already there
+func addTag(tags []string, tag string) []string {
+	for _, t := range tags {
+		if t == tag {
+			return tags
+		}
+	}
+	return append(tags, tag)
+}
+
+// Feeds returns the feeds in the OPML document, the outlines with
+// an xmlUrl. The text of the outlines holding a feed and its
+// category attribute become its tags.
+func (doc *OPML) Feeds() []*Feed {
+	feeds := []*Feed{}
+	var walk func(outlines []*Outline, tags []string)
+	walk = func(outlines []*Outline, tags []string) {
+		for _, o := range outlines {
+			if o.XMLURL != "" {
+				feed := &Feed{
+					Title:       o.Title,
+					URL:         o.XMLURL,
+					HTMLURL:     o.HTMLURL,
+					Description: o.Description,
+					Tags:        append([]string{}, tags...),
+				}
+				if feed.Title == "" && o.Text != o.XMLURL {
+					feed.Title = o.Text
+				}
+				for _, tag := range categoryTags(o.Category) {
+					feed.Tags = addTag(feed.Tags, tag)
+				}
+				feeds = append(feeds, feed)
+			}
+			if len(o.Outlines) > 0 {
+				walk(o.Outlines, append(tags[:len(tags):len(tags)], o.Text))
+			}
+		}
+	}
+	if doc.Body != nil {
+		walk(doc.Body.Outlines, nil)
+	}
+	return feeds
+}
+
+// New returns an OPML document listing feeds. Feeds with tags are
+// placed in an outline named by their first tag, all their tags
+// are kept in the category attribute.
+func New(title string, feeds []*Feed) *OPML {
+	now := time.Now().UTC().Format(time.RFC1123Z)
+	doc := &OPML{
+		Version: "2.0",
+		Head:    &Head{Title: title, DateCreated: now, DateModified: now},
+		Body:    new(Body),
+	}
+	groups := map[string]*Outline{}
+	for _, feed := range feeds {
+		o := &Outline{
+			Text:        feed.Title,
+			Type:        "rss",
+			Title:       feed.Title,
+			XMLURL:      feed.URL,
+			HTMLURL:     feed.HTMLURL,
+			Description: feed.Description,
+		}
+		if o.Text == "" {
+			o.Text = feed.URL
+		}
+		if len(feed.Tags) == 0 {
+			doc.Body.Outlines = append(doc.Body.Outlines, o)
+			continue
+		}
+		if len(feed.Tags) > 1 {
+			categories := []string{}
+			for _, tag := range feed.Tags {
+				categories = append(categories, "/"+tag)
+			}
+			o.Category = strings.Join(categories, ",")
+		}
+		group, ok := groups[feed.Tags[0]]
+		if !ok {
+			group = &Outline{Text: feed.Tags[0]}
+			groups[feed.Tags[0]] = group
+			doc.Body.Outlines = append(doc.Body.Outlines, group)
+		}
+		group.Outlines = append(group.Outlines, o)
+	}
+	return doc
+}
+
+// fields splits a line of a newsboat urls file into fields, double
+// quotes hold fields with spaces.
+func fields(line string) []string {
+	vals := []string{}
+	var sb strings.Builder
+	quoted, inField := false, false
+	for _, r := range line {
+		switch {
+		case r == '"':
+			quoted = !quoted
+			inField = true
+		case (r == ' ' || r == '\t') && !quoted:
+			if inField {
+				vals = append(vals, sb.String())
+				sb.Reset()
+				inField = false
+			}
+		default:
+			sb.WriteRune(r)
+			inField = true
+		}
+	}
+	if inField {
+		vals = append(vals, sb.String())
+	}
+	return vals
+}
+
+// ParseURLs returns the feeds listed in a newsboat style "urls"
+// file. Each line holds a URL followed by its tags, a tag starting
+// with "~" is the feed's title. Blank lines, comments (lines
+// starting with "#") and newsboat's "query:" feeds are skipped.
+func ParseURLs(src []byte) []*Feed {
+	feeds := []*Feed{}
+	for _, line := range strings.Split(string(src), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		vals := fields(line)
+		if len(vals) == 0 || strings.HasPrefix(vals[0], "query:") {
+			continue
+		}
+		feed := &Feed{URL: vals[0]}
+		for _, tag := range vals[1:] {
+			if strings.HasPrefix(tag, "~") {
+				feed.Title = strings.TrimPrefix(tag, "~")
+			} else {
+				feed.Tags = append(feed.Tags, tag)
+			}
+		}
+		feeds = append(feeds, feed)
+	}
+	return feeds
+}
+
+// quote returns a newsboat field, quoted when it holds spaces
+func quote(s string) string {
+	if strings.ContainsAny(s, " \t\"") {
+		return `"` + strings.ReplaceAll(s, `"`, `'`) + `"`
+	}
+	return s
+}
+
+// URLs renders feeds as a newsboat style "urls" file
+func URLs(feeds []*Feed) []byte {
+	buf := new(bytes.Buffer)
+	for _, feed := range feeds {
+		buf.WriteString(quote(feed.URL))
+		for _, tag := range feed.Tags {
+			buf.WriteString(" " + quote(tag))
+		}
+		if feed.Title != "" {
+			buf.WriteString(" " + quote("~"+feed.Title))
+		}
+		buf.WriteString("\n")
+	}
+	return buf.Bytes()
+}
+
+// ParseBlogroll reads a blogroll from YAML, either a Blogroll or
+// just the list of its sites. Each site has a title, url (the
+// site), feed, description and tags.
+func ParseBlogroll(src []byte) (*Blogroll, error) {
+	blogroll := new(Blogroll)
+	if err := yaml.Unmarshal(src, blogroll); err != nil {
+		sites := []*Feed{}
+		if err2 := yaml.Unmarshal(src, &sites); err2 != nil {
+			return nil, err
+		}
+		blogroll.Sites = sites
+	}
+	return blogroll, nil
+}
+
+// ToOPML returns the blogroll as an OPML document
+func (blogroll *Blogroll) ToOPML() *OPML {
+	doc := New(blogroll.Title, blogroll.Sites)
+	doc.Head.OwnerName = blogroll.OwnerName
+	doc.Head.OwnerEmail = blogroll.OwnerEmail
+	return doc
+}
+
+// markdownLink returns a Markdown link, or just the text when
+// there is no URL.
+func markdownLink(text string, u string) string {
+	text = strings.NewReplacer("[", `\[`, "]", `\]`).Replace(text)
+	if u == "" {
+		return text
+	}
+	return fmt.Sprintf("[%s](%s)", text, u)
+}
+
+// ToMarkdown renders the OPML document's outline as a Markdown list
+// suitable for a blogroll page. Sites link to their htmlUrl (or
+// url) and are followed by a link to their feed and description.
+func (doc *OPML) ToMarkdown() string {
+	var sb strings.Builder
+	if doc.Head != nil && doc.Head.Title != "" {
+		fmt.Fprintf(&sb, "# %s\n\n", doc.Head.Title)
+	}
+	var walk func(outlines []*Outline, depth int)
+	walk = func(outlines []*Outline, depth int) {
+		indent := strings.Repeat("    ", depth)
+		for _, o := range outlines {
+			text := o.Text
+			if text == "" {
+				text = o.Title
+			}
+			u := o.HTMLURL
+			if u == "" {
+				u = o.URL
+			}
+			if u == "" && o.XMLURL == "" && len(o.Outlines) > 0 {
+				// A group of outlines
+				fmt.Fprintf(&sb, "%s- **%s**\n", indent, text)
+				walk(o.Outlines, depth+1)
+				continue
+			}
+			line := markdownLink(text, u)
+			if o.XMLURL != "" {
+				line += " (" + markdownLink("feed", o.XMLURL) + ")"
+			}
+			if o.Description != "" {
+				line += ", " + o.Description
+			}
+			fmt.Fprintf(&sb, "%s- %s\n", indent, line)
+			walk(o.Outlines, depth+1)
+		}
+	}
+	if doc.Body != nil {
+		walk(doc.Body.Outlines, 0)
+	}
+	return sb.String()
+}
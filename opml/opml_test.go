@@ -0,0 +1,115 @@
+package opml
+
+import (
+	"os"
+	"path"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	doc, err := ReadFile(path.Join("testdata", "feeds.opml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.Head.Title != "Feeds" || doc.Head.OwnerName != "Jane Doe" {
+		t.Errorf("unexpected head %+v", doc.Head)
+	}
+	feeds := doc.Feeds()
+	if len(feeds) != 3 {
+		t.Fatalf("expected 3 feeds, got %d", len(feeds))
+	}
+	expected := []string{"Friends", "Friends Nested go", ""}
+	for i, feed := range feeds {
+		if tags := strings.Join(feed.Tags, " "); tags != expected[i] {
+			t.Errorf("expected %q tagged %q, got %q", feed.URL, expected[i], tags)
+		}
+	}
+	if feeds[0].Title != "Example" || feeds[0].HTMLURL != "https://example.org/" {
+		t.Errorf("unexpected feed %+v", feeds[0])
+	}
+
+	// Round trip the document
+	src, err := doc.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(src), `<outline text="Loose" type="rss" xmlUrl="https://example.com/feed.json"/>`) {
+		t.Errorf("expected empty outline element, got\n%s", src)
+	}
+	doc2, err := Parse(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(doc2.Feeds()) != 3 || doc2.Version != "2.0" {
+		t.Errorf("round trip failed\n%s", src)
+	}
+}
+
+func TestURLs(t *testing.T) {
+	src, err := os.ReadFile(path.Join("testdata", "urls"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	feeds := ParseURLs(src)
+	if len(feeds) != 4 {
+		t.Fatalf("expected 4 feeds, got %d", len(feeds))
+	}
+	if feeds[1].Title != "Atom News" || strings.Join(feeds[1].Tags, " ") != "news" {
+		t.Errorf("unexpected feed %+v", feeds[1])
+	}
+	doc := New("Feeds", feeds)
+	if len(doc.Body.Outlines) != 2 {
+		t.Errorf("expected outlines for friends and news, got %d", len(doc.Body.Outlines))
+	}
+	expected := `testdata/rss.xml friends
+testdata/twtxt.txt friends
+testdata/atom.xml news "~Atom News"
+testdata/feed.json news
+`
+	if got := string(URLs(doc.Feeds())); got != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, got)
+	}
+}
+
+func TestBlogroll(t *testing.T) {
+	src, err := os.ReadFile(path.Join("testdata", "blogroll.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	blogroll, err := ParseBlogroll(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(blogroll.Sites) != 3 {
+		t.Fatalf("expected 3 sites, got %d", len(blogroll.Sites))
+	}
+	doc := blogroll.ToOPML()
+	if doc.Head.OwnerEmail != "jane@example.org" {
+		t.Errorf("unexpected head %+v", doc.Head)
+	}
+	feeds := doc.Feeds()
+	if len(feeds) != 3 || strings.Join(feeds[1].Tags, " ") != "news go" {
+		t.Errorf("unexpected feeds %+v", feeds)
+	}
+	expected := `# My Blogroll
+
+- **friends**
+    - [Example](https://example.org/) ([feed](https://example.org/rss.xml)), An example site
+- **news**
+    - [Example News](https://example.net/) ([feed](https://example.net/atom.xml))
+- [No Tags](https://example.com/) ([feed](https://example.com/feed.json))
+`
+	if got := doc.ToMarkdown(); got != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, got)
+	}
+
+	// A list of sites on its own
+	blogroll, err = ParseBlogroll([]byte("- title: Example\n  feed: https://example.org/rss.xml\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(blogroll.Sites) != 1 || blogroll.Sites[0].URL != "https://example.org/rss.xml" {
+		t.Errorf("unexpected blogroll %+v", blogroll)
+	}
+}
@@ -48,6 +48,14 @@ var (
 	setLicense     string
 	setLanguage    string
 	setMasthead    string
+
+	// Gophermap Options
+	recursive  bool
+	headerName string
+	footerName string
+	ignoreList string
+	sortBy     string
+	keepEdits  bool
 )
 
 func usage(appName string, verb string, helpText string, exitCode int) {
@@ -101,6 +109,12 @@ func RunGophermap(appName string, verb string, vargs []string) error {
 
 	// Application specific options
 	flagSet.StringVar(&setMasthead, "masthead", "", "Read in the Masthead from the filename provided")
+	flagSet.BoolVar(&recursive, "r", false, "Generate a gophermap in each directory of the tree")
+	flagSet.StringVar(&headerName, "header", "gophermap.header", "Name of the per-directory header file (used with -r)")
+	flagSet.StringVar(&footerName, "footer", "gophermap.footer", "Name of the per-directory footer file (used with -r)")
+	flagSet.StringVar(&ignoreList, "ignore", "", "A colon delimited list of glob patterns to leave out (used with -r)")
+	flagSet.StringVar(&sortBy, "sort", "name", "Sort menus by name, date or title (used with -r)")
+	flagSet.BoolVar(&keepEdits, "keep", false, "Keep hand edited sections of existing gophermaps (used with -r)")
 
 	flagSet.Parse(vargs)
 	args := flagSet.Args()
@@ -125,12 +139,37 @@ func RunGophermap(appName string, verb string, vargs []string) error {
 		meta.Masthead = fmt.Sprintf("%s", src)
 	}
 
+	// Handle the recursive case, a gophermap for each directory in the tree.
+	if recursive {
+		dirName := "."
+		if len(args) > 0 {
+			dirName = args[0]
+		}
+		switch sortBy {
+		case "name", "date", "title":
+		default:
+			return fmt.Errorf("-sort must be name, date or title, got %q", sortBy)
+		}
+		opts := new(GophermapOptions)
+		opts.Header = headerName
+		opts.Footer = footerName
+		opts.SortBy = sortBy
+		opts.Keep = keepEdits
+		if ignoreList != "" {
+			opts.Ignore = strings.Split(ignoreList, ":")
+		}
+		if err := meta.GophermapTree(dirName, opts); err != nil {
+			return fmt.Errorf("%s\n", err)
+		}
+		return nil
+	}
+
 	// We have a standard Gophermap command, process args.
 	gophermapName, fNames := "", []string{}
-	if len(args) > 0 {
-		gophermapName = args[0]
-	} else if len(args) > 1 {
+	if len(args) > 1 {
 		gophermapName, fNames = args[0], args[1:]
+	} else if len(args) > 0 {
+		gophermapName = args[0]
 	} else {
 		usage(appName, verb, helpTextGophermap, 1)
 	}
@@ -21,6 +21,8 @@ const (
 
 {app_name} {verb} [OPTIONS] [GOPHERMAP_NAME] [FILES_TO_LIST]
 
+{app_name} {verb} -r [OPTIONS] [DIRECTORY]
+
 # DESCRIPTIOJ
 
 {app_name} {verb} provides support for generating Gophermaps, the "index" page
@@ -32,16 +34,50 @@ If FILES_TO_LIST is not provided then the working directory or location of
 the GOPHERMAP_NAME will be used. The list of links will be files or directires
 with no extensions and files with the extensions of ".txt" and ".md".
 
+With the "-r" option {app_name} {verb} walks the DIRECTORY tree (defaults to
+the working directory) and writes a gophermap in each directory found.
+Every file and sub directory is listed except dot files, the gophermap
+itself, the header and footer files and anything matching an ignore
+pattern. Directories are listed before files. Markdown and text files are
+listed using the "title" in their front matter when available.
+
+If a directory holds a header file (default "gophermap.header") its
+contents are placed at the top of that directory's gophermap, likewise
+a footer file (default "gophermap.footer") is placed at the bottom.
+
+With "-keep" any lines of an existing gophermap between a "#pttk:keep"
+line and a "#pttk:end" line are preserved where they were, after the
+same line of the generated menu. Gophernicus treats lines starting
+with "#" as comments so the markers are not shown to Gopher clients.
+
 # OPTIONS
 
 What follows are the options supported by the phlogit verb.
 
+-footer FILENAME
+: Name of the per-directory footer file used with "-r" (default "gophermap.footer")
+
+-header FILENAME
+: Name of the per-directory header file used with "-r" (default "gophermap.header")
+
 -help
 : display {verb} help
 
+-ignore PATTERNS
+: A colon delimited list of glob patterns for files and directories to leave out, used with "-r"
+
+-keep
+: Keep hand edited sections of existing gophermaps, used with "-r"
+
 -masthead FILENAME
 : Use thie specified file contents as the "masthead" of the Gophermap
 
+-r
+: Generate a gophermap for each directory in the tree
+
+-sort name|date|title
+: Sort menu items by name, modification date (newest first) or title, used with "-r" (default "name")
+
 -verbose
 : verbose output
 
@@ -55,6 +91,13 @@ What follows are the options supported by the phlogit verb.
 	   TheWholeHole.txt
 ~~~
 
+Generating gophermaps for a whole document tree, newest first,
+leaving out drafts and keeping any hand edited sections.
+
+~~~shell
+	{app_name} {verb} -r -sort date -ignore "drafts:*.bak" -keep $HOME/gopher
+~~~
+
 `
 	helpTextPhlog = `% {app_name}-{verb}(1) {app_name}-{verb} user manual
 % R. S. Doiel
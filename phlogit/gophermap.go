@@ -0,0 +1,326 @@
+// pttk is software for working with plain text content.
+// Copyright (C) 2022 R. S. Doiel
+//
+// This program is free software: you can redistribute it and/or modify it under the terms of the GNU Affero General Public License as published by the Free Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License along with this program. If not, see <https://www.gnu.org/licenses/>.
+package phlogit
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	// My packages
+	"github.com/rsdoiel/pttk/frontmatter"
+
+	// 3rd Party Packages
+	"git.mills.io/prologic/go-gopher"
+)
+
+const (
+	// KeepStart marks the start of a hand edited section in a gophermap.
+	// Lines starting with "#" are treated as comments by Gophernicus
+	// so the marker is not shown to Gopher clients.
+	KeepStart = "#pttk:keep"
+	// KeepEnd marks the end of a hand edited section in a gophermap.
+	KeepEnd = "#pttk:end"
+)
+
+// GophermapOptions holds the settings used when generating gophermaps
+// for a whole directory tree.
+type GophermapOptions struct {
+	// Header is the name of a file that, when found in a directory,
+	// is included at the top of that directory's gophermap.
+	Header string `json:"header,omitempty" yaml:"header,omitempty"`
+	// Footer is the name of a file that, when found in a directory,
+	// is included at the bottom of that directory's gophermap.
+	Footer string `json:"footer,omitempty" yaml:"footer,omitempty"`
+	// Ignore holds a list of glob patterns (see path.Match). Files
+	// and directories matching a pattern are left out of the menus.
+	Ignore []string `json:"ignore,omitempty" yaml:"ignore,omitempty"`
+	// SortBy is either "name", "date" (newest first) or "title".
+	SortBy string `json:"sort_by,omitempty" yaml:"sort_by,omitempty"`
+	// Keep preserves sections of an existing gophermap delimited
+	// by KeepStart and KeepEnd lines.
+	Keep bool `json:"keep,omitempty" yaml:"keep,omitempty"`
+}
+
+// DefaultGophermapOptions returns the options used by
+// `pttk gophermap -r` when none are given.
+func DefaultGophermapOptions() *GophermapOptions {
+	opts := new(GophermapOptions)
+	opts.Header = "gophermap.header"
+	opts.Footer = "gophermap.footer"
+	opts.SortBy = "name"
+	return opts
+}
+
+// menuEntry holds what we need to know to list a file or directory
+// in a gophermap.
+type menuEntry struct {
+	typeCode gopher.ItemType
+	name     string
+	title    string
+	info     os.FileInfo
+}
+
+// isIgnored checks the base name and relative path against
+// the ignore patterns.
+func (opts *GophermapOptions) isIgnored(relPath string) bool {
+	name := path.Base(relPath)
+	for _, pattern := range opts.Ignore {
+		if pattern == "" {
+			continue
+		}
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+		if ok, _ := path.Match(pattern, relPath); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// titleFromFile returns the front matter title of a document
+// or an empty string if none is found.
+func titleFromFile(fName string) string {
+	src, err := frontmatter.ReadFile(fName)
+	if err != nil || len(src) == 0 {
+		return ""
+	}
+	obj := map[string]interface{}{}
+	if err := json.Unmarshal(src, &obj); err != nil {
+		return ""
+	}
+	if title, ok := obj["title"]; ok {
+		switch title.(type) {
+		case string:
+			return strings.TrimSpace(title.(string))
+		}
+	}
+	return ""
+}
+
+// keepSection is a hand edited section of a gophermap and where it
+// was found, after the generated line after and at generated lines
+// from the top. last is true when no generated lines follow it.
+type keepSection struct {
+	lines []string
+	after string
+	at    int
+	last  bool
+}
+
+// readKeepSections returns the hand edited sections, including
+// their delimiters, found in an existing gophermap. Sections that
+// follow each other are kept as one.
+func readKeepSections(fName string) []*keepSection {
+	src, err := os.ReadFile(fName)
+	if err != nil {
+		return nil
+	}
+	lines := strings.Split(strings.TrimSuffix(strings.ReplaceAll(string(src), "\r\n", "\n"), "\n"), "\n")
+	sections := []*keepSection{}
+	generated := []string{}
+	inKeep := false
+	for _, line := range lines {
+		if !inKeep && strings.HasPrefix(line, KeepStart) {
+			inKeep = true
+			if n := len(sections); n == 0 || sections[n-1].at != len(generated) {
+				section := new(keepSection)
+				section.at = len(generated)
+				if section.at > 0 {
+					section.after = generated[section.at-1]
+				}
+				sections = append(sections, section)
+			}
+		}
+		if !inKeep {
+			generated = append(generated, line)
+			continue
+		}
+		section := sections[len(sections)-1]
+		section.lines = append(section.lines, line)
+		if strings.HasPrefix(line, KeepEnd) {
+			inKeep = false
+		}
+	}
+	// Close an unterminated section so the next run can find it.
+	if inKeep {
+		section := sections[len(sections)-1]
+		section.lines = append(section.lines, KeepEnd)
+	}
+	for _, section := range sections {
+		section.last = section.at > 0 && section.at == len(generated)
+	}
+	return sections
+}
+
+// insertKeepSections puts the hand edited sections back into the
+// generated lines of a gophermap where they were found, after the
+// same line when it is still there otherwise at the same position.
+func insertKeepSections(lines []string, sections []*keepSection) []string {
+	offset := 0
+	for _, section := range sections {
+		at := section.at + offset
+		if section.last {
+			at = len(lines)
+		} else if section.after != "" {
+			for i, line := range lines {
+				if line == section.after {
+					at = i + 1
+					break
+				}
+			}
+		}
+		if at > len(lines) {
+			at = len(lines)
+		}
+		merged := append([]string{}, lines[:at]...)
+		merged = append(merged, section.lines...)
+		lines = append(merged, lines[at:]...)
+		offset += len(section.lines)
+	}
+	return lines
+}
+
+// readTextFile reads a header or footer file returning its lines.
+func readTextFile(fName string) ([]string, error) {
+	src, err := os.ReadFile(fName)
+	if err != nil {
+		return nil, err
+	}
+	txt := strings.TrimSuffix(strings.ReplaceAll(string(src), "\r\n", "\n"), "\n")
+	return strings.Split(txt, "\n"), nil
+}
+
+// sortEntries orders directories before files, then applies
+// the sort option.
+func sortEntries(entries []*menuEntry, sortBy string) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if (a.typeCode == gopher.DIRECTORY) != (b.typeCode == gopher.DIRECTORY) {
+			return a.typeCode == gopher.DIRECTORY
+		}
+		switch sortBy {
+		case "date":
+			return a.info.ModTime().After(b.info.ModTime())
+		case "title":
+			return strings.ToLower(a.title) < strings.ToLower(b.title)
+		default:
+			return a.name < b.name
+		}
+	})
+}
+
+// gophermapDir renders the gophermap for a single directory.
+// root is the top of the tree being mapped.
+func (meta *PhlogMeta) gophermapDir(root string, dirName string, opts *GophermapOptions) error {
+	gophermapName := path.Join(dirName, "gophermap")
+	lines := []string{}
+	if meta.Masthead != "" {
+		lines = append(lines, strings.Split(strings.TrimSuffix(strings.ReplaceAll(meta.Masthead, "\r\n", "\n"), "\n"), "\n")...)
+	}
+	if opts.Header != "" {
+		if header, err := readTextFile(path.Join(dirName, opts.Header)); err == nil {
+			lines = append(lines, header...)
+		}
+	}
+
+	items, err := os.ReadDir(dirName)
+	if err != nil {
+		return err
+	}
+	entries := []*menuEntry{}
+	for _, item := range items {
+		name := item.Name()
+		if strings.HasPrefix(name, ".") || name == "gophermap" ||
+			name == opts.Header || name == opts.Footer {
+			continue
+		}
+		relPath, err := filepath.Rel(root, path.Join(dirName, name))
+		if err != nil {
+			relPath = name
+		}
+		if opts.isIgnored(filepath.ToSlash(relPath)) {
+			continue
+		}
+		info, err := item.Info()
+		if err != nil {
+			return err
+		}
+		entry := new(menuEntry)
+		entry.name = name
+		entry.title = name
+		entry.info = info
+		if item.IsDir() {
+			entry.typeCode = gopher.DIRECTORY
+		} else if ext := path.Ext(name); ext == ".md" || ext == ".txt" || ext == "" {
+			// Markdown and plain text are always text to a Gopher client.
+			entry.typeCode = gopher.FILE
+			if title := titleFromFile(path.Join(dirName, name)); title != "" {
+				entry.title = title
+			}
+		} else {
+			entry.typeCode = gopher.GetItemType(path.Join(dirName, name))
+		}
+		entries = append(entries, entry)
+	}
+	sortEntries(entries, opts.SortBy)
+	for _, entry := range entries {
+		lines = append(lines, fmt.Sprintf("%c%s\t%s", entry.typeCode, entry.title, entry.name))
+	}
+
+	if opts.Footer != "" {
+		if footer, err := readTextFile(path.Join(dirName, opts.Footer)); err == nil {
+			lines = append(lines, footer...)
+		}
+	}
+	if opts.Keep {
+		lines = insertKeepSections(lines, readKeepSections(gophermapName))
+	}
+	src := []byte(strings.Join(lines, "\r\n") + "\r\n")
+	return os.WriteFile(gophermapName, src, 0664)
+}
+
+// GophermapTree walks the directory tree starting at root and
+// writes a gophermap in each directory found. Dot directories and
+// directories matching an ignore pattern are skipped.
+// @param root - the directory at the top of the Gopher hole
+// @param opts - the options to apply, if nil DefaultGophermapOptions() is used
+//
+// @returns an error type
+func (meta *PhlogMeta) GophermapTree(root string, opts *GophermapOptions) error {
+	if opts == nil {
+		opts = DefaultGophermapOptions()
+	}
+	if root == "" {
+		root = "."
+	}
+	return filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if p != root {
+			if strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			if relPath, err := filepath.Rel(root, p); err == nil && opts.isIgnored(filepath.ToSlash(relPath)) {
+				return filepath.SkipDir
+			}
+		}
+		return meta.gophermapDir(root, p, opts)
+	})
+}
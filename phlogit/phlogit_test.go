@@ -16,47 +16,6 @@ import (
 	"testing"
 )
 
-func TestPrivateFuncs(t *testing.T) {
-	rsdoiel := "R. S. Doiel"
-	obj := map[string]string{}
-	obj["name"] = rsdoiel
-	objs := append([]interface{}{}, obj)
-	creators := unpackCreators(objs)
-	if len(creators) != 1 {
-		t.Errorf("expected one creator got %d", len(creators))
-	} else {
-		if fmt.Sprintf("%T", creators[0].Name) != "string" {
-			t.Errorf("Expected crestors[0].Name to ba a string, got %T", creators[0].Name)
-		}
-		if strings.Compare(creators[0].Name, rsdoiel) != 0 {
-			t.Errorf("expected creators[0].Name = %q, got %q", rsdoiel, creators[0].Name)
-		}
-	}
-	ymd, err := calcYMD("2003-01-02")
-	if err != nil {
-		t.Errorf("Unexpected err %s, calcYMD()", err)
-		t.FailNow()
-	}
-	if len(ymd) != 3 {
-		t.Errorf("expected len(ymd) = 3, got %+v", ymd)
-	} else {
-		for i, val := range []string{"2003", "01", "02"} {
-			if ymd[i] != val {
-				t.Errorf("expected %q, got %q", val, ymd[i])
-			}
-		}
-		expectedS := path.Join("phlog", "2003", "01", "02")
-		resultS, err := calcPath("phlog", ymd)
-		if err != nil {
-			t.Errorf("Unexpected err %s, calcPath()", err)
-		}
-
-		if expectedS != resultS {
-			t.Errorf("expected %q, got %q", expectedS, resultS)
-		}
-	}
-}
-
 func TestExportedFuncs(t *testing.T) {
 	var (
 		pName       string
@@ -122,3 +81,75 @@ func TestRefreshFromPath(t *testing.T) {
 	}
 	meta.Save(phlogJSON)
 }
+
+func TestGophermapTree(t *testing.T) {
+	root := t.TempDir()
+	os.MkdirAll(path.Join(root, "2022", "07"), 0775)
+	os.MkdirAll(path.Join(root, "drafts"), 0775)
+	os.MkdirAll(path.Join(root, ".git"), 0775)
+	os.WriteFile(path.Join(root, "gophermap.header"), []byte("Welcome to my hole\n"), 0664)
+	os.WriteFile(path.Join(root, "about.md"), []byte("---\ntitle: About this hole\n---\n\nHello\n"), 0664)
+	os.WriteFile(path.Join(root, "notes.txt"), []byte("notes\n"), 0664)
+	os.WriteFile(path.Join(root, "notes.bak"), []byte("notes\n"), 0664)
+	os.WriteFile(path.Join(root, "2022", "07", "post.md"), []byte("# A post\n"), 0664)
+	// An existing gophermap with hand edited sections at the top, in
+	// the menu and at the bottom
+	os.WriteFile(path.Join(root, "gophermap"), []byte(strings.Join([]string{
+		"#pttk:keep",
+		"at the top",
+		"#pttk:end",
+		"Welcome to my hole",
+		"12022\t2022",
+		"#pttk:keep",
+		"after 2022",
+		"#pttk:end",
+		"0notes.txt\tnotes.txt",
+		"#pttk:keep",
+		"at the bottom",
+		"#pttk:end",
+		"",
+	}, "\r\n")), 0664)
+
+	meta := new(PhlogMeta)
+	opts := DefaultGophermapOptions()
+	opts.Ignore = []string{"drafts", "*.bak"}
+	opts.Keep = true
+	if err := meta.GophermapTree(root, opts); err != nil {
+		t.Errorf("expected nil, got %s", err)
+		t.FailNow()
+	}
+	src, err := os.ReadFile(path.Join(root, "gophermap"))
+	if err != nil {
+		t.Errorf("expected a root gophermap, %s", err)
+		t.FailNow()
+	}
+	expected := strings.Join([]string{
+		"#pttk:keep",
+		"at the top",
+		"#pttk:end",
+		"Welcome to my hole",
+		"12022\t2022",
+		"#pttk:keep",
+		"after 2022",
+		"#pttk:end",
+		"0About this hole\tabout.md",
+		"0notes.txt\tnotes.txt",
+		"#pttk:keep",
+		"at the bottom",
+		"#pttk:end",
+		"",
+	}, "\r\n")
+	if string(src) != expected {
+		t.Errorf("expected\n%q\ngot\n%q", expected, src)
+	}
+	for _, p := range []string{path.Join(root, "2022", "gophermap"), path.Join(root, "2022", "07", "gophermap")} {
+		if _, err := os.Stat(p); err != nil {
+			t.Errorf("expected %q, %s", p, err)
+		}
+	}
+	for _, p := range []string{path.Join(root, "drafts", "gophermap"), path.Join(root, ".git", "gophermap")} {
+		if _, err := os.Stat(p); err == nil {
+			t.Errorf("did not expect %q", p)
+		}
+	}
+}
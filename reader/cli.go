@@ -0,0 +1,99 @@
+// pttk is software for working with plain text content.
+// Copyright (C) 2022 R. S. Doiel
+//
+// This program is free software: you can redistribute it and/or modify it under the terms of the GNU Affero General Public License as published by the Free Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License along with this program. If not, see <https://www.gnu.org/licenses/>.
+package reader
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	// My packages
+	"github.com/rsdoiel/pttk/help"
+)
+
+var (
+	// Standard options
+	showHelp bool
+
+	// App specific options
+	storeName   string
+	htmlName    string
+	riverTitle  string
+	offline     bool
+	riverSize   int
+	keepSize    int
+	summarySize int
+	tagName     string
+)
+
+func usage(appName string, verb string, exitCode int) {
+	out := os.Stdout
+	if exitCode > 0 {
+		out = os.Stderr
+	}
+	fmt.Fprint(out, help.Render(appName, verb, helpText))
+	os.Exit(exitCode)
+}
+
+// RunReader implements the reader verb. It updates the store from
+// the subscription list and renders the river of news.
+func RunReader(appName string, verb string, options []string) error {
+	flagSet := flag.NewFlagSet(appName+":"+verb, flag.ExitOnError)
+
+	// Standard options
+	flagSet.BoolVar(&showHelp, "help", false, "display help")
+
+	// App specific options
+	flagSet.StringVar(&storeName, "store", "reader.json", "the JSON file holding the entries read")
+	flagSet.StringVar(&htmlName, "html", "", "write the river of news as an HTML page to this file")
+	flagSet.StringVar(&riverTitle, "title", "River of News", "title of the HTML page")
+	flagSet.BoolVar(&offline, "offline", false, "render the store without fetching the feeds")
+	flagSet.IntVar(&riverSize, "n", 50, "number of entries in the river, zero for all")
+	flagSet.IntVar(&keepSize, "keep", 1000, "number of entries kept in the store, zero for all")
+	flagSet.IntVar(&summarySize, "summary", 280, "maximum length of a summary in characters")
+	flagSet.StringVar(&tagName, "tag", "", "only show entries from subscriptions with this tag")
+
+	flagSet.Parse(options)
+	args := flagSet.Args()
+
+	if showHelp {
+		usage(appName, verb, 0)
+	}
+	if len(args) == 0 && !offline {
+		return fmt.Errorf("expected an OPML or urls file listing subscriptions")
+	}
+
+	store, err := LoadStore(storeName)
+	if err != nil {
+		return err
+	}
+	if !offline {
+		subscriptions, err := ReadSubscriptions(args[0])
+		if err != nil {
+			return err
+		}
+		for _, err := range Update(store, subscriptions, summarySize, keepSize) {
+			fmt.Fprintf(os.Stderr, "WARNING: %s\n", err)
+		}
+		if err := store.Save(storeName); err != nil {
+			return err
+		}
+	}
+	entries := store.River(riverSize, tagName)
+	if htmlName != "" {
+		out, err := os.Create(htmlName)
+		if err != nil {
+			return fmt.Errorf("Creating %q, %s", htmlName, err)
+		}
+		defer out.Close()
+		return RenderHTML(out, riverTitle, store.Updated, entries)
+	}
+	RenderText(os.Stdout, entries)
+	return nil
+}
@@ -0,0 +1,86 @@
+// pttk is software for working with plain text content.
+// Copyright (C) 2022 R. S. Doiel
+//
+// This program is free software: you can redistribute it and/or modify it under the terms of the GNU Affero General Public License as published by the Free Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License along with this program. If not, see <https://www.gnu.org/licenses/>.
+package reader
+
+const (
+	helpText = `% {app_name}-{verb}(1) {app_name}-{verb} user manual
+% R. S. Doiel
+% October 19, 2026
+
+# NAME
+
+{app_name} {verb}
+
+# SYNOPSIS
+
+{app_name} {verb} [OPTIONS] SUBSCRIPTIONS
+
+# DESCRIPTION
+
+{app_name} {verb} is a feed reader. It reads the subscription list
+SUBSCRIPTIONS, an OPML file or a newsboat style "urls" file, fetches
+each feed and adds the new entries to a local JSON store (see
+"-store"). It then renders a "mailbox" style river of news, the
+newest entries first, to the terminal or with "-html" to a static
+HTML page.
+
+RSS 2, Atom, JSON Feed and twtxt feeds are read. A feed's URL can be
+http://, https://, gopher:// (a menu's items become entries),
+file:// or the path to a local file. If a feed can't be read a
+warning is shown and the other feeds are still read.
+
+In a "urls" file each line holds a feed's URL followed by its tags,
+a tag starting with "~" names the feed (e.g. ~"My Friend").
+In an OPML file the outlines holding a feed are its tags.
+
+# OPTIONS
+
+-help
+: display help
+
+-html string
+: write the river of news as an HTML page to this file
+
+-keep int
+: number of entries kept in the store, zero for all (default 1000)
+
+-n int
+: number of entries in the river, zero for all (default 50)
+
+-offline
+: render the store without fetching the feeds
+
+-store string
+: the JSON file holding the entries read (default "reader.json")
+
+-summary int
+: maximum length of a summary in characters (default 280)
+
+-tag string
+: only show entries from subscriptions with this tag
+
+-title string
+: title of the HTML page (default "River of News")
+
+# EXAMPLES
+
+Reading the feeds in newsboat's urls file
+
+~~~
+	{app_name} {verb} $HOME/.newsboat/urls
+~~~
+
+Writing a river of news page for the feeds in "feeds.opml"
+
+~~~
+	{app_name} {verb} -store river.json -html river.html feeds.opml
+~~~
+
+`
+)
@@ -0,0 +1,288 @@
+// pttk is software for working with plain text content.
+// Copyright (C) 2022 R. S. Doiel
+//
+// This program is free software: you can redistribute it and/or modify it under the terms of the GNU Affero General Public License as published by the Free Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License along with this program. If not, see <https://www.gnu.org/licenses/>.
+package reader
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	// My packages
+	"github.com/rsdoiel/pttk/feed"
+	"github.com/rsdoiel/pttk/gs"
+	// jsonfeed and rss register their formats with feed
+	_ "github.com/rsdoiel/pttk/jsonfeed"
+	_ "github.com/rsdoiel/pttk/rss"
+
+	// 3rd Party packages
+	"git.mills.io/prologic/go-gopher"
+)
+
+var (
+	// tagExp matches HTML tags, they are removed from summaries
+	tagExp = regexp.MustCompile(`<[^>]*>`)
+	// spaceExp matches runs of white space
+	spaceExp = regexp.MustCompile(`\s+`)
+
+	// Timeout points at feed.Timeout, how long we wait for a feed to
+	// be fetched.
+	//
+	// Deprecated: set feed.Timeout.
+	Timeout = &feed.Timeout
+)
+
+// Entry is an item read from a feed
+type Entry struct {
+	// ID is the item's guid, id or link
+	ID string `json:"id"`
+	// Feed is the title of the feed the item was read from
+	Feed string `json:"feed"`
+	// FeedURL is the URL of the subscription
+	FeedURL string `json:"feed_url"`
+	// Tags of the subscription
+	Tags []string `json:"tags,omitempty"`
+	// Title of the item
+	Title string `json:"title,omitempty"`
+	// Link to the item
+	Link string `json:"link,omitempty"`
+	// Summary is the item's description as plain text
+	Summary string `json:"summary,omitempty"`
+	// Published is the item's publication date (RFC 3339)
+	Published string `json:"published,omitempty"`
+	// Seen is when the item was first read (RFC 3339)
+	Seen string `json:"seen"`
+}
+
+// Store holds the entries read from the subscriptions
+type Store struct {
+	Updated string   `json:"updated,omitempty"`
+	Entries []*Entry `json:"entries"`
+}
+
+// Date returns when the entry was published or, for items without
+// a date, when it was first seen.
+func (entry *Entry) Date() time.Time {
+	for _, s := range []string{entry.Published, entry.Seen} {
+		if dt, err := time.Parse(time.RFC3339, s); err == nil {
+			return dt
+		}
+	}
+	return time.Time{}
+}
+
+// LoadStore reads a store, a new store is returned when fName
+// doesn't exist yet.
+func LoadStore(fName string) (*Store, error) {
+	store := new(Store)
+	src, err := os.ReadFile(fName)
+	if os.IsNotExist(err) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Reading %q, %s", fName, err)
+	}
+	if err := json.Unmarshal(src, store); err != nil {
+		return nil, fmt.Errorf("Unmarshal %q, %s", fName, err)
+	}
+	return store, nil
+}
+
+// Save writes the store as JSON
+func (store *Store) Save(fName string) error {
+	src, err := json.MarshalIndent(store, "", "    ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(fName, append(src, '\n'), 0664); err != nil {
+		return fmt.Errorf("Writing %q, %s", fName, err)
+	}
+	return nil
+}
+
+// Merge adds the entries not already in the store and updates the
+// ones that are, keeping when they were first seen. The entries are
+// kept newest first and trimmed to keep entries when keep is
+// greater than zero.
+func (store *Store) Merge(entries []*Entry, keep int) {
+	known := map[string]*Entry{}
+	for _, entry := range store.Entries {
+		known[entry.FeedURL+"\t"+entry.ID] = entry
+	}
+	for _, entry := range entries {
+		key := entry.FeedURL + "\t" + entry.ID
+		if old, ok := known[key]; ok {
+			entry.Seen = old.Seen
+			*old = *entry
+			continue
+		}
+		known[key] = entry
+		store.Entries = append(store.Entries, entry)
+	}
+	sort.SliceStable(store.Entries, func(i, j int) bool {
+		return store.Entries[i].Date().After(store.Entries[j].Date())
+	})
+	if keep > 0 && len(store.Entries) > keep {
+		store.Entries = store.Entries[:keep]
+	}
+}
+
+// Fetch retrieves a feed from a http(s)://, gopher:// or file://
+// URL or a local file.
+func Fetch(uri string) ([]byte, error) {
+	if !strings.HasPrefix(uri, "gopher://") {
+		return feed.Fetch(uri)
+	}
+	item, err := gs.ParseGopherURL(uri)
+	if err != nil {
+		return nil, err
+	}
+	if item.Type == gopher.DIRECTORY {
+		dir, err := item.FetchDirectory()
+		if err != nil {
+			return nil, err
+		}
+		// Menus are kept as tab delimited gophermap lines
+		lines := []string{}
+		for _, child := range dir.Items {
+			lines = append(lines, fmt.Sprintf("%c%s\t%s\t%s\t%d", child.Type, child.Description, child.Selector, child.Host, child.Port))
+		}
+		return []byte(strings.Join(lines, "\n")), nil
+	}
+	rd, err := item.FetchFile()
+	if err != nil {
+		return nil, err
+	}
+	defer rd.Close()
+	return io.ReadAll(rd)
+}
+
+// plainText turns an HTML description into a short plain text
+// summary of at most size characters.
+func plainText(src string, size int) string {
+	txt := html.UnescapeString(tagExp.ReplaceAllString(src, " "))
+	txt = strings.TrimSpace(spaceExp.ReplaceAllString(txt, " "))
+	if runes := []rune(txt); size > 0 && len(runes) > size {
+		txt = strings.TrimSpace(string(runes[:size])) + " ..."
+	}
+	return txt
+}
+
+// ParseFeed returns the title and entries of an RSS 2, Atom, JSON
+// Feed, twtxt or gophermap document read from feedURL. Summaries
+// are trimmed to summarySize characters.
+func ParseFeed(src []byte, feedURL string, summarySize int) (string, []*Entry, error) {
+	txt := bytes.TrimSpace(src)
+	entries := []*Entry{}
+	if feed.Sniff(txt) != "" {
+		f, _, err := feed.Parse(txt)
+		if err != nil {
+			return "", nil, err
+		}
+		for _, item := range f.Entries {
+			entry := &Entry{ID: item.ID, Title: item.Title, Link: item.Link, Published: item.Published}
+			if entry.ID == "" {
+				entry.ID = item.Link
+			}
+			if entry.Published == "" {
+				entry.Published = item.Updated
+			}
+			switch {
+			case item.Summary != "":
+				entry.Summary = plainText(item.Summary, summarySize)
+			case item.ContentHTML != "":
+				entry.Summary = plainText(item.ContentHTML, summarySize)
+			default:
+				entry.Summary = plainText(html.EscapeString(item.ContentText), summarySize)
+			}
+			entries = append(entries, entry)
+		}
+		return f.Title, entries, nil
+	}
+	if strings.HasPrefix(feedURL, "gopher://") {
+		for _, line := range strings.Split(string(txt), "\n") {
+			parts := strings.Split(strings.TrimRight(line, "\r"), "\t")
+			if len(parts) < 4 || len(parts[0]) < 1 || parts[0][0] == byte(gopher.INFO) {
+				continue
+			}
+			entry := &Entry{Title: parts[0][1:]}
+			entry.Link = fmt.Sprintf("gopher://%s:%s/%c%s", parts[2], parts[3], parts[0][0], parts[1])
+			if strings.HasPrefix(parts[1], "URL:") {
+				entry.Link = strings.TrimPrefix(parts[1], "URL:")
+			}
+			entry.ID = entry.Link
+			entries = append(entries, entry)
+		}
+		return feedURL, entries, nil
+	}
+	// Otherwise we have a twtxt file, "TIMESTAMP\tTEXT" lines
+	for _, line := range strings.Split(string(txt), "\n") {
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		parts := strings.SplitN(strings.TrimRight(line, "\r"), "\t", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		published := feed.NormalizeDate(parts[0])
+		if published == "" {
+			continue
+		}
+		entry := &Entry{ID: parts[0], Published: published}
+		entry.Summary = plainText(html.EscapeString(parts[1]), summarySize)
+		entries = append(entries, entry)
+	}
+	if len(entries) == 0 && len(txt) > 0 {
+		return "", nil, fmt.Errorf("unknown feed format")
+	}
+	return feedURL, entries, nil
+}
+
+// Update fetches and parses each subscription merging their entries
+// into the store. The errors of the subscriptions that couldn't be
+// read are returned, the others are still merged.
+func Update(store *Store, subscriptions []*Subscription, summarySize int, keep int) []error {
+	errs := []error{}
+	now := time.Now().UTC().Format(time.RFC3339)
+	entries := []*Entry{}
+	for _, subscription := range subscriptions {
+		src, err := Fetch(subscription.URL)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s, %s", subscription.URL, err))
+			continue
+		}
+		title, items, err := ParseFeed(src, subscription.URL, summarySize)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s, %s", subscription.URL, err))
+			continue
+		}
+		if subscription.Title != "" {
+			title = subscription.Title
+		}
+		for _, entry := range items {
+			entry.Feed = title
+			entry.FeedURL = subscription.URL
+			entry.Tags = subscription.Tags
+			entry.Seen = now
+			if entry.ID == "" {
+				entry.ID = entry.Link + "\t" + entry.Title
+			}
+			entries = append(entries, entry)
+		}
+	}
+	store.Merge(entries, keep)
+	store.Updated = now
+	return errs
+}
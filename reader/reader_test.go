@@ -0,0 +1,109 @@
+package reader
+
+import (
+	"bytes"
+	"path"
+	"strings"
+	"testing"
+)
+
+func TestSubscriptions(t *testing.T) {
+	fromOPML, err := ReadSubscriptions(path.Join("testdata", "subscriptions.opml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromURLs, err := ReadSubscriptions(path.Join("testdata", "urls"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, subscriptions := range map[string][]*Subscription{"opml": fromOPML, "urls": fromURLs} {
+		if len(subscriptions) != 4 {
+			t.Fatalf("%s: expected 4 subscriptions, got %d", name, len(subscriptions))
+		}
+		for _, subscription := range subscriptions {
+			if len(subscription.Tags) != 1 {
+				t.Errorf("%s: expected one tag for %q, got %+v", name, subscription.URL, subscription.Tags)
+			}
+			if subscription.URL == "testdata/atom.xml" && subscription.Title != "Atom News" {
+				t.Errorf("%s: expected title %q, got %q", name, "Atom News", subscription.Title)
+			}
+		}
+	}
+	if tag := fromOPML[0].Tags[0]; tag != "Friends" {
+		t.Errorf("expected tag %q, got %q", "Friends", tag)
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	subscriptions, err := ReadSubscriptions(path.Join("testdata", "subscriptions.opml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	subscriptions = append(subscriptions, &Subscription{URL: path.Join("testdata", "missing.xml")})
+	store := new(Store)
+	errs := Update(store, subscriptions, 280, 0)
+	if len(errs) != 1 {
+		t.Errorf("expected one error for the missing feed, got %+v", errs)
+	}
+	if len(store.Entries) != 6 {
+		t.Fatalf("expected 6 entries, got %d", len(store.Entries))
+	}
+	expected := []string{"", "Atom entry", "Second RSS post", "First RSS post", "JSON Feed item", ""}
+	for i, entry := range store.Entries {
+		if entry.Title != expected[i] {
+			t.Errorf("expected entry %d to be %q, got %q", i, expected[i], entry.Title)
+		}
+	}
+	if s := store.Entries[0].Summary; s != "Hello from twtxt" {
+		t.Errorf("expected twtxt summary, got %q", s)
+	}
+	if s := store.Entries[2].Summary; s != "The second post." {
+		t.Errorf("expected plain text summary, got %q", s)
+	}
+	if s := store.Entries[1].Feed; s != "Atom News" {
+		t.Errorf("expected feed %q, got %q", "Atom News", s)
+	}
+
+	// Reading the feeds again shouldn't add entries or change
+	// when they were first seen.
+	seen := "2022-08-01T00:00:00Z"
+	for _, entry := range store.Entries {
+		entry.Seen = seen
+	}
+	Update(store, subscriptions, 280, 3)
+	if len(store.Entries) != 3 {
+		t.Fatalf("expected 3 entries kept, got %d", len(store.Entries))
+	}
+	for _, entry := range store.Entries {
+		if entry.Seen != seen {
+			t.Errorf("expected %q seen %q, got %q", entry.Title, seen, entry.Seen)
+		}
+	}
+
+	if river := store.River(0, "news"); len(river) != 1 || river[0].Title != "Atom entry" {
+		t.Errorf("expected the Atom entry for tag news, got %+v", river)
+	}
+
+	buf := new(bytes.Buffer)
+	RenderText(buf, store.River(2, ""))
+	txt := buf.String()
+	for _, s := range []string{"Hello from twtxt\n", "Atom entry\n    Atom News, ", "    https://example.net/atom-entry.html\n"} {
+		if !strings.Contains(txt, s) {
+			t.Errorf("expected %q in text river, got\n%s", s, txt)
+		}
+	}
+	if strings.Contains(txt, "Second RSS post") {
+		t.Errorf("expected two entries in text river, got\n%s", txt)
+	}
+
+	buf.Reset()
+	if err := RenderHTML(buf, "My River", store.Updated, store.River(0, "")); err != nil {
+		t.Fatal(err)
+	}
+	src := buf.String()
+	for _, s := range []string{"<title>My River</title>", `<a href="https://example.org/2022/08/02/second.html">Second RSS post</a>`, "<p>The second post.</p>"} {
+		if !strings.Contains(src, s) {
+			t.Errorf("expected %q in HTML river, got\n%s", s, src)
+		}
+	}
+}
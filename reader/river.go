@@ -0,0 +1,117 @@
+// pttk is software for working with plain text content.
+// Copyright (C) 2022 R. S. Doiel
+//
+// This program is free software: you can redistribute it and/or modify it under the terms of the GNU Affero General Public License as published by the Free Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License along with this program. If not, see <https://www.gnu.org/licenses/>.
+package reader
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+)
+
+const (
+	// riverDateFmt is the layout of the dates shown in a river
+	riverDateFmt = "2006-01-02 15:04"
+
+	// riverTmpl renders a river of news as a static HTML page
+	riverTmpl = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+</head>
+<body>
+<header><h1>{{.Title}}</h1></header>
+<main>
+{{- range .Entries}}
+<article>
+{{- if .Title}}
+<h2>{{if .Link}}<a href="{{.Link}}">{{.Title}}</a>{{else}}{{.Title}}{{end}}</h2>
+{{- end}}
+<p class="feed">{{.Feed}}, <time datetime="{{.Published}}">{{date .}}</time></p>
+{{- if .Summary}}
+<p>{{.Summary}}</p>
+{{- end}}
+</article>
+{{- end}}
+</main>
+<footer>Updated {{.Updated}}</footer>
+</body>
+</html>
+`
+)
+
+// River returns the newest n entries of the store, all when n is
+// less than one. When tag isn't empty only the entries of
+// subscriptions with that tag are returned.
+func (store *Store) River(n int, tag string) []*Entry {
+	entries := []*Entry{}
+	for _, entry := range store.Entries {
+		if n > 0 && len(entries) >= n {
+			break
+		}
+		if tag != "" {
+			tagged := false
+			for _, t := range entry.Tags {
+				if strings.EqualFold(t, tag) {
+					tagged = true
+					break
+				}
+			}
+			if !tagged {
+				continue
+			}
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// entryDate formats the date of an entry for a river
+func entryDate(entry *Entry) string {
+	if dt := entry.Date(); !dt.IsZero() {
+		return dt.Local().Format(riverDateFmt)
+	}
+	return ""
+}
+
+// RenderText writes a "mailbox" style river of news for the
+// terminal, newest first. Each entry shows its title, the feed
+// and date, its link and summary.
+func RenderText(out io.Writer, entries []*Entry) {
+	for _, entry := range entries {
+		title := entry.Title
+		if title == "" {
+			title = entry.Summary
+			entry = &Entry{Feed: entry.Feed, Link: entry.Link, Published: entry.Published, Seen: entry.Seen}
+		}
+		fmt.Fprintf(out, "%s\n", title)
+		fmt.Fprintf(out, "    %s, %s\n", entry.Feed, entryDate(entry))
+		if entry.Link != "" {
+			fmt.Fprintf(out, "    %s\n", entry.Link)
+		}
+		if entry.Summary != "" {
+			fmt.Fprintf(out, "    %s\n", entry.Summary)
+		}
+		fmt.Fprintln(out)
+	}
+}
+
+// RenderHTML writes the river of news as a static HTML page
+func RenderHTML(out io.Writer, title string, updated string, entries []*Entry) error {
+	tmpl, err := template.New("river").Funcs(template.FuncMap{"date": entryDate}).Parse(riverTmpl)
+	if err != nil {
+		return err
+	}
+	return tmpl.Execute(out, map[string]interface{}{
+		"Title":   title,
+		"Updated": updated,
+		"Entries": entries,
+	})
+}
@@ -0,0 +1,58 @@
+// pttk is software for working with plain text content.
+// Copyright (C) 2022 R. S. Doiel
+//
+// This program is free software: you can redistribute it and/or modify it under the terms of the GNU Affero General Public License as published by the Free Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License along with this program. If not, see <https://www.gnu.org/licenses/>.
+package reader
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	// My packages
+	"github.com/rsdoiel/pttk/opml"
+)
+
+// Subscription is a feed to read
+type Subscription struct {
+	// Title is the name given to the feed by the subscription list,
+	// the feed's own title is used when it is empty.
+	Title string `json:"title,omitempty"`
+	// URL of the feed, a http(s)://, gopher:// or file:// URL or
+	// the path to a local file
+	URL string `json:"url"`
+	// Tags are the newsboat tags or the OPML outlines holding the feed
+	Tags []string `json:"tags,omitempty"`
+}
+
+// ReadSubscriptions reads a subscription list, an OPML document or
+// a newsboat style "urls" file.
+func ReadSubscriptions(fName string) ([]*Subscription, error) {
+	src, err := os.ReadFile(fName)
+	if err != nil {
+		return nil, fmt.Errorf("Reading %q, %s", fName, err)
+	}
+	var feeds []*opml.Feed
+	if bytes.HasPrefix(bytes.TrimSpace(src), []byte("<")) {
+		doc, err := opml.Parse(src)
+		if err != nil {
+			return nil, fmt.Errorf("Parsing %q, %s", fName, err)
+		}
+		feeds = doc.Feeds()
+	} else {
+		feeds = opml.ParseURLs(src)
+	}
+	subscriptions := []*Subscription{}
+	for _, feed := range feeds {
+		subscriptions = append(subscriptions, &Subscription{
+			Title: feed.Title,
+			URL:   feed.URL,
+			Tags:  feed.Tags,
+		})
+	}
+	return subscriptions, nil
+}
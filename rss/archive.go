@@ -0,0 +1,172 @@
+// pttk is software for working with plain text content.
+// Copyright (C) 2022 R. S. Doiel
+//
+// This program is free software: you can redistribute it and/or modify it under the terms of the GNU Affero General Public License as published by the Free Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License along with this program. If not, see <https://www.gnu.org/licenses/>.
+package rss
+
+import (
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	// My packages
+	"github.com/rsdoiel/pttk/feed"
+)
+
+const (
+	// HistoryNameSpace is the XML name space of RFC 5005's feed
+	// history elements, e.g. fh:archive
+	HistoryNameSpace = "http://purl.org/syndication/history/1.0"
+)
+
+// Archive is the empty fh:archive element of an archive document
+type Archive struct{}
+
+// SetAtomLink sets the atom:link of a feed with the relation rel,
+// replacing any link with the same relation.
+func (r *RSS2) SetAtomLink(rel string, href string) {
+	links := []*AtomLink{}
+	for _, link := range r.AtomLinks {
+		if link.Rel != rel {
+			links = append(links, link)
+		}
+	}
+	r.AtomLinks = append(links, &AtomLink{HRef: href, Rel: rel, Type: "application/rss+xml"})
+}
+
+// AtomLinkHRef returns the href of the atom:link with the relation
+// rel, e.g. "self", or an empty string if there isn't one.
+func (r *RSS2) AtomLinkHRef(rel string) string {
+	for _, link := range r.AtomLinks {
+		if link.Rel == rel {
+			return link.HRef
+		}
+	}
+	return ""
+}
+
+// SortItems orders the feed's items newest first by pubDate, items
+// without a date we can parse are kept in order after those with one.
+func (r *RSS2) SortItems() {
+	sort.SliceStable(r.ItemList, func(i, j int) bool {
+		a, errA := feed.ParseDate(r.ItemList[i].PubDate)
+		b, errB := feed.ParseDate(r.ItemList[j].PubDate)
+		switch {
+		case errA != nil:
+			return false
+		case errB != nil:
+			return true
+		}
+		return a.After(b)
+	})
+}
+
+// Limit keeps the first n items of the feed, n less than one
+// keeps them all.
+func (r *RSS2) Limit(n int) {
+	if n > 0 && len(r.ItemList) > n {
+		r.ItemList = r.ItemList[:n]
+	}
+}
+
+// ArchiveName returns the name (or URL) of an archive document of an
+// archived feed, the oldest archive of "rss.xml" is "rss-archive-1.xml".
+func ArchiveName(name string, n int) string {
+	ext := path.Ext(name)
+	return strings.TrimSuffix(name, ext) + "-archive-" + strconv.Itoa(n) + ext
+}
+
+// copyChannel returns a feed with r's channel metadata and the items.
+func (r *RSS2) copyChannel(items []Item) *RSS2 {
+	page := new(RSS2)
+	*page = *r
+	// Only the WebSub hubs are shared by the pages
+	page.AtomLinks = nil
+	for _, link := range r.AtomLinks {
+		if link.Rel == "hub" {
+			page.AtomLinks = append(page.AtomLinks, link)
+		}
+	}
+	page.Archive = nil
+	page.ItemList = items
+	return page
+}
+
+// Paginate splits a feed, sorted newest first, into pages of size
+// items as a paged feed (RFC 5005 section 3). The pages link to each
+// other with rel="first", "last", "previous" and "next", they are
+// named from feedURL with feed.PageName. If size is less than one or
+// the items fit on one page the feed is returned as is.
+func (r *RSS2) Paginate(size int, feedURL string) []*RSS2 {
+	if size < 1 || len(r.ItemList) <= size {
+		return []*RSS2{r}
+	}
+	pages := []*RSS2{}
+	for i := 0; i < len(r.ItemList); i += size {
+		end := i + size
+		if end > len(r.ItemList) {
+			end = len(r.ItemList)
+		}
+		pages = append(pages, r.copyChannel(r.ItemList[i:end]))
+	}
+	last := len(pages)
+	for i, page := range pages {
+		n := i + 1
+		page.SetAtomLink("self", feed.PageName(feedURL, n))
+		page.SetAtomLink("first", feed.PageName(feedURL, 1))
+		page.SetAtomLink("last", feed.PageName(feedURL, last))
+		if n > 1 {
+			page.SetAtomLink("previous", feed.PageName(feedURL, n-1))
+		}
+		if n < last {
+			page.SetAtomLink("next", feed.PageName(feedURL, n+1))
+		}
+	}
+	return pages
+}
+
+// Archived splits a feed, sorted newest first, into an archived feed
+// (RFC 5005 section 4). The archive documents hold the items after
+// the newest size in groups of size starting from the oldest, only
+// full groups are archived so an archive document doesn't change as
+// posts are added. The subscription feed returned holds the items
+// that aren't archived, the newest size up to 2*size-1 items, so no
+// item appears in both. Archives are named from feedURL with
+// ArchiveName, the oldest first. Each links to the subscription feed
+// with rel="current" and to its neighbours with rel="prev-archive"
+// and rel="next-archive", the subscription feed links to the newest.
+func (r *RSS2) Archived(size int, feedURL string) (*RSS2, []*RSS2) {
+	current := r.copyChannel(r.ItemList)
+	current.AtomLinks = r.AtomLinks
+	if size < 1 || len(r.ItemList) < 2*size {
+		return current, nil
+	}
+	// Oldest first, only full groups of the items after the newest
+	// size are archived
+	cnt := (len(r.ItemList) - size) / size
+	current.ItemList = r.ItemList[:len(r.ItemList)-cnt*size]
+	current.SetAtomLink("self", feedURL)
+	archives := []*RSS2{}
+	for n := 1; n <= cnt; n++ {
+		end := len(r.ItemList) - (n-1)*size
+		archive := r.copyChannel(r.ItemList[end-size : end])
+		archive.HistoryNameSpace = HistoryNameSpace
+		archive.Archive = new(Archive)
+		archive.SetAtomLink("self", ArchiveName(feedURL, n))
+		archive.SetAtomLink("current", feedURL)
+		if n > 1 {
+			archive.SetAtomLink("prev-archive", ArchiveName(feedURL, n-1))
+		}
+		if n < cnt {
+			archive.SetAtomLink("next-archive", ArchiveName(feedURL, n+1))
+		}
+		archives = append(archives, archive)
+	}
+	current.SetAtomLink("prev-archive", ArchiveName(feedURL, cnt))
+	return current, archives
+}
@@ -0,0 +1,295 @@
+// pttk is software for working with plain text content.
+// Copyright (C) 2022 R. S. Doiel
+//
+// This program is free software: you can redistribute it and/or modify it under the terms of the GNU Affero General Public License as published by the Free Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License along with this program. If not, see <https://www.gnu.org/licenses/>.
+package rss
+
+import (
+	"encoding/xml"
+	"html"
+	"regexp"
+	"strings"
+	"time"
+
+	// My packages
+	"github.com/rsdoiel/pttk/feed"
+)
+
+const (
+	// AtomNameSpace is the XML name space of an Atom 1.0 feed
+	AtomNameSpace = "http://www.w3.org/2005/Atom"
+)
+
+var (
+	// xhtmlDivExp matches the div holding the markup of xhtml text
+	xhtmlDivExp = regexp.MustCompile(`(?s)^<div[^>]*>(.*)</div>$`)
+)
+
+// Atom is an Atom 1.0 feed, see RFC 4287.
+type Atom struct {
+	XMLName   xml.Name        `xml:"http://www.w3.org/2005/Atom feed" json:"-"`
+	Lang      string          `xml:"xml:lang,attr,omitempty" json:"lang,omitempty"`
+	ID        string          `xml:"id" json:"id"`
+	Title     string          `xml:"title" json:"title"`
+	Subtitle  string          `xml:"subtitle,omitempty" json:"subtitle,omitempty"`
+	Updated   string          `xml:"updated" json:"updated"`
+	Links     []*AtomLinkRel  `xml:"link" json:"link,omitempty"`
+	Authors   []*AtomPerson   `xml:"author" json:"author,omitempty"`
+	Rights    string          `xml:"rights,omitempty" json:"rights,omitempty"`
+	Generator string          `xml:"generator,omitempty" json:"generator,omitempty"`
+	Icon      string          `xml:"icon,omitempty" json:"icon,omitempty"`
+	Logo      string          `xml:"logo,omitempty" json:"logo,omitempty"`
+	Category  []*AtomCategory `xml:"category,omitempty" json:"category,omitempty"`
+	Archive   *Archive        `xml:"http://purl.org/syndication/history/1.0 archive,omitempty" json:"archive,omitempty"`
+	Entries   []*AtomEntry    `xml:"entry" json:"entry,omitempty"`
+}
+
+// AtomLinkRel is an Atom link element
+type AtomLinkRel struct {
+	HRef   string `xml:"href,attr" json:"href"`
+	Rel    string `xml:"rel,attr,omitempty" json:"rel,omitempty"`
+	Type   string `xml:"type,attr,omitempty" json:"type,omitempty"`
+	Length int64  `xml:"length,attr,omitempty" json:"length,omitempty"`
+}
+
+// AtomPerson describes an author or contributor
+type AtomPerson struct {
+	Name  string `xml:"name" json:"name"`
+	URI   string `xml:"uri,omitempty" json:"uri,omitempty"`
+	Email string `xml:"email,omitempty" json:"email,omitempty"`
+}
+
+// AtomCategory is an Atom category element
+type AtomCategory struct {
+	Term   string `xml:"term,attr" json:"term"`
+	Scheme string `xml:"scheme,attr,omitempty" json:"scheme,omitempty"`
+	Label  string `xml:"label,attr,omitempty" json:"label,omitempty"`
+}
+
+// AtomText is an Atom text construct, e.g. a summary or content.
+// The Value of "xhtml" text is the markup inside its div.
+type AtomText struct {
+	Type  string `xml:"type,attr,omitempty" json:"type,omitempty"`
+	Value string `xml:",chardata" json:"value"`
+}
+
+// UnmarshalXML reads a text construct keeping the markup of "xhtml"
+// text, it would be lost read as character data.
+func (text *AtomText) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	elem := struct {
+		Type  string `xml:"type,attr"`
+		Value string `xml:",chardata"`
+		Inner string `xml:",innerxml"`
+	}{}
+	if err := d.DecodeElement(&elem, &start); err != nil {
+		return err
+	}
+	text.Type, text.Value = elem.Type, elem.Value
+	if elem.Type == "xhtml" {
+		text.Value = strings.TrimSpace(elem.Inner)
+		if m := xhtmlDivExp.FindStringSubmatch(text.Value); m != nil {
+			text.Value = strings.TrimSpace(m[1])
+		}
+	}
+	return nil
+}
+
+// MarshalXML writes a text construct, "xhtml" text is wrapped in the
+// div Atom requires.
+func (text AtomText) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if text.Type != "xhtml" {
+		type plainText AtomText
+		return e.EncodeElement(plainText(text), start)
+	}
+	return e.EncodeElement(struct {
+		Type  string `xml:"type,attr"`
+		Inner string `xml:",innerxml"`
+	}{text.Type, `<div xmlns="http://www.w3.org/1999/xhtml">` + text.Value + `</div>`}, start)
+}
+
+// AtomEntry is an entry in an Atom feed
+type AtomEntry struct {
+	ID        string          `xml:"id" json:"id"`
+	Title     string          `xml:"title" json:"title"`
+	Updated   string          `xml:"updated" json:"updated"`
+	Published string          `xml:"published,omitempty" json:"published,omitempty"`
+	Links     []*AtomLinkRel  `xml:"link" json:"link,omitempty"`
+	Authors   []*AtomPerson   `xml:"author" json:"author,omitempty"`
+	Category  []*AtomCategory `xml:"category,omitempty" json:"category,omitempty"`
+	Summary   *AtomText       `xml:"summary,omitempty" json:"summary,omitempty"`
+	Content   *AtomText       `xml:"content,omitempty" json:"content,omitempty"`
+}
+
+// atomDate converts an RSS 2.0 date (RFC 1123 or RFC 822) to the
+// RFC 3339 dates used by Atom.
+func atomDate(s string) (string, error) {
+	dt, err := feed.ParseDate(s)
+	if err != nil {
+		return "", err
+	}
+	return dt.Format(time.RFC3339), nil
+}
+
+// TextToHTML renders plain text (or Markdown) as escaped HTML
+// paragraphs so it can be used where HTML is expected.
+func TextToHTML(src string) string {
+	paras := []string{}
+	for _, para := range strings.Split(strings.ReplaceAll(src, "\r", ""), "\n\n") {
+		if para = strings.TrimSpace(para); para != "" {
+			paras = append(paras, "<p>"+html.EscapeString(para)+"</p>")
+		}
+	}
+	return strings.Join(paras, "\n")
+}
+
+// absoluteURL makes a link absolute using the channel's link.
+func absoluteURL(channelLink string, link string) string {
+	if link == "" || strings.Contains(link, "://") {
+		return link
+	}
+	return strings.TrimSuffix(channelLink, "/") + "/" + strings.TrimPrefix(link, "/")
+}
+
+// ToAtom converts an RSS 2.0 feed, e.g. one built by BlogMetaToRSS or
+// WalkRSS, into an Atom 1.0 feed. selfLink is the URL the Atom feed
+// will be published at, it is used as the feed's id when set.
+// author is used for the feed when the items don't name their
+// authors, the feed's title is used if author is empty.
+func (r *RSS2) ToAtom(selfLink string, author string) (*Atom, error) {
+	var err error
+	feed := new(Atom)
+	feed.Lang = r.Language
+	feed.Title = r.Title
+	feed.Subtitle = r.Description
+	feed.Rights = r.Copyright
+	feed.Generator = r.Generator
+	feed.ID = selfLink
+	if feed.ID == "" {
+		feed.ID = r.Link
+	}
+	feed.Links = append(feed.Links, &AtomLinkRel{HRef: r.Link, Rel: "alternate", Type: "text/html"})
+	if selfLink != "" {
+		feed.Links = append(feed.Links, &AtomLinkRel{HRef: selfLink, Rel: "self", Type: "application/atom+xml"})
+	}
+	// Paged and archived feeds (RFC 5005) keep their other links
+	for _, link := range r.AtomLinks {
+		switch link.Rel {
+		case "self":
+		case "hub":
+			feed.Links = append(feed.Links, &AtomLinkRel{HRef: link.HRef, Rel: link.Rel})
+		default:
+			feed.Links = append(feed.Links, &AtomLinkRel{HRef: link.HRef, Rel: link.Rel, Type: "application/atom+xml"})
+		}
+	}
+	if r.Archive != nil {
+		feed.Archive = new(Archive)
+	}
+	for _, category := range r.Category {
+		feed.Category = append(feed.Category, &AtomCategory{Term: category.Value, Scheme: category.Domain})
+	}
+	if r.Image != nil {
+		feed.Logo = r.Image.URL
+	}
+	updated := r.LastBuildDate
+	if updated == "" {
+		updated = r.PubDate
+	}
+	if updated == "" {
+		feed.Updated = time.Now().Format(time.RFC3339)
+	} else if feed.Updated, err = atomDate(updated); err != nil {
+		return nil, err
+	}
+	needsAuthor := false
+	for _, item := range r.ItemList {
+		entry := new(AtomEntry)
+		link := absoluteURL(r.Link, item.Link)
+		entry.ID = absoluteURL(r.Link, item.GUID)
+		if entry.ID == "" {
+			entry.ID = link
+		}
+		entry.Title = item.Title
+		if entry.Title == "" {
+			// Atom requires a title, RSS 2.0 items may omit it.
+			entry.Title = link
+		}
+		if link != "" {
+			entry.Links = append(entry.Links, &AtomLinkRel{HRef: link, Rel: "alternate", Type: "text/html"})
+		}
+		if item.Enclosure != nil {
+			entry.Links = append(entry.Links, &AtomLinkRel{HRef: absoluteURL(r.Link, item.Enclosure.URL), Rel: "enclosure", Type: item.Enclosure.Type, Length: item.Enclosure.Length})
+		}
+		if item.PubDate != "" {
+			if entry.Published, err = atomDate(item.PubDate); err != nil {
+				return nil, err
+			}
+			entry.Updated = entry.Published
+		} else {
+			entry.Updated = feed.Updated
+		}
+		if item.Author != "" {
+			entry.Authors = append(entry.Authors, &AtomPerson{Name: item.Author})
+		} else {
+			needsAuthor = true
+		}
+		for _, category := range item.Category {
+			entry.Category = append(entry.Category, &AtomCategory{Term: category.Value, Scheme: category.Domain})
+		}
+		if content := item.ContentString(); content != "" {
+			entry.Content = &AtomText{Type: "html", Value: content}
+			// Items with full content have an HTML description.
+			if item.Description != "" {
+				entry.Summary = &AtomText{Type: "html", Value: item.Description}
+			}
+		} else if item.Description != "" {
+			entry.Content = &AtomText{Type: "html", Value: TextToHTML(item.Description)}
+		}
+		feed.Entries = append(feed.Entries, entry)
+	}
+	// An Atom feed needs an author unless every entry has one.
+	if needsAuthor || len(feed.Entries) == 0 {
+		if author == "" {
+			author = r.Title
+		}
+		feed.Authors = append(feed.Authors, &AtomPerson{Name: author, URI: r.Link})
+	}
+	return feed, nil
+}
+
+// ParseAtom return an Atom document as an Atom structure.
+func ParseAtom(buf []byte) (*Atom, error) {
+	data := new(Atom)
+	if err := xml.Unmarshal(buf, &data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// UnmarshalXML reads an Atom feed, xml:lang is matched by the XML
+// name space.
+func (atom *Atom) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type Plain Atom
+	obj := struct {
+		Lang string `xml:"http://www.w3.org/XML/1998/namespace lang,attr"`
+		Plain
+	}{}
+	if err := d.DecodeElement(&obj, &start); err != nil {
+		return err
+	}
+	*atom = Atom(obj.Plain)
+	atom.Lang = obj.Lang
+	return nil
+}
+
+// Marshal renders the feed as an indented Atom document with self
+// closed link elements.
+func (atom *Atom) Marshal() ([]byte, error) {
+	src, err := xml.MarshalIndent(atom, "", "    ")
+	if err != nil {
+		return nil, err
+	}
+	return []byte(strings.ReplaceAll(xml.Header+string(src), "></link>", "/>")), nil
+}
@@ -9,18 +9,21 @@
 package rss
 
 import (
+	"bytes"
 	"encoding/json"
-	"encoding/xml"
 	"flag"
 	"fmt"
+	"io"
 	"os"
 	"path"
+	"path/filepath"
 	"strings"
 	"time"
 
 	// My packages
 	"github.com/rsdoiel/pttk"
 	"github.com/rsdoiel/pttk/blogit"
+	"github.com/rsdoiel/pttk/feed"
 	"github.com/rsdoiel/pttk/help"
 )
 
@@ -41,9 +44,47 @@ var (
 	channelBuildDate   string
 	channelCopyright   string
 	channelCategory    string
+	channelImage       string
+	skipHours          string
+	skipDays           string
 	bylineExp          string
 	titleExp           string
 	dateExp            string
+	feedFormat         string
+	feedAuthor         string
+	fullContent        bool
+	itunesAuthor       string
+	itunesImage        string
+	itunesCategory     string
+	itunesExplicit     string
+	itunesOwner        string
+	itunesType         string
+	feedsDir           string
+	feedsURL           string
+	limit              int
+	pagedFeed          bool
+	archivedFeed       bool
+	outName            string
+	queryExpr          string
+	queryFormat        string
+	validateName       string
+	htmlPages          bool
+	sitemapName        string
+	mergeFeeds         bool
+	riverName          string
+	hubList            string
+	pingHubURL         string
+)
+
+// emptyElements closes the elements that only hold attributes
+var emptyElements = strings.NewReplacer(
+	"></atom:link>", "/>",
+	"></enclosure>", "/>",
+	"></cloud>", "/>",
+	"></itunes:image>", "/>",
+	"></podcast:transcript>", "/>",
+	"></podcast:chapters>", "/>",
+	"></fh:archive>", "/>",
 )
 
 func usage(appName string, verb string, exitCode int) {
@@ -51,7 +92,7 @@ func usage(appName string, verb string, exitCode int) {
 	if exitCode > 0 {
 		out = os.Stderr
 	}
-	fmt.Fprintf(out, help.Render(appName, verb, helpText))
+	fmt.Fprint(out, help.Render(appName, verb, helpText))
 	os.Exit(exitCode)
 }
 
@@ -74,10 +115,37 @@ func RunRSS(appName string, verb string, options []string) ([]byte, error) {
 	flagSet.StringVar(&channelPubDate, "channel-pubdate", "", "Pub Date for channel (e.g. 2006-01-02 15:04:05 -0700)")
 	flagSet.StringVar(&channelBuildDate, "channel-builddate", "", "Build Date for channel (e.g. 2006-01-02 15:04:05 -0700)")
 	flagSet.StringVar(&channelCopyright, "channel-copyright", "", "Copyright for channel")
-	flagSet.StringVar(&channelCategory, "channel-category", "", "category for channel")
+	flagSet.StringVar(&channelCategory, "channel-category", "", "comma separated categories for channel")
+	flagSet.StringVar(&channelImage, "channel-image", "", "URL of the channel's image (GIF, JPEG or PNG)")
+	flagSet.StringVar(&skipHours, "skip-hours", "", "comma separated hours (0 to 23, GMT) aggregators may skip")
+	flagSet.StringVar(&skipDays, "skip-days", "", "comma separated days aggregators may skip, e.g. Saturday, Sunday")
 	flagSet.StringVar(&dateExp, "date-format", DateExp, "set date regexp")
 	flagSet.StringVar(&titleExp, "title", TitleExp, "set title regexp")
 	flagSet.StringVar(&bylineExp, "byline", BylineExp, "set byline regexp")
+	flagSet.StringVar(&feedFormat, "format", "rss", "feed format to render, rss or atom")
+	flagSet.StringVar(&feedAuthor, "author", "", "feed author used by Atom when items have none")
+	flagSet.BoolVar(&fullContent, "full-content", false, "include each post's rendered HTML in content:encoded")
+	flagSet.StringVar(&itunesAuthor, "itunes-author", "", "podcast author")
+	flagSet.StringVar(&itunesImage, "itunes-image", "", "URL of the podcast's artwork")
+	flagSet.StringVar(&itunesCategory, "itunes-category", "", "comma separated podcast categories, e.g. Technology, Arts/Books")
+	flagSet.StringVar(&itunesExplicit, "itunes-explicit", "", "podcast has explicit content, true or false")
+	flagSet.StringVar(&itunesOwner, "itunes-owner", "", "podcast owner, e.g. \"Jane Doe <jane@example.org>\"")
+	flagSet.StringVar(&itunesType, "itunes-type", "", "podcast type, episodic or serial")
+	flagSet.StringVar(&feedsDir, "feeds", "", "write the blog's feed and a feed per keyword, category, series and author to this directory")
+	flagSet.StringVar(&feedsURL, "feeds-url", "", "URL of the -feeds directory, defaults to its path under the blog's URL")
+	flagSet.IntVar(&limit, "limit", 0, "limit the feed to the newest N items, the page size for -paged and -archive")
+	flagSet.BoolVar(&pagedFeed, "paged", false, "write a paged feed (RFC 5005), pages of -limit items named from -o")
+	flagSet.BoolVar(&archivedFeed, "archive", false, "write an archived feed (RFC 5005), archives of -limit items named from -o")
+	flagSet.StringVar(&outName, "o", "", "write the feed to this file")
+	flagSet.StringVar(&queryExpr, "query", "", "run a query over the RSS, Atom or JSON Feed files given")
+	flagSet.StringVar(&queryFormat, "query-format", "json", "format of the query results, json, csv or lines")
+	flagSet.BoolVar(&htmlPages, "html", false, "build the feed from the rendered HTML pages in PATH_TO_SITE")
+	flagSet.StringVar(&sitemapName, "sitemap", "", "build the feed from the HTML pages listed in this sitemap.xml")
+	flagSet.BoolVar(&mergeFeeds, "merge", false, "merge the RSS, Atom or JSON Feed files or URLs given into one feed")
+	flagSet.StringVar(&riverName, "river", "", "with -merge write a river of news page, Markdown if the name ends in .md otherwise HTML")
+	flagSet.StringVar(&validateName, "validate", "", "check an RSS 2.0 feed, reporting problems by line number")
+	flagSet.StringVar(&hubList, "hub", "", "comma separated WebSub hub URLs the feed declares")
+	flagSet.StringVar(&pingHubURL, "ping", "", "send a WebSub publish notification to this hub once the feed is written")
 
 	flagSet.Parse(options)
 	args := flagSet.Args()
@@ -89,6 +157,38 @@ func RunRSS(appName string, verb string, options []string) ([]byte, error) {
 	if showHelp {
 		usage(appName, verb, 0)
 	}
+	if validateName != "" {
+		return runValidate(validateName)
+	}
+	if queryExpr != "" {
+		return runQuery(queryExpr, queryFormat, args)
+	}
+	if feedFormat != "rss" && feedFormat != "atom" {
+		return nil, fmt.Errorf("-format %q not supported, expected rss or atom", feedFormat)
+	}
+	if pagedFeed || archivedFeed {
+		switch {
+		case pagedFeed && archivedFeed:
+			return nil, fmt.Errorf("-paged and -archive can't be combined")
+		case limit < 1:
+			return nil, fmt.Errorf("-paged and -archive require -limit")
+		case outName == "":
+			return nil, fmt.Errorf("-paged and -archive require -o to name the feed's files")
+		case atomLink == "":
+			return nil, fmt.Errorf("-paged and -archive require -atom-link to link the feed's files")
+		case feedsDir != "":
+			return nil, fmt.Errorf("-paged and -archive can't be combined with -feeds")
+		}
+	}
+	if pingHubURL != "" && feedsDir == "" {
+		// The hub fetches the feed when pinged so it must be written first
+		switch {
+		case outName == "":
+			return nil, fmt.Errorf("-ping requires -o, the feed is written before the hub is notified")
+		case atomLink == "":
+			return nil, fmt.Errorf("-ping requires -atom-link, the feed URL sent to the hub")
+		}
+	}
 
 	if len(channelTitle) == 0 {
 		channelTitle = `A website`
@@ -100,33 +200,70 @@ func RunRSS(appName string, verb string, options []string) ([]byte, error) {
 		channelLink = `http://localhost:8000`
 	}
 
-	// Setup the Channel metadata for feed.
-	feed := new(RSS2)
-	feed.Version = "2.0"
-	feed.Title = channelTitle
-	feed.Description = channelDescription
-	feed.Link = channelLink
-	feed.AtomNameSpace = "http://www.w3.org/2005/Atom"
+	// Setup the Channel metadata for the feed.
+	rssDoc := new(RSS2)
+	rssDoc.Version = "2.0"
+	rssDoc.Title = channelTitle
+	rssDoc.Description = channelDescription
+	rssDoc.Link = channelLink
+	rssDoc.AtomNameSpace = "http://www.w3.org/2005/Atom"
 	if len(channelLanguage) > 0 {
-		feed.Language = channelLanguage
+		rssDoc.Language = channelLanguage
 	}
 	if len(channelCopyright) > 0 {
-		feed.Copyright = channelCopyright
+		rssDoc.Copyright = channelCopyright
 	}
 	if len(channelCategory) > 0 {
-		feed.Category = channelCategory
+		rssDoc.Category = Categories("", strings.Split(channelCategory, ",")...)
+	}
+	if len(skipHours) > 0 {
+		if rssDoc.SkipHours, err = ParseSkipHours(skipHours); err != nil {
+			return nil, fmt.Errorf("-skip-hours %s", err)
+		}
+	}
+	if len(skipDays) > 0 {
+		if rssDoc.SkipDays, err = ParseSkipDays(skipDays); err != nil {
+			return nil, fmt.Errorf("-skip-days %s", err)
+		}
+	}
+	for _, hub := range strings.Split(hubList, ",") {
+		if hub = strings.TrimSpace(hub); hub != "" {
+			rssDoc.AddHub(hub)
+		}
+	}
+	if hubList == "" && pingHubURL != "" {
+		// The hub we notify is the one subscribers are sent to
+		rssDoc.AddHub(pingHubURL)
 	}
 	if len(channelGenerator) == 0 {
-		feed.Generator = fmt.Sprintf("%s %s %s", appName, verb, pttk.Version)
+		rssDoc.Generator = fmt.Sprintf("%s %s %s", appName, verb, pttk.Version)
 	} else {
-		feed.Generator = channelGenerator
+		rssDoc.Generator = channelGenerator
+	}
+	if len(itunesAuthor) > 0 {
+		rssDoc.ItunesAuthor = itunesAuthor
+	}
+	if len(itunesImage) > 0 {
+		rssDoc.ItunesImage = &ItunesImage{HRef: itunesImage}
+	}
+	if len(itunesCategory) > 0 {
+		rssDoc.ItunesCategory = ParseItunesCategories(itunesCategory)
+	}
+	if len(itunesExplicit) > 0 {
+		rssDoc.ItunesExplicit = itunesExplicit
+	}
+	if len(itunesOwner) > 0 {
+		rssDoc.ItunesOwner = ParseOwner(itunesOwner)
+	}
+	if len(itunesType) > 0 {
+		rssDoc.ItunesType = itunesType
 	}
 	now := time.Now()
 	if len(channelPubDate) == 0 {
 		// RSS spec shows RFC 1123 dates
 		// Validators indicate the RFC-822, note UTC isn't list on RFC-822
-		//feed.PubDate = now.Format(time.RFC822Z)
-		feed.PubDate = now.Format(time.RFC1123Z)
+		//rssDoc.PubDate = now.Format(time.RFC822Z)
+		rssDoc.PubDate = now.Format(time.RFC1123Z)
 	} else {
 		dt, err := NormalizeDate(channelPubDate)
 		if err != nil {
@@ -134,14 +271,14 @@ func RunRSS(appName string, verb string, options []string) ([]byte, error) {
 		}
 		// RSS spec shows RFC 1123 dates
 		// Validators indicate the RFC-822 and "UTC" isn't that.
-		//feed.PubDate = dt.Format(time.RFC822Z)
-		feed.PubDate = dt.Format(time.RFC1123Z)
+		//rssDoc.PubDate = dt.Format(time.RFC822Z)
+		rssDoc.PubDate = dt.Format(time.RFC1123Z)
 	}
 	if len(channelBuildDate) == 0 {
 		// RSS spec shows RFC 1123 dates
 		// Validators indicate the RFC-822
-		//feed.LastBuildDate = now.Format(time.RFC822Z)
-		feed.LastBuildDate = now.Format(time.RFC1123Z)
+		//rssDoc.LastBuildDate = now.Format(time.RFC822Z)
+		rssDoc.LastBuildDate = now.Format(time.RFC1123Z)
 	} else {
 		dt, err := NormalizeDate(channelBuildDate)
 		if err != nil {
@@ -149,8 +286,12 @@ func RunRSS(appName string, verb string, options []string) ([]byte, error) {
 		}
 		// RSS spec shows RFC 1123 dates
 		// Validators indicate the RFC-822
-		//feed.LastBuildDate = dt.Format(time.RFC822Z)
-		feed.LastBuildDate = dt.Format(time.RFC1123Z)
+		//rssDoc.LastBuildDate = dt.Format(time.RFC822Z)
+		rssDoc.LastBuildDate = dt.Format(time.RFC1123Z)
+	}
+
+	if mergeFeeds {
+		return runMerge(rssDoc, args)
 	}
 
 	// Process command line parameters
@@ -158,12 +299,14 @@ func RunRSS(appName string, verb string, options []string) ([]byte, error) {
 	if len(args) > 0 {
 		htdocs = args[0]
 	}
+	opts := new(Options)
+	opts.FullContent = fullContent
+	opts.Htdocs = htdocs
 	blogJSON := path.Join(htdocs, "blog.json")
+	blog := new(blogit.BlogMeta)
 	if _, err := os.Stat(blogJSON); os.IsNotExist(err) {
-
-		err = WalkRSS(feed, htdocs, baseURL, excludeList, titleExp, bylineExp, dateExp)
+		blog = nil
 	} else {
-		blog := new(blogit.BlogMeta)
 		src, err := os.ReadFile(blogJSON)
 		if err != nil {
 			return nil, fmt.Errorf("Reading %q, %s\n", blogJSON, err)
@@ -174,24 +317,239 @@ func RunRSS(appName string, verb string, options []string) ([]byte, error) {
 		if blog.BaseURL == "" {
 			blog.BaseURL = baseURL
 		}
-		err = BlogMetaToRSS(blog, feed)
+	}
+	if feedsDir != "" {
+		if blog == nil {
+			return nil, fmt.Errorf("-feeds requires %q", blogJSON)
+		}
+		if feedsURL == "" {
+			// Feeds written inside htdocs are found under the blog's URL
+			feedsURL = blog.BaseURL
+			if rel, err := filepath.Rel(htdocs, feedsDir); err == nil && rel != "." && !strings.HasPrefix(rel, "..") {
+				feedsURL = strings.TrimSuffix(blog.BaseURL, "/") + "/" + filepath.ToSlash(rel)
+			}
+		}
+		siteName := "rss.xml"
+		if feedFormat == "atom" {
+			siteName = "atom.xml"
+		}
+		feeds, err := BlogMetaToFeeds(blog, rssDoc, siteName, feedsURL, opts)
+		if err != nil {
+			return nil, err
+		}
+		topics := []string{}
+		for name, f := range feeds {
+			f.Limit(limit)
+			if channelImage != "" {
+				f.SetImage(channelImage)
+			}
+			if err := writeFeed(path.Join(feedsDir, name), f); err != nil {
+				return nil, err
+			}
+			if self := f.AtomLinkHRef("self"); self != "" {
+				topics = append(topics, self)
+			}
+		}
+		return nil, ping(topics...)
+	}
+	switch {
+	case sitemapName != "":
+		var src []byte
+		if src, err = os.ReadFile(sitemapName); err != nil {
+			return nil, fmt.Errorf("Reading %q, %s", sitemapName, err)
+		}
+		err = SitemapToRSS(rssDoc, src, htdocs, baseURL, opts)
+	case htmlPages:
+		err = WalkHTML(rssDoc, htdocs, baseURL, excludeList, opts)
+	case blog == nil:
+		err = WalkRSS(rssDoc, htdocs, baseURL, excludeList, titleExp, bylineExp, dateExp, opts)
+	default:
+		err = BlogMetaToRSS(blog, rssDoc, opts)
 	}
 	if err != nil {
 		return nil, err
 	}
+	if channelImage != "" {
+		rssDoc.SetImage(channelImage)
+	}
+	if atomLink != "" {
+		rssDoc.SetAtomLink("self", atomLink)
+	}
+	switch {
+	case pagedFeed:
+		for i, page := range rssDoc.Paginate(limit, atomLink) {
+			if err := writeFeed(feed.PageName(outName, i+1), page); err != nil {
+				return nil, err
+			}
+		}
+		return nil, ping(atomLink)
+	case archivedFeed:
+		current, archives := rssDoc.Archived(limit, atomLink)
+		for i, archive := range archives {
+			if err := writeFeed(ArchiveName(outName, i+1), archive); err != nil {
+				return nil, err
+			}
+		}
+		if err := writeFeed(outName, current); err != nil {
+			return nil, err
+		}
+		return nil, ping(atomLink)
+	}
+	rssDoc.Limit(limit)
+	if outName != "" {
+		if err := writeFeed(outName, rssDoc); err != nil {
+			return nil, err
+		}
+		return nil, ping(atomLink)
+	}
+	return renderFeed(rssDoc)
+}
+
+// ping sends the -ping hub a WebSub publish notification for each
+// feed URL in topics, it does nothing without -ping.
+func ping(topics ...string) error {
+	if pingHubURL == "" {
+		return nil
+	}
+	if len(topics) == 0 {
+		return fmt.Errorf("-ping needs the feed URL, set -atom-link or -feeds-url")
+	}
+	return PingHub(pingHubURL, topics)
+}
+
+// runMerge merges the feeds named in args into planet, the feeds that
+// can't be read are reported and skipped. The merged feed is
+// rendered like any other, -river writes its river of news page.
+func runMerge(planet *RSS2, args []string) ([]byte, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("-merge expects the feeds to merge")
+	}
+	for _, uri := range args {
+		src, err := Fetch(uri)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "WARNING: %s, %s\n", uri, err)
+			continue
+		}
+		f, _, err := feed.Parse(src)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "WARNING: %s, %s\n", uri, err)
+			continue
+		}
+		planet.Merge(f, uri)
+	}
+	planet.SortItems()
+	planet.Limit(limit)
+	if channelImage != "" {
+		planet.SetImage(channelImage)
+	}
 	if atomLink != "" {
-		feed.AtomLink = new(AtomLink)
-		feed.AtomLink.HRef = atomLink
-		feed.AtomLink.Rel = "self"
-		feed.AtomLink.Type = "application/rss+xml"
+		planet.SetAtomLink("self", atomLink)
 	}
+	if riverName != "" {
+		format := "html"
+		if ext := strings.ToLower(path.Ext(riverName)); ext == ".md" || ext == ".markdown" {
+			format = "markdown"
+		}
+		buf := new(bytes.Buffer)
+		if err := planet.RenderRiver(buf, format, 280); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(riverName, buf.Bytes(), 0664); err != nil {
+			return nil, fmt.Errorf("Writing %q, %s", riverName, err)
+		}
+	}
+	if outName != "" {
+		if err := writeFeed(outName, planet); err != nil {
+			return nil, err
+		}
+		return nil, ping(atomLink)
+	}
+	return renderFeed(planet)
+}
 
-	// Marshal RSS2 and render output
-	src, err := xml.MarshalIndent(feed, "", "    ")
+// runValidate checks a feed, the problems found are returned as
+// the error when there are errors otherwise the warnings are
+// returned as output.
+func runValidate(fName string) ([]byte, error) {
+	src, err := os.ReadFile(fName)
+	if err != nil {
+		return nil, fmt.Errorf("Reading %q, %s", fName, err)
+	}
+	lines := []string{}
+	errCnt := 0
+	for _, problem := range Validate(src) {
+		if problem.Level == "error" {
+			errCnt++
+		}
+		lines = append(lines, fmt.Sprintf("%s:%d: %s: %s", fName, problem.Line, problem.Level, problem.Message))
+	}
+	if errCnt > 0 {
+		lines = append(lines, fmt.Sprintf("%s: %d error(s), %d warning(s)", fName, errCnt, len(lines)-errCnt))
+		return nil, fmt.Errorf("%s", strings.Join(lines, "\n"))
+	}
+	return []byte(strings.Join(lines, "\n")), nil
+}
+
+// runQuery runs a query over each feed named in args, or over the
+// feed read from standard input, returning the formatted results.
+func runQuery(expr string, format string, args []string) ([]byte, error) {
+	q, err := CompileQuery(expr)
 	if err != nil {
 		return nil, err
 	}
+	if len(args) == 0 {
+		args = []string{"-"}
+	}
+	results := []interface{}{}
+	for _, fName := range args {
+		var src []byte
+		if fName == "-" {
+			src, err = io.ReadAll(os.Stdin)
+		} else {
+			src, err = os.ReadFile(fName)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Reading %q, %s", fName, err)
+		}
+		data, err := FeedData(src)
+		if err != nil {
+			return nil, fmt.Errorf("Parsing %q, %s", fName, err)
+		}
+		vals, err := q.Run(data)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, vals...)
+	}
+	return FormatResults(results, format)
+}
 
-	txt := strings.ReplaceAll(fmt.Sprintf(`%s%s`, xml.Header, src), "></atom:link>", "/>")
-	return []byte(txt), nil
+// writeFeed renders a feed to fName creating its directory if needed.
+func writeFeed(fName string, feed *RSS2) error {
+	src, err := renderFeed(feed)
+	if err != nil {
+		return err
+	}
+	if dName := path.Dir(fName); dName != "." {
+		if err := os.MkdirAll(dName, 0775); err != nil {
+			return fmt.Errorf("Creating %q, %s", dName, err)
+		}
+	}
+	if err := os.WriteFile(fName, append(src, '\n'), 0664); err != nil {
+		return fmt.Errorf("Writing %q, %s", fName, err)
+	}
+	return nil
+}
+
+// renderFeed marshals a feed as RSS 2 or, when -format is atom, as
+// Atom 1.0 using the feed's atom:link as the Atom feed's self link.
+func renderFeed(feed *RSS2) ([]byte, error) {
+	if feedFormat == "atom" {
+		atom, err := feed.ToAtom(feed.AtomLinkHRef("self"), feedAuthor)
+		if err != nil {
+			return nil, err
+		}
+		return atom.Marshal()
+	}
+	return feed.Marshal()
 }
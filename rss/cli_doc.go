@@ -40,6 +40,128 @@ htdocs/myblog.
 This would build an RSS 2 file in htdocs/rss.xml from the
 articles in htdocs/myblog/YYYY/MM/DD.
 
+Items are sorted newest first. "-limit N" keeps the newest N
+items. With "-paged" or "-archive" the rest stay reachable as an
+RFC 5005 paged feed (rss.xml, rss-2.xml, ...) or archived feed
+(rss.xml linking to rss-archive-1.xml, ...) of N items per file
+named from "-o" and linked with URLs formed from "-atom-link". Only
+full archives are written, rss.xml holds the newest N up to 2N-1
+items not archived.
+
+    {app_name} {verb} -limit 20 -archive -o htdocs/rss.xml \
+        -atom-link="http://blog.example.org/rss.xml" htdocs
+
+With "-full-content" each item includes the HTML page rendered
+from the post (e.g. "post.html" for "post.md") in a CDATA wrapped
+content:encoded element and the description is HTML rather than
+Markdown.
+
+Posts can be podcast episodes. An item's enclosure comes from the
+"enclosure" front matter or an audio file bundled with the post
+(e.g. "episode.mp3" for "episode.md"), its size and type are read
+from the local file. A root relative name (e.g. "/media/episode.mp3")
+is found under htdocs and its URL formed from the site's base URL.
+The front matter duration, episode, season, episode_type, explicit,
+transcript and chapters become itunes:* and podcast:* tags, a
+bundled "episode.vtt" or "episode.chapters.json" is used too. Channel tags are set with the "-itunes-*" options.
+
+    {app_name} {verb} -itunes-author="Jane Doe" \
+        -itunes-owner="Jane Doe <jane@example.org>" \
+        -itunes-category="Technology" \
+        -base-url="http://example.org/show" \
+        show >show/podcast.xml
+
+With "-feeds DIR" a blog with a "blog.json" gets the feed for the
+whole blog in DIR/rss.xml plus one feed per keyword, category,
+series and author in DIR/tags, DIR/categories, DIR/series and
+DIR/authors, e.g. DIR/tags/plain-text.xml. Each has an atom:link
+self reference under "-feeds-url", by default DIR's path under
+the blog's URL.
+
+    {app_name} {verb} -feeds htdocs/feeds \
+        -feeds-url="http://blog.example.org/feeds" htdocs
+
+Front matter keywords (or tags) and a post's category become the
+item's category elements. "-channel-category" takes a comma
+separated list, "-channel-image URL" sets the channel's image and
+"-skip-hours" and "-skip-days" list when aggregators can skip
+checking the feed.
+
+Front matter may be YAML, TOML ("+++" blocks, e.g. Hugo posts),
+JSON or a Pandoc title block. A post's "pubDate", or "date", is
+its publication date.
+
+With "-format atom" the same content is rendered as an Atom 1.0
+feed. The "-atom-link" URL becomes the feed's id and "self" link.
+A blog.json post's creators, or its author, are the item's author.
+If an item doesn't name its author the feed's author is set from
+"-author" (or the channel title) as Atom requires.
+
+    {app_name} {verb} -format atom -author="Jane Doe" \
+        -atom-link="http://blog.example.org/atom.xml" \
+        -channel-link="http://blog.example.org" \
+        htdocs >htdocs/atom.xml
+
+With "-html" the feed is built from the rendered HTML pages found
+in PATH_TO_SITE, with "-sitemap FILE" from the pages listed in a
+sitemap.xml, skipping those not found in PATH_TO_SITE. Pages with
+a date (article:published_time meta, a <time datetime> or the
+sitemap's lastmod) become items, their title and description come
+from <title> and the description meta element.
+
+    {app_name} {verb} -html -e drafts -base-url="http://example.org" \
+        htdocs >htdocs/rss.xml
+
+With "-merge" the RSS, Atom or JSON Feed files or URLs given are
+merged into one feed, e.g. for a "planet". Items are deduplicated by
+guid or link, sorted newest first and each names its channel in a
+source element. "-river FILE" also writes a river of news page,
+Markdown for a ".md" file otherwise HTML.
+
+    {app_name} {verb} -merge -channel-title="Planet" \
+        -river htdocs/index.html -o htdocs/rss.xml \
+        https://a.example.org/rss.xml https://b.example.org/atom.xml
+
+With "-query EXPR" the RSS, Atom or JSON Feed files named (or
+standard input) are queried with a small jq like language. The
+results are written as JSON, CSV or lines ("-query-format"). A
+query names the paths of the feed's format, RSS items are in .item,
+Atom entries in .entry and JSON Feed items in .items. An RSS
+.category is always a list of strings, with the domains in
+.categories. Paths (.item[0:5], .item[]), pipes, comparisons,
+"and", "or", objects ({title, url: .link}) and the functions
+select, length, keys, not, contains, startswith, endswith, test
+and join are supported.
+
+    {app_name} {verb} -query-format lines \
+        -query '.item[0:5] | select(.category | contains("go")) | .link' \
+        htdocs/rss.xml
+    {app_name} {verb} -query-format lines \
+        -query '.entry[0:5] | select(.category[].term == "go") | .link[0].href' \
+        htdocs/atom.xml
+    {app_name} {verb} -query-format lines \
+        -query '.items[0:5] | select(.tags | contains("go")) | .url' \
+        htdocs/feed.json
+
+With "-validate FILE" an RSS 2.0 feed is checked offline for the
+problems the W3C and RSS Board validators report, e.g. missing
+channel elements, dates that aren't RFC 822, relative links, guids
+that repeat or aren't permalinks, a missing atom:link self and
+undeclared name spaces. Problems are listed with their line number,
+errors make the verb exit with an error.
+
+    {app_name} {verb} -validate htdocs/rss.xml
+
+With "-hub URL" the feed declares WebSub hubs (comma separated) as
+atom:link elements with rel="hub". With "-ping HUB" the hub is sent
+a WebSub publish notification for the "-atom-link" URL once the
+feed is written with "-o" (for "-feeds" each feed's URL), HUB is
+declared when "-hub" isn't given.
+
+    {app_name} {verb} -ping https://pubsubhubbub.appspot.com/ \
+        -atom-link="http://blog.example.org/rss.xml" \
+        -o htdocs/rss.xml htdocs
+
 DESCRIPTION
 
 EXAMPLE
@@ -0,0 +1,440 @@
+// pttk is software for working with plain text content.
+// Copyright (C) 2022 R. S. Doiel
+//
+// This program is free software: you can redistribute it and/or modify it under the terms of the GNU Affero General Public License as published by the Free Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License along with this program. If not, see <https://www.gnu.org/licenses/>.
+package rss
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	// My packages
+	"github.com/rsdoiel/pttk/feed"
+)
+
+var (
+	// rssAuthorExp matches an RSS author, "EMAIL (NAME)"
+	rssAuthorExp = regexp.MustCompile(`^\s*(\S+@\S+)\s*\((.*)\)\s*$`)
+)
+
+// init registers RSS 2.0 and Atom 1.0 as feed formats
+func init() {
+	feed.RegisterFormat(feed.RSS, func(src []byte) (*feed.Feed, error) {
+		r, err := Parse(src)
+		if err != nil {
+			return nil, err
+		}
+		return r.ToFeed(), nil
+	}, func(f *feed.Feed) ([]byte, error) {
+		return FromFeed(f).Marshal()
+	})
+	feed.RegisterFormat(feed.Atom, func(src []byte) (*feed.Feed, error) {
+		atom, err := ParseAtom(src)
+		if err != nil {
+			return nil, err
+		}
+		return atom.ToFeed(), nil
+	}, func(f *feed.Feed) ([]byte, error) {
+		return AtomFromFeed(f).Marshal()
+	})
+}
+
+// rssDate converts an RFC 3339 date to the RFC 1123 dates of RSS
+func rssDate(s string) string {
+	if dt, err := time.Parse(time.RFC3339, s); err == nil {
+		return dt.Format(time.RFC1123Z)
+	}
+	return ""
+}
+
+// parseRSSAuthor reads an RSS author, an email address optionally
+// followed by the name in parentheses or, as often found, a name.
+func parseRSSAuthor(s string) *feed.Person {
+	s = strings.TrimSpace(s)
+	if m := rssAuthorExp.FindStringSubmatch(s); m != nil {
+		return &feed.Person{Name: strings.TrimSpace(m[2]), Email: m[1]}
+	}
+	if strings.Contains(s, "@") && !strings.Contains(s, " ") {
+		return &feed.Person{Email: s}
+	}
+	return &feed.Person{Name: s}
+}
+
+// rssAuthor formats a person as an RSS author
+func rssAuthor(person *feed.Person) string {
+	switch {
+	case person.Email != "" && person.Name != "":
+		return fmt.Sprintf("%s (%s)", person.Email, person.Name)
+	case person.Email != "":
+		return person.Email
+	}
+	return person.Name
+}
+
+// categoryTags returns RSS categories as tags
+func categoryTags(categories []*Category) []string {
+	var tags []string
+	for _, category := range categories {
+		tags = append(tags, category.Value)
+	}
+	return tags
+}
+
+// parseDuration reads an itunes:duration, "H:MM:SS", "MM:SS" or
+// seconds, as seconds.
+func parseDuration(s string) int {
+	seconds := 0
+	for _, part := range strings.Split(strings.TrimSpace(s), ":") {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return 0
+		}
+		seconds = seconds*60 + n
+	}
+	return seconds
+}
+
+// formatDuration formats seconds as an itunes:duration, "H:MM:SS"
+func formatDuration(seconds int) string {
+	return fmt.Sprintf("%d:%02d:%02d", seconds/3600, seconds/60%60, seconds%60)
+}
+
+// ToFeed reads the RSS 2.0 feed into the common feed model. An
+// item's content:encoded is its HTML content and its description
+// the summary, without content:encoded the description is the
+// content.
+func (r *RSS2) ToFeed() *feed.Feed {
+	f := &feed.Feed{
+		Title:       r.Title,
+		Description: r.Description,
+		Link:        r.Link,
+		FeedURL:     r.AtomLinkHRef("self"),
+		Language:    r.Language,
+		Copyright:   r.Copyright,
+		Generator:   r.Generator,
+		Hubs:        r.Hubs(),
+		Tags:        categoryTags(r.Category),
+	}
+	if len(f.Hubs) == 0 {
+		f.Hubs = nil
+	}
+	if f.Updated = feed.NormalizeDate(r.LastBuildDate); f.Updated == "" {
+		f.Updated = feed.NormalizeDate(r.PubDate)
+	}
+	switch {
+	case r.ManagingEditor != "":
+		f.Authors = append(f.Authors, parseRSSAuthor(r.ManagingEditor))
+	case r.ItunesAuthor != "":
+		f.Authors = append(f.Authors, &feed.Person{Name: r.ItunesAuthor})
+	}
+	switch {
+	case r.Image != nil:
+		f.Icon = r.Image.URL
+	case r.ItunesImage != nil:
+		f.Icon = r.ItunesImage.HRef
+	}
+	for _, item := range r.ItemList {
+		entry := &feed.Entry{
+			ID:        item.GUID,
+			Link:      item.Link,
+			Title:     item.Title,
+			Published: feed.NormalizeDate(item.PubDate),
+			Tags:      categoryTags(item.Category),
+		}
+		if entry.ID == "" {
+			entry.ID = item.Link
+		}
+		if item.Author != "" {
+			entry.Authors = append(entry.Authors, parseRSSAuthor(item.Author))
+		}
+		if content := item.ContentString(); content != "" {
+			entry.ContentHTML = content
+			entry.Summary = item.Description
+		} else {
+			entry.ContentHTML = item.Description
+		}
+		if item.Enclosure != nil {
+			entry.Enclosures = append(entry.Enclosures, &feed.Enclosure{
+				URL:      item.Enclosure.URL,
+				Type:     item.Enclosure.Type,
+				Length:   item.Enclosure.Length,
+				Duration: parseDuration(item.Duration),
+			})
+		}
+		if item.Source != nil {
+			entry.Source = &feed.Source{Title: item.Source.Value, URL: item.Source.URL}
+		}
+		f.Entries = append(f.Entries, entry)
+	}
+	return f
+}
+
+// FromFeed writes a feed as RSS 2.0. RSS has one author and one
+// enclosure per item, the first is kept.
+func FromFeed(f *feed.Feed) *RSS2 {
+	r := &RSS2{
+		Version:       "2.0",
+		Title:         f.Title,
+		Link:          f.Link,
+		Description:   f.Description,
+		Language:      f.Language,
+		Copyright:     f.Copyright,
+		Generator:     f.Generator,
+		LastBuildDate: rssDate(f.Updated),
+		Category:      Categories("", f.Tags...),
+	}
+	if f.Icon != "" {
+		r.SetImage(f.Icon)
+	}
+	// The managingEditor is an email address
+	if len(f.Authors) > 0 && f.Authors[0].Email != "" {
+		r.ManagingEditor = rssAuthor(f.Authors[0])
+	}
+	if f.FeedURL != "" {
+		r.AtomNameSpace = AtomNameSpace
+		r.SetAtomLink("self", f.FeedURL)
+	}
+	for _, hub := range f.Hubs {
+		r.AddHub(hub)
+	}
+	for _, entry := range f.Entries {
+		item := Item{
+			Title:    entry.Title,
+			Link:     entry.Link,
+			GUID:     entry.ID,
+			Category: Categories("", entry.Tags...),
+		}
+		if item.PubDate = rssDate(entry.Published); item.PubDate == "" {
+			item.PubDate = rssDate(entry.Updated)
+		}
+		if len(entry.Authors) > 0 {
+			item.Author = rssAuthor(entry.Authors[0])
+		}
+		switch {
+		case entry.ContentHTML != "" && entry.Summary != "":
+			r.ContentNameSpace = ContentNameSpace
+			item.Description = entry.Summary
+			item.Content = &CData{Value: entry.ContentHTML}
+		case entry.ContentHTML != "":
+			item.Description = entry.ContentHTML
+		case entry.ContentText != "":
+			item.Description = TextToHTML(entry.ContentText)
+		default:
+			item.Description = entry.Summary
+		}
+		if len(entry.Enclosures) > 0 {
+			enclosure := entry.Enclosures[0]
+			item.Enclosure = &Enclosure{URL: enclosure.URL, Type: enclosure.Type, Length: enclosure.Length}
+			if enclosure.Duration > 0 {
+				r.ItunesNameSpace = ItunesNameSpace
+				item.Duration = formatDuration(enclosure.Duration)
+			}
+		}
+		if entry.Source != nil {
+			item.Source = &Source{URL: entry.Source.URL, Value: entry.Source.Title}
+		}
+		r.ItemList = append(r.ItemList, item)
+	}
+	// Podcasts keep their artwork
+	if f.Icon != "" && r.ItunesNameSpace != "" {
+		r.ItunesImage = &ItunesImage{HRef: f.Icon}
+	}
+	return r
+}
+
+// fromAtomPeople reads Atom authors
+func fromAtomPeople(people []*AtomPerson) []*feed.Person {
+	var persons []*feed.Person
+	for _, person := range people {
+		persons = append(persons, &feed.Person{Name: person.Name, Email: person.Email, URL: person.URI})
+	}
+	return persons
+}
+
+// toAtomPeople writes Atom authors, Atom requires a name
+func toAtomPeople(persons []*feed.Person) []*AtomPerson {
+	var people []*AtomPerson
+	for _, person := range persons {
+		name := person.Name
+		if name == "" {
+			name = person.Email
+		}
+		people = append(people, &AtomPerson{Name: name, URI: person.URL, Email: person.Email})
+	}
+	return people
+}
+
+// fromAtomCategories reads Atom categories as tags
+func fromAtomCategories(categories []*AtomCategory) []string {
+	var tags []string
+	for _, category := range categories {
+		tags = append(tags, category.Term)
+	}
+	return tags
+}
+
+// toAtomCategories writes tags as Atom categories
+func toAtomCategories(tags []string) []*AtomCategory {
+	var categories []*AtomCategory
+	for _, tag := range tags {
+		categories = append(categories, &AtomCategory{Term: tag})
+	}
+	return categories
+}
+
+// ToFeed reads the Atom 1.0 feed into the common feed model.
+// Content of type "html" or "xhtml" is HTML content, otherwise it
+// is text.
+func (atom *Atom) ToFeed() *feed.Feed {
+	f := &feed.Feed{
+		Title:       atom.Title,
+		Description: atom.Subtitle,
+		Language:    atom.Lang,
+		Copyright:   atom.Rights,
+		Generator:   atom.Generator,
+		Icon:        atom.Logo,
+		Favicon:     atom.Icon,
+		Updated:     feed.NormalizeDate(atom.Updated),
+		Authors:     fromAtomPeople(atom.Authors),
+		Tags:        fromAtomCategories(atom.Category),
+	}
+	for _, link := range atom.Links {
+		switch link.Rel {
+		case "", "alternate":
+			if f.Link == "" {
+				f.Link = link.HRef
+			}
+		case "self":
+			f.FeedURL = link.HRef
+		case "hub":
+			f.Hubs = append(f.Hubs, link.HRef)
+		}
+	}
+	for _, item := range atom.Entries {
+		entry := &feed.Entry{
+			ID:        item.ID,
+			Title:     item.Title,
+			Published: feed.NormalizeDate(item.Published),
+			Updated:   feed.NormalizeDate(item.Updated),
+			Authors:   fromAtomPeople(item.Authors),
+			Tags:      fromAtomCategories(item.Category),
+		}
+		for _, link := range item.Links {
+			switch link.Rel {
+			case "", "alternate":
+				if entry.Link == "" {
+					entry.Link = link.HRef
+				}
+			case "enclosure":
+				entry.Enclosures = append(entry.Enclosures, &feed.Enclosure{URL: link.HRef, Type: link.Type, Length: link.Length})
+			}
+		}
+		if item.Summary != nil {
+			entry.Summary = item.Summary.Value
+		}
+		if item.Content != nil {
+			if item.Content.Type == "html" || item.Content.Type == "xhtml" {
+				entry.ContentHTML = item.Content.Value
+			} else {
+				entry.ContentText = item.Content.Value
+			}
+		}
+		f.Entries = append(f.Entries, entry)
+	}
+	return f
+}
+
+// AtomFromFeed writes a feed as Atom 1.0. Atom requires an id, title
+// and updated date for the feed and its entries, the link (or title)
+// and the newest date are used when they are missing. When an entry
+// has no author the feed's title is used as the feed's author.
+func AtomFromFeed(f *feed.Feed) *Atom {
+	atom := &Atom{
+		Lang:      f.Language,
+		ID:        f.FeedURL,
+		Title:     f.Title,
+		Subtitle:  f.Description,
+		Updated:   f.Updated,
+		Rights:    f.Copyright,
+		Generator: f.Generator,
+		Icon:      f.Favicon,
+		Logo:      f.Icon,
+		Authors:   toAtomPeople(f.Authors),
+		Category:  toAtomCategories(f.Tags),
+	}
+	if atom.ID == "" {
+		atom.ID = f.Link
+	}
+	if f.Link != "" {
+		atom.Links = append(atom.Links, &AtomLinkRel{HRef: f.Link, Rel: "alternate", Type: "text/html"})
+	}
+	if f.FeedURL != "" {
+		atom.Links = append(atom.Links, &AtomLinkRel{HRef: f.FeedURL, Rel: "self", Type: "application/atom+xml"})
+	}
+	for _, hub := range f.Hubs {
+		atom.Links = append(atom.Links, &AtomLinkRel{HRef: hub, Rel: "hub"})
+	}
+	needsAuthor := false
+	newest := time.Time{}
+	for _, entry := range f.Entries {
+		item := &AtomEntry{
+			ID:        entry.ID,
+			Title:     entry.Title,
+			Published: entry.Published,
+			Updated:   entry.Updated,
+			Authors:   toAtomPeople(entry.Authors),
+			Category:  toAtomCategories(entry.Tags),
+		}
+		if item.ID == "" {
+			item.ID = entry.Link
+		}
+		if item.Title == "" {
+			item.Title = entry.Link
+		}
+		if item.Updated == "" {
+			item.Updated = entry.Published
+		}
+		// Dates are compared as times, offsets and fractions differ
+		if dt, err := feed.ParseDate(item.Updated); f.Updated == "" && err == nil && dt.After(newest) {
+			newest, atom.Updated = dt, item.Updated
+		}
+		if entry.Link != "" {
+			item.Links = append(item.Links, &AtomLinkRel{HRef: entry.Link, Rel: "alternate", Type: "text/html"})
+		}
+		for _, enclosure := range entry.Enclosures {
+			item.Links = append(item.Links, &AtomLinkRel{HRef: enclosure.URL, Rel: "enclosure", Type: enclosure.Type, Length: enclosure.Length})
+		}
+		if entry.Summary != "" {
+			item.Summary = &AtomText{Type: "html", Value: entry.Summary}
+		}
+		switch {
+		case entry.ContentHTML != "":
+			item.Content = &AtomText{Type: "html", Value: entry.ContentHTML}
+		case entry.ContentText != "":
+			item.Content = &AtomText{Type: "text", Value: entry.ContentText}
+		}
+		if len(item.Authors) == 0 {
+			needsAuthor = true
+		}
+		atom.Entries = append(atom.Entries, item)
+	}
+	if atom.Updated == "" {
+		atom.Updated = time.Now().Format(time.RFC3339)
+	}
+	for _, item := range atom.Entries {
+		if item.Updated == "" {
+			item.Updated = atom.Updated
+		}
+	}
+	if len(atom.Authors) == 0 && (needsAuthor || len(atom.Entries) == 0) {
+		atom.Authors = append(atom.Authors, &AtomPerson{Name: f.Title, URI: f.Link})
+	}
+	return atom
+}
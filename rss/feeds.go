@@ -0,0 +1,195 @@
+// pttk is software for working with plain text content.
+// Copyright (C) 2022 R. S. Doiel
+//
+// This program is free software: you can redistribute it and/or modify it under the terms of the GNU Affero General Public License as published by the Free Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License along with this program. If not, see <https://www.gnu.org/licenses/>.
+package rss
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"unicode"
+
+	// My packages
+	"github.com/rsdoiel/pttk/blogit"
+)
+
+const (
+	// FeedTags is the directory of the feeds for each keyword
+	FeedTags = "tags"
+	// FeedCategories is the directory of the feeds for each category
+	FeedCategories = "categories"
+	// FeedSeries is the directory of the feeds for each series
+	FeedSeries = "series"
+	// FeedAuthors is the directory of the feeds for each author
+	FeedAuthors = "authors"
+)
+
+// Slug turns a keyword, category, series or author into a name
+// safe to use in a path or URL, e.g. "Plain Text!" becomes "plain-text".
+func Slug(s string) string {
+	var sb strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(strings.TrimSpace(s)) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if dash && sb.Len() > 0 {
+				sb.WriteRune('-')
+			}
+			sb.WriteRune(r)
+			dash = false
+		} else {
+			dash = true
+		}
+	}
+	return sb.String()
+}
+
+// FeedPath returns the path, relative to the feeds' directory, of
+// the feed for name of kind (e.g. FeedTags), "tags/plain-text.xml".
+func FeedPath(kind string, name string) string {
+	return path.Join(kind, Slug(name)+".xml")
+}
+
+// postFacets returns the names a post is listed under by kind.
+func postFacets(post *blogit.PostObj) map[string][]string {
+	facets := map[string][]string{}
+	facets[FeedTags] = append(facets[FeedTags], post.Keywords...)
+	if post.Category != "" {
+		facets[FeedCategories] = append(facets[FeedCategories], post.Category)
+	}
+	if post.Series != "" {
+		facets[FeedSeries] = append(facets[FeedSeries], post.Series)
+	}
+	if post.Author != "" {
+		facets[FeedAuthors] = append(facets[FeedAuthors], post.Author)
+	}
+	for _, creator := range post.Creators {
+		if creator.Name != "" {
+			facets[FeedAuthors] = append(facets[FeedAuthors], creator.Name)
+		}
+	}
+	return facets
+}
+
+// filterBlog returns a copy of blog holding the posts where
+// keep is true.
+func filterBlog(blog *blogit.BlogMeta, keep func(post *blogit.PostObj) bool) *blogit.BlogMeta {
+	filtered := new(blogit.BlogMeta)
+	filtered.Meta = blog.Meta
+	filtered.Years = nil
+	for _, year := range blog.Years {
+		yr := &blogit.YearObj{Year: year.Year}
+		for _, month := range year.Months {
+			mn := &blogit.MonthObj{Month: month.Month}
+			for _, day := range month.Days {
+				dy := &blogit.DayObj{Day: day.Day}
+				for _, post := range day.Posts {
+					if keep(post) {
+						// NOTE: BlogMetaToRSS updates the posts
+						// it reads so each feed gets a copy.
+						p := *post
+						dy.Posts = append(dy.Posts, &p)
+					}
+				}
+				if len(dy.Posts) > 0 {
+					mn.Days = append(mn.Days, dy)
+				}
+			}
+			if len(mn.Days) > 0 {
+				yr.Months = append(yr.Months, mn)
+			}
+		}
+		if len(yr.Months) > 0 {
+			filtered.Years = append(filtered.Years, yr)
+		}
+	}
+	return filtered
+}
+
+// BlogMetaToFeeds generates the feed of a blog and one feed for each
+// keyword, category, series and author found in its posts. The
+// feeds are returned by their path relative to the directory they
+// will be written to, siteName (e.g. "rss.xml") for the whole blog
+// and FeedPath() for the others. Each feed's atom:link is set to
+// its path joined to feedsURL when feedsURL isn't empty, the items
+// of the other feeds then name the blog's feed as their source. A
+// feed's category, in the domain of its kind, is the name it is
+// for. feed holds the channel metadata shared by the feeds.
+func BlogMetaToFeeds(blog *blogit.BlogMeta, feed *RSS2, siteName string, feedsURL string, options ...*Options) (map[string]*RSS2, error) {
+	channel := *feed
+	channel.ItemList = nil
+	feeds := map[string]*RSS2{}
+	setSelf := func(name string, f *RSS2) {
+		if feedsURL != "" {
+			f.SetAtomLink("self", strings.TrimSuffix(feedsURL, "/")+"/"+name)
+		}
+		feeds[name] = f
+	}
+	if err := BlogMetaToRSS(filterBlog(blog, func(*blogit.PostObj) bool { return true }), feed, options...); err != nil {
+		return nil, err
+	}
+	setSelf(siteName, feed)
+
+	// Collect the names used by each kind, keeping the first
+	// spelling seen for each slug.
+	names := map[string]map[string]string{}
+	for _, year := range blog.Years {
+		for _, month := range year.Months {
+			for _, day := range month.Days {
+				for _, post := range day.Posts {
+					for kind, vals := range postFacets(post) {
+						if names[kind] == nil {
+							names[kind] = map[string]string{}
+						}
+						for _, val := range vals {
+							if slug := Slug(val); slug != "" {
+								if _, ok := names[kind][slug]; !ok {
+									names[kind][slug] = strings.TrimSpace(val)
+								}
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+	kinds := []string{}
+	for kind := range names {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	for _, kind := range kinds {
+		for slug, name := range names[kind] {
+			kind, slug := kind, slug
+			sub := new(RSS2)
+			*sub = channel
+			filtered := filterBlog(blog, func(post *blogit.PostObj) bool {
+				for _, val := range postFacets(post)[kind] {
+					if Slug(val) == slug {
+						return true
+					}
+				}
+				return false
+			})
+			if err := BlogMetaToRSS(filtered, sub, options...); err != nil {
+				return nil, err
+			}
+			// The feed's category is its keyword, category, series
+			// or author, its items come from the blog's feed.
+			sub.Category = append(append([]*Category{}, channel.Category...), &Category{Domain: kind, Value: name})
+			if siteURL := feed.AtomLinkHRef("self"); siteURL != "" {
+				for i := range sub.ItemList {
+					sub.ItemList[i].Source = &Source{URL: siteURL, Value: feed.Title}
+				}
+			}
+			sub.Title = fmt.Sprintf("%s: %s", sub.Title, name)
+			setSelf(FeedPath(kind, slug), sub)
+		}
+	}
+	return feeds, nil
+}
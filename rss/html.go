@@ -0,0 +1,289 @@
+// pttk is software for working with plain text content.
+// Copyright (C) 2022 R. S. Doiel
+//
+// This program is free software: you can redistribute it and/or modify it under the terms of the GNU Affero General Public License as published by the Free Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License along with this program. If not, see <https://www.gnu.org/licenses/>.
+package rss
+
+import (
+	"encoding/xml"
+	"fmt"
+	"html"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	// My packages
+	"github.com/rsdoiel/pttk/feed"
+)
+
+var (
+	// titleTagExp, metaTagExp, linkTagExp, timeTagExp and h1TagExp find
+	// the parts of a rendered HTML page used to describe it in a feed.
+	titleTagExp = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+	metaTagExp  = regexp.MustCompile(`(?is)<meta\s[^>]*>`)
+	linkTagExp  = regexp.MustCompile(`(?is)<link\s[^>]*>`)
+	timeTagExp  = regexp.MustCompile(`(?is)<time\s[^>]*>`)
+	h1TagExp    = regexp.MustCompile(`(?is)<h1[^>]*>(.*?)</h1>`)
+	// attrExp matches the attributes of a tag
+	attrExp = regexp.MustCompile(`(?s)([\w:.-]+)\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'>]+))`)
+	// htmlTagExp matches any tag, they are removed from plain text
+	htmlTagExp = regexp.MustCompile(`(?s)<[^>]*>`)
+	// punctuationExp matches the space left before punctuation when
+	// an inline tag is removed
+	punctuationExp = regexp.MustCompile(`\s+([.,;:!?])`)
+)
+
+// HTMLMeta is the metadata of a rendered HTML page
+type HTMLMeta struct {
+	// Title from <title>, og:title or the first <h1>
+	Title string `json:"title,omitempty"`
+	// Description from the description meta element or og:description
+	Description string `json:"description,omitempty"`
+	// Author from the author meta element or article:author
+	Author string `json:"author,omitempty"`
+	// Published from article:published_time, the first <time>
+	// element's datetime or a date meta element
+	Published string `json:"published,omitempty"`
+	// Canonical is the page's link rel="canonical" or og:url
+	Canonical string `json:"canonical,omitempty"`
+	// Keywords from the keywords meta element and article:tag
+	Keywords []string `json:"keywords,omitempty"`
+}
+
+// tagAttrs returns the attributes of an HTML tag by their lower
+// cased name.
+func tagAttrs(tag string) map[string]string {
+	attrs := map[string]string{}
+	for _, m := range attrExp.FindAllStringSubmatch(tag, -1) {
+		attrs[strings.ToLower(m[1])] = html.UnescapeString(m[2] + m[3] + m[4])
+	}
+	return attrs
+}
+
+// htmlText returns HTML as plain text
+func htmlText(src string) string {
+	txt := html.UnescapeString(htmlTagExp.ReplaceAllString(src, " "))
+	return punctuationExp.ReplaceAllString(strings.Join(strings.Fields(txt), " "), "$1")
+}
+
+// ReadHTMLMeta reads the metadata of a rendered HTML page
+func ReadHTMLMeta(src []byte) *HTMLMeta {
+	page := new(HTMLMeta)
+	meta := map[string]string{}
+	for _, tag := range metaTagExp.FindAllString(string(src), -1) {
+		attrs := tagAttrs(tag)
+		name := attrs["name"]
+		if name == "" {
+			name = attrs["property"]
+		}
+		name = strings.ToLower(name)
+		switch {
+		case name == "":
+		case name == "article:tag":
+			page.Keywords = append(page.Keywords, strings.TrimSpace(attrs["content"]))
+		default:
+			if _, ok := meta[name]; !ok {
+				meta[name] = strings.TrimSpace(attrs["content"])
+			}
+		}
+	}
+	first := func(vals ...string) string {
+		for _, val := range vals {
+			if val != "" {
+				return val
+			}
+		}
+		return ""
+	}
+	var title, h1, timeTag, canonical string
+	if m := titleTagExp.FindStringSubmatch(string(src)); m != nil {
+		title = htmlText(m[1])
+	}
+	if m := h1TagExp.FindStringSubmatch(string(src)); m != nil {
+		h1 = htmlText(m[1])
+	}
+	for _, tag := range timeTagExp.FindAllString(string(src), -1) {
+		if dt := tagAttrs(tag)["datetime"]; dt != "" {
+			timeTag = dt
+			break
+		}
+	}
+	for _, tag := range linkTagExp.FindAllString(string(src), -1) {
+		if attrs := tagAttrs(tag); strings.ToLower(attrs["rel"]) == "canonical" {
+			canonical = attrs["href"]
+			break
+		}
+	}
+	page.Title = first(title, meta["og:title"], h1)
+	page.Description = first(meta["description"], meta["og:description"], meta["dc.description"])
+	page.Author = first(meta["author"], meta["article:author"], meta["dc.creator"])
+	page.Published = first(meta["article:published_time"], timeTag, meta["date"], meta["dc.date"])
+	page.Canonical = first(canonical, meta["og:url"])
+	if val := meta["keywords"]; val != "" {
+		for _, keyword := range strings.Split(val, ",") {
+			if keyword = strings.TrimSpace(keyword); keyword != "" {
+				page.Keywords = append(page.Keywords, keyword)
+			}
+		}
+	}
+	return page
+}
+
+// HTMLToItem builds a feed item from a rendered HTML page found at
+// link. It returns nil if the page doesn't have a publication date,
+// e.g. an index or about page. When fullContent is true the page's
+// content is included as content:encoded and the description is
+// HTML.
+func HTMLToItem(src []byte, link string, fullContent bool) *Item {
+	return htmlItem(src, ReadHTMLMeta(src), link, fullContent)
+}
+
+// htmlItem builds a feed item from a page and its metadata
+func htmlItem(src []byte, page *HTMLMeta, link string, fullContent bool) *Item {
+	dt, err := feed.ParseDate(page.Published)
+	if err != nil {
+		return nil
+	}
+	if strings.Contains(page.Canonical, "://") {
+		link = page.Canonical
+	}
+	content := feed.HTMLContent(src)
+	item := new(Item)
+	item.Title = page.Title
+	item.Link = link
+	item.GUID = link
+	item.Author = page.Author
+	item.PubDate = dt.Format(time.RFC1123Z)
+	item.Category = Categories("", page.Keywords...)
+	item.Description = page.Description
+	if item.Description == "" {
+		item.Description = htmlText(HTMLParagraphs(content, 1))
+	}
+	if fullContent {
+		item.Content = new(CData)
+		item.Content.Set(content)
+		if page.Description == "" {
+			item.Description = HTMLParagraphs(content, 5)
+		} else {
+			item.Description = TextToHTML(page.Description)
+		}
+	}
+	return item
+}
+
+// excluded reports if the path p, relative to htdocs, is in the colon
+// delimited list of path exclusions.
+func excluded(p string, excludeList string) bool {
+	for _, exclude := range strings.Split(excludeList, ":") {
+		exclude = strings.Trim(exclude, "/")
+		if exclude != "" && (p == exclude || strings.HasPrefix(p, exclude+"/")) {
+			return true
+		}
+	}
+	return false
+}
+
+// WalkHTML generates a feed from the rendered HTML pages found in
+// htdocs, it doesn't need blogit's /YYYY/MM/DD/ layout. Pages with a
+// publication date (article:published_time, a <time> element or a
+// date meta element) become items linked under baseURL (or the
+// channel's link), the items are sorted newest first.
+func WalkHTML(feed *RSS2, htdocs string, baseURL string, excludeList string, options ...*Options) error {
+	opts := getOptions(options)
+	if opts.FullContent {
+		feed.ContentNameSpace = ContentNameSpace
+	}
+	if baseURL == "" {
+		baseURL = feed.Link
+	}
+	err := Walk(htdocs, func(p string, info os.FileInfo) bool {
+		rel, err := filepath.Rel(htdocs, p)
+		if err != nil || excluded(filepath.ToSlash(rel), excludeList) {
+			return false
+		}
+		ext := strings.ToLower(path.Ext(p))
+		return !info.IsDir() && (ext == ".html" || ext == ".htm")
+	}, func(p string, info os.FileInfo) error {
+		src, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(htdocs, p)
+		link := strings.TrimSuffix(baseURL, "/") + "/" + filepath.ToSlash(rel)
+		if item := HTMLToItem(src, link, opts.FullContent); item != nil {
+			feed.ItemList = append(feed.ItemList, *item)
+		}
+		return nil
+	})
+	feed.SortItems()
+	return err
+}
+
+// sitemapDoc is the part of a sitemap.xml used to find pages
+type sitemapDoc struct {
+	URLs []struct {
+		Loc     string `xml:"loc"`
+		LastMod string `xml:"lastmod"`
+	} `xml:"url"`
+}
+
+// SitemapToRSS generates a feed from the HTML pages listed in a
+// sitemap. Each page's URL is mapped to a file in htdocs by removing
+// baseURL, a URL ending in "/" is its "index.html". A page without a
+// publication date uses the sitemap's lastmod, if neither is
+// available it is skipped. Pages that aren't in htdocs, e.g.
+// redirects or generated pages, are skipped with a warning. The
+// items are sorted newest first.
+func SitemapToRSS(feed *RSS2, sitemap []byte, htdocs string, baseURL string, options ...*Options) error {
+	opts := getOptions(options)
+	if opts.FullContent {
+		feed.ContentNameSpace = ContentNameSpace
+	}
+	if baseURL == "" {
+		baseURL = feed.Link
+	}
+	doc := new(sitemapDoc)
+	if err := xml.Unmarshal(sitemap, doc); err != nil {
+		return fmt.Errorf("Parsing sitemap, %s", err)
+	}
+	for _, page := range doc.URLs {
+		loc := strings.TrimSpace(page.Loc)
+		rel := strings.TrimPrefix(loc, strings.TrimSuffix(baseURL, "/"))
+		if i := strings.Index(rel, "://"); i >= 0 {
+			// Not under baseURL, use the path of the URL
+			rel = rel[i+3:]
+			if j := strings.Index(rel, "/"); j >= 0 {
+				rel = rel[j:]
+			} else {
+				rel = "/"
+			}
+		}
+		if strings.HasSuffix(rel, "/") || rel == "" {
+			rel += "index.html"
+		}
+		src, err := os.ReadFile(filepath.Join(htdocs, filepath.FromSlash(strings.TrimPrefix(rel, "/"))))
+		if os.IsNotExist(err) {
+			fmt.Fprintf(os.Stderr, "WARNING: %s, no page in %s, skipped\n", loc, htdocs)
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("Reading page for %q, %s", loc, err)
+		}
+		meta := ReadHTMLMeta(src)
+		if meta.Published == "" {
+			meta.Published = strings.TrimSpace(page.LastMod)
+		}
+		if item := htmlItem(src, meta, loc, opts.FullContent); item != nil {
+			feed.ItemList = append(feed.ItemList, *item)
+		}
+	}
+	feed.SortItems()
+	return nil
+}
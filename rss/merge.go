@@ -0,0 +1,219 @@
+// pttk is software for working with plain text content.
+// Copyright (C) 2022 R. S. Doiel
+//
+// This program is free software: you can redistribute it and/or modify it under the terms of the GNU Affero General Public License as published by the Free Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License along with this program. If not, see <https://www.gnu.org/licenses/>.
+package rss
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+
+	// My packages
+	"github.com/rsdoiel/pttk/feed"
+)
+
+const (
+	// riverHTML renders a river of news page for a merged feed
+	riverHTML = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+</head>
+<body>
+<header><h1>{{if .Link}}<a href="{{.Link}}">{{.Title}}</a>{{else}}{{.Title}}{{end}}</h1>
+{{- if .Description}}
+<p>{{.Description}}</p>
+{{- end}}
+</header>
+<main>
+{{- range .Days}}
+<section>
+<h2>{{.Day}}</h2>
+{{- range .Items}}
+<article>
+<h3>{{if .Link}}<a href="{{.Link}}">{{.Title}}</a>{{else}}{{.Title}}{{end}}</h3>
+<p class="source">{{if .SourceURL}}<a href="{{.SourceURL}}">{{.Source}}</a>{{else}}{{.Source}}{{end}}{{if .Author}}, {{.Author}}{{end}}</p>
+{{- if .Summary}}
+<p>{{.Summary}}</p>
+{{- end}}
+</article>
+{{- end}}
+</section>
+{{- end}}
+</main>
+</body>
+</html>
+`
+)
+
+var (
+	// FetchTimeout points at feed.Timeout, how long Fetch waits for
+	// a feed.
+	//
+	// Deprecated: set feed.Timeout.
+	FetchTimeout = &feed.Timeout
+)
+
+// Source is the channel an item came from, an aggregated item keeps
+// its source's title and feed URL.
+type Source struct {
+	URL   string `xml:"url,attr" json:"url"`
+	Value string `xml:",chardata" json:"title,omitempty"`
+}
+
+// Fetch reads a feed from a http(s):// or file:// URL or a local
+// file, waiting at most feed.Timeout.
+func Fetch(uri string) ([]byte, error) {
+	return feed.Fetch(uri)
+}
+
+// Merge adds the entries of f, a feed in any format read from
+// feedURL, to r as items. Items already in r, with the same guid or
+// link, are skipped. Each item added names f as its source unless it
+// already has one, e.g. when merging a merged feed, and its links are
+// made absolute using f's link. Call SortItems once the feeds are
+// merged. Reading a JSON Feed with feed.Parse needs the jsonfeed
+// package imported, it registers the format.
+func (r *RSS2) Merge(f *feed.Feed, feedURL string) {
+	src := FromFeed(f)
+	if src.ContentNameSpace != "" {
+		r.ContentNameSpace = src.ContentNameSpace
+	}
+	if src.ItunesNameSpace != "" {
+		r.ItunesNameSpace = src.ItunesNameSpace
+	}
+	seen := map[string]bool{}
+	for _, item := range r.ItemList {
+		if item.GUID != "" {
+			seen[item.GUID] = true
+		}
+		if item.Link != "" {
+			seen[item.Link] = true
+		}
+	}
+	for _, item := range src.ItemList {
+		item.Link = absoluteURL(src.Link, item.Link)
+		if item.GUID != "" && !strings.Contains(item.GUID, ":") {
+			item.GUID = absoluteURL(src.Link, item.GUID)
+		}
+		if seen[item.GUID] || seen[item.Link] {
+			continue
+		}
+		if item.GUID != "" {
+			seen[item.GUID] = true
+		}
+		if item.Link != "" {
+			seen[item.Link] = true
+		}
+		if item.Source == nil {
+			item.Source = &Source{URL: feedURL, Value: src.Title}
+		}
+		r.ItemList = append(r.ItemList, item)
+	}
+}
+
+// riverItem is an item shown in a river of news
+type riverItem struct {
+	Title     string
+	Link      string
+	Author    string
+	Source    string
+	SourceURL string
+	Summary   string
+}
+
+// riverDay holds the items published on a day
+type riverDay struct {
+	Day   string
+	Items []*riverItem
+}
+
+// riverDays groups the feed's items, sorted newest first, by the
+// day they were published. Summaries are plain text of at most
+// summarySize characters.
+func (r *RSS2) riverDays(summarySize int) []*riverDay {
+	days := []*riverDay{}
+	for _, item := range r.ItemList {
+		day := "Undated"
+		if dt, err := feed.ParseDate(item.PubDate); err == nil {
+			day = dt.Format("Monday, January 2, 2006")
+		}
+		if len(days) == 0 || days[len(days)-1].Day != day {
+			days = append(days, &riverDay{Day: day})
+		}
+		ri := &riverItem{Title: item.Title, Link: item.Link, Author: item.Author}
+		if item.Source != nil {
+			ri.Source, ri.SourceURL = item.Source.Value, item.Source.URL
+			if ri.Source == "" {
+				ri.Source = item.Source.URL
+			}
+		}
+		ri.Summary = htmlText(item.Description)
+		if runes := []rune(ri.Summary); summarySize > 0 && len(runes) > summarySize {
+			ri.Summary = strings.TrimSpace(string(runes[:summarySize])) + " ..."
+		}
+		if ri.Title == "" {
+			ri.Title, ri.Summary = ri.Summary, ""
+		}
+		days[len(days)-1].Items = append(days[len(days)-1].Items, ri)
+	}
+	return days
+}
+
+// markdownText escapes the characters Markdown treats as links
+func markdownText(s string) string {
+	return strings.NewReplacer("[", `\[`, "]", `\]`).Replace(s)
+}
+
+// RenderRiver writes the feed's items as a "planet" river of news
+// page grouped by day, as HTML or, when format is "markdown", as
+// Markdown. Item descriptions are shown as plain text summaries of
+// at most summarySize characters so markup from other sites isn't
+// copied into the page.
+func (r *RSS2) RenderRiver(out io.Writer, format string, summarySize int) error {
+	days := r.riverDays(summarySize)
+	if format != "markdown" {
+		tmpl, err := template.New("river").Parse(riverHTML)
+		if err != nil {
+			return err
+		}
+		return tmpl.Execute(out, map[string]interface{}{
+			"Title":       r.Title,
+			"Link":        r.Link,
+			"Description": r.Description,
+			"Days":        days,
+		})
+	}
+	fmt.Fprintf(out, "# %s\n", r.Title)
+	if r.Description != "" {
+		fmt.Fprintf(out, "\n%s\n", r.Description)
+	}
+	for _, day := range days {
+		fmt.Fprintf(out, "\n## %s\n", day.Day)
+		for _, item := range day.Items {
+			title := markdownText(item.Title)
+			if item.Link != "" {
+				title = fmt.Sprintf("[%s](%s)", title, item.Link)
+			}
+			source := markdownText(item.Source)
+			if item.SourceURL != "" {
+				source = fmt.Sprintf("[%s](%s)", source, item.SourceURL)
+			}
+			if item.Author != "" {
+				source += ", " + markdownText(item.Author)
+			}
+			fmt.Fprintf(out, "\n- %s, %s\n", title, source)
+			if item.Summary != "" {
+				fmt.Fprintf(out, "\n    %s\n", markdownText(item.Summary))
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,307 @@
+// pttk is software for working with plain text content.
+// Copyright (C) 2022 R. S. Doiel
+//
+// This program is free software: you can redistribute it and/or modify it under the terms of the GNU Affero General Public License as published by the Free Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License along with this program. If not, see <https://www.gnu.org/licenses/>.
+package rss
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/mail"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	// My packages
+	"github.com/rsdoiel/pttk/feed"
+	"github.com/rsdoiel/pttk/frontmatter"
+)
+
+const (
+	// ItunesNameSpace is the XML name space of Apple's podcast tags
+	ItunesNameSpace = "http://www.itunes.com/dtds/podcast-1.0.dtd"
+	// PodcastNameSpace is the XML name space of the Podcasting 2.0 tags
+	PodcastNameSpace = "https://podcastindex.org/namespace/1.0"
+)
+
+var (
+	// audioExts are the extensions of audio files bundled with
+	// a post that are used as its enclosure, in order of preference.
+	audioExts = []string{".mp3", ".m4a", ".ogg", ".oga", ".opus", ".flac", ".wav"}
+)
+
+// Enclosure describes a media object attached to an item,
+// e.g. the audio of a podcast episode.
+type Enclosure struct {
+	URL    string `xml:"url,attr" json:"url"`
+	Length int64  `xml:"length,attr" json:"length"`
+	Type   string `xml:"type,attr" json:"type"`
+}
+
+// ItunesImage is the artwork of a podcast
+type ItunesImage struct {
+	HRef string `xml:"href,attr" json:"href"`
+}
+
+// ItunesCategory is one of Apple's podcast categories, it may
+// hold a subcategory.
+type ItunesCategory struct {
+	Text        string          `xml:"text,attr" json:"text"`
+	Subcategory *ItunesCategory `xml:"itunes:category,omitempty" json:"category,omitempty"`
+}
+
+// ItunesOwner is the contact for a podcast
+type ItunesOwner struct {
+	Name  string `xml:"itunes:name,omitempty" json:"name,omitempty"`
+	Email string `xml:"itunes:email,omitempty" json:"email,omitempty"`
+}
+
+// Transcript is a podcast:transcript of an episode
+type Transcript struct {
+	URL      string `xml:"url,attr" json:"url"`
+	Type     string `xml:"type,attr" json:"type"`
+	Language string `xml:"language,attr,omitempty" json:"language,omitempty"`
+}
+
+// Chapters is the podcast:chapters of an episode
+type Chapters struct {
+	URL  string `xml:"url,attr" json:"url"`
+	Type string `xml:"type,attr" json:"type"`
+}
+
+// ParseOwner parses an owner written as "Jane Doe <jane@example.org>",
+// a value without an email address is used as the name.
+func ParseOwner(s string) *ItunesOwner {
+	if addr, err := mail.ParseAddress(s); err == nil {
+		return &ItunesOwner{Name: addr.Name, Email: addr.Address}
+	}
+	return &ItunesOwner{Name: strings.TrimSpace(s)}
+}
+
+// ParseItunesCategories parses a comma separated list of categories,
+// a subcategory follows its category after a slash, e.g.
+// "Technology, Arts/Books".
+func ParseItunesCategories(s string) []*ItunesCategory {
+	categories := []*ItunesCategory{}
+	for _, val := range strings.Split(s, ",") {
+		names := strings.SplitN(val, "/", 2)
+		category := &ItunesCategory{Text: strings.TrimSpace(names[0])}
+		if category.Text == "" {
+			continue
+		}
+		if len(names) > 1 && strings.TrimSpace(names[1]) != "" {
+			category.Subcategory = &ItunesCategory{Text: strings.TrimSpace(names[1])}
+		}
+		categories = append(categories, category)
+	}
+	return categories
+}
+
+// readFrontMatter returns the front matter of a document, an empty
+// map is returned if it can't be read.
+func readFrontMatter(fName string) map[string]interface{} {
+	fMatter := map[string]interface{}{}
+	buf, err := os.ReadFile(fName)
+	if err != nil {
+		return fMatter
+	}
+	fSrc, err := frontmatter.ReadAll(bytes.NewBuffer(buf))
+	if err != nil || len(fSrc) == 0 {
+		return fMatter
+	}
+	if err := json.Unmarshal(fSrc, &fMatter); err != nil {
+		return map[string]interface{}{}
+	}
+	return fMatter
+}
+
+// fmString returns a front matter value as a string.
+func fmString(fMatter map[string]interface{}, key string) string {
+	switch val := fMatter[key].(type) {
+	case string:
+		return strings.TrimSpace(val)
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(val)
+	}
+	return ""
+}
+
+// fmKeywords returns the front matter's keywords (or tags), a list
+// or a comma separated string.
+func fmKeywords(fMatter map[string]interface{}) []string {
+	for _, key := range []string{"keywords", "tags"} {
+		switch val := fMatter[key].(type) {
+		case string:
+			return strings.Split(val, ",")
+		case []interface{}:
+			keywords := []string{}
+			for _, v := range val {
+				if s, ok := v.(string); ok {
+					keywords = append(keywords, s)
+				}
+			}
+			return keywords
+		}
+	}
+	return nil
+}
+
+// site is where a post's assets are found, baseURL is the URL of
+// the site's document root htdocs.
+type site struct {
+	baseURL string
+	htdocs  string
+}
+
+// assetURL returns the URL of a file bundled with the post at link,
+// a root relative name (e.g. "/media/episode.mp3") is resolved
+// against the site's base URL.
+func (s site) assetURL(link string, name string) string {
+	switch {
+	case strings.Contains(name, "://"):
+		return name
+	case strings.HasPrefix(name, "/"):
+		if s.baseURL == "" {
+			return name
+		}
+		return strings.TrimSuffix(s.baseURL, "/") + name
+	}
+	return link[:strings.LastIndex(link, "/")+1] + name
+}
+
+// localAsset returns the path of a file bundled with the document
+// fName, a root relative name is found under htdocs. An empty string
+// is returned for remote files.
+func (s site) localAsset(fName string, name string) string {
+	switch {
+	case strings.Contains(name, "://"):
+		return ""
+	case strings.HasPrefix(name, "/"):
+		return path.Join(s.htdocs, name)
+	}
+	return path.Join(path.Dir(fName), name)
+}
+
+// newEnclosure describes the media file name bundled with the
+// document fName, its size and type are taken from the local copy
+// when there is one.
+func (s site) newEnclosure(link string, fName string, name string) *Enclosure {
+	enclosure := new(Enclosure)
+	enclosure.URL = s.assetURL(link, name)
+	if p := s.localAsset(fName, name); p != "" {
+		if info, err := os.Stat(p); err == nil {
+			enclosure.Length = info.Size()
+		}
+	}
+	enclosure.Type = feed.MediaType(name)
+	return enclosure
+}
+
+// bundled returns the name of the first file next to fName
+// with the same base name and one of the extensions, e.g.
+// "episode.mp3" for "episode.md".
+func bundled(fName string, exts ...string) string {
+	base := strings.TrimSuffix(fName, path.Ext(fName))
+	for _, ext := range exts {
+		if _, err := os.Stat(base + ext); err == nil {
+			return path.Base(base + ext)
+		}
+	}
+	return ""
+}
+
+// setPodcast adds an enclosure and the iTunes and Podcasting 2.0
+// tags of an episode to an item. They are taken from the front
+// matter of the document fName (enclosure, duration, episode,
+// season, episode_type, explicit, transcript and chapters) or from
+// files bundled with it, e.g. "episode.mp3", "episode.vtt" and
+// "episode.chapters.json" for "episode.md". Root relative names are
+// found on the site s.
+func (s site) setPodcast(item *Item, fName string, fMatter map[string]interface{}) {
+	switch val := fMatter["enclosure"].(type) {
+	case string:
+		item.Enclosure = s.newEnclosure(item.Link, fName, val)
+	case map[string]interface{}:
+		if name := fmString(val, "url"); name != "" {
+			item.Enclosure = s.newEnclosure(item.Link, fName, name)
+			if length, err := strconv.ParseInt(fmString(val, "length"), 10, 64); err == nil {
+				item.Enclosure.Length = length
+			}
+			if mediaType := fmString(val, "type"); mediaType != "" {
+				item.Enclosure.Type = mediaType
+			}
+		}
+	}
+	if item.Enclosure == nil {
+		if name := bundled(fName, audioExts...); name != "" {
+			item.Enclosure = s.newEnclosure(item.Link, fName, name)
+		}
+	}
+	item.Duration = fmString(fMatter, "duration")
+	item.Episode = fmString(fMatter, "episode")
+	item.Season = fmString(fMatter, "season")
+	item.EpisodeType = fmString(fMatter, "episode_type")
+	item.Explicit = fmString(fMatter, "explicit")
+
+	switch val := fMatter["transcript"].(type) {
+	case string:
+		item.Transcripts = append(item.Transcripts, &Transcript{URL: s.assetURL(item.Link, val), Type: feed.MediaType(val)})
+	case map[string]interface{}:
+		if name := fmString(val, "url"); name != "" {
+			transcript := &Transcript{URL: s.assetURL(item.Link, name), Type: feed.MediaType(name)}
+			if mediaType := fmString(val, "type"); mediaType != "" {
+				transcript.Type = mediaType
+			}
+			transcript.Language = fmString(val, "language")
+			item.Transcripts = append(item.Transcripts, transcript)
+		}
+	}
+	if len(item.Transcripts) == 0 {
+		if name := bundled(fName, ".vtt", ".srt"); name != "" {
+			item.Transcripts = append(item.Transcripts, &Transcript{URL: s.assetURL(item.Link, name), Type: feed.MediaType(name)})
+		}
+	}
+	name := fmString(fMatter, "chapters")
+	if name == "" {
+		name = bundled(fName, ".chapters.json")
+	}
+	if name != "" {
+		item.Chapters = &Chapters{URL: s.assetURL(item.Link, name), Type: "application/json+chapters"}
+	}
+}
+
+// isPodcastItem checks if an item uses the iTunes tags.
+func (item *Item) isPodcastItem() bool {
+	return item.Duration != "" || item.Episode != "" || item.Season != "" ||
+		item.EpisodeType != "" || item.Explicit != ""
+}
+
+// podcastNameSpaces declares the iTunes and Podcasting 2.0 name
+// spaces when the channel or its items use them.
+func (r *RSS2) podcastNameSpaces() {
+	usesItunes := r.ItunesAuthor != "" || r.ItunesImage != nil ||
+		len(r.ItunesCategory) > 0 || r.ItunesExplicit != "" ||
+		r.ItunesOwner != nil || r.ItunesType != ""
+	usesPodcast := false
+	for _, item := range r.ItemList {
+		if item.isPodcastItem() {
+			usesItunes = true
+		}
+		if len(item.Transcripts) > 0 || item.Chapters != nil {
+			usesPodcast = true
+		}
+	}
+	if usesItunes {
+		r.ItunesNameSpace = ItunesNameSpace
+	}
+	if usesPodcast {
+		r.PodcastNameSpace = PodcastNameSpace
+	}
+}
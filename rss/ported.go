@@ -24,6 +24,7 @@ import (
 	// My packages
 	"github.com/rsdoiel/fountain"
 	"github.com/rsdoiel/pttk/blogit"
+	"github.com/rsdoiel/pttk/feed"
 	"github.com/rsdoiel/pttk/frontmatter"
 	// 3rd Part support (e.g. YAML)
 )
@@ -159,21 +160,10 @@ func RelativeDocPath(source, target string) string {
 	return p
 }
 
-// NormalizeDate takes a MySQL like date string and returns a time.Time or error
+// NormalizeDate takes a MySQL like date string and returns a time.Time
+// or error, it accepts the dates feed.ParseDate does.
 func NormalizeDate(s string) (time.Time, error) {
-	switch len(s) {
-	case len(`2006-01-02 15:04:05 -0700`):
-		dt, err := time.Parse(`2006-01-02 15:04:05 -0700`, s)
-		return dt, err
-	case len(`2006-01-02 15:04:05`):
-		dt, err := time.Parse(`2006-01-02 15:04:05`, s)
-		return dt, err
-	case len(`2006-01-02`):
-		dt, err := time.Parse(`2006-01-02`, s)
-		return dt, err
-	default:
-		return time.Time{}, fmt.Errorf("Can't format %s, expected format like 2006-01-02 15:04:05 -0700", s)
-	}
+	return feed.ParseDate(s)
 }
 
 // Walk takes a start path and walks the file system to process Markdown files f or useful elements.
@@ -209,8 +199,65 @@ func Grep(exp string, src string) string {
 	return ""
 }
 
-// Generate a Feed from walking the blogit.BlogMeta structure
-func BlogMetaToRSS(blog *blogit.BlogMeta, feed *RSS2) error {
+var (
+	// paragraphExp finds the paragraphs of a rendered HTML page used
+	// as an item's description.
+	paragraphExp = regexp.MustCompile(`(?is)<p[\s>].*?</p>`)
+)
+
+// Options changes how BlogMetaToRSS and WalkRSS build a feed's items.
+type Options struct {
+	// FullContent includes the HTML page rendered from a post in the
+	// item's content:encoded and uses HTML for the description.
+	FullContent bool
+	// Htdocs is the site's document root used by BlogMetaToRSS, root
+	// relative podcast files (e.g. "/media/episode.mp3") are read
+	// from it.
+	Htdocs string
+}
+
+// getOptions returns the options passed or the defaults.
+func getOptions(options []*Options) *Options {
+	if len(options) > 0 && options[0] != nil {
+		return options[0]
+	}
+	return new(Options)
+}
+
+// HTMLParagraphs returns the first cnt paragraphs of an HTML fragment.
+func HTMLParagraphs(src string, cnt int) string {
+	return strings.Join(paragraphExp.FindAllString(src, cnt), "\n")
+}
+
+// setFullContent sets an item's content:encoded from the HTML rendered
+// from fName. The description becomes HTML, the opening paragraphs
+// of the page when the description was taken from the document's
+// text or the description rendered as HTML paragraphs otherwise.
+func setFullContent(item *Item, fName string, openingParagraphs bool) {
+	content := feed.RenderedHTML(fName)
+	if content == "" {
+		item.Description = TextToHTML(item.Description)
+		return
+	}
+	item.Content = new(CData)
+	item.Content.Set(content)
+	if openingParagraphs {
+		if description := HTMLParagraphs(content, 5); description != "" {
+			item.Description = description
+			return
+		}
+	}
+	item.Description = TextToHTML(item.Description)
+}
+
+// Generate a Feed from walking the blogit.BlogMeta structure, the
+// items are sorted newest first. If options are passed the first
+// is used.
+func BlogMetaToRSS(blog *blogit.BlogMeta, feed *RSS2, options ...*Options) error {
+	opts := getOptions(options)
+	if opts.FullContent {
+		feed.ContentNameSpace = ContentNameSpace
+	}
 	if len(blog.Name) > 0 {
 		feed.Title = blog.Name
 	}
@@ -237,6 +284,7 @@ func BlogMetaToRSS(blog *blogit.BlogMeta, feed *RSS2) error {
 	if len(blog.Copyright) > 0 {
 		feed.Copyright = blog.Copyright
 	}
+	podcastSite := site{baseURL: feed.Link, htdocs: opts.Htdocs}
 	//FIXME: Need to iterate over years, months, days and build our
 	// blog items.
 	for _, years := range blog.Years {
@@ -275,6 +323,7 @@ func BlogMetaToRSS(blog *blogit.BlogMeta, feed *RSS2) error {
 						item.GUID = strings.TrimSuffix(feed.Link, "/") + "/" + strings.TrimPrefix(item.Link, "/")
 					}
 					item.PubDate = pubDate.Format(time.RFC1123Z)
+					fromText := false
 					if len(post.Description) == 0 && len(post.Document) > 0 {
 						// Read the article, extract a description
 						buf, err := os.ReadFile(post.Document)
@@ -300,6 +349,7 @@ func BlogMetaToRSS(blog *blogit.BlogMeta, feed *RSS2) error {
 						} else if val, ok := fMatter["abstract"]; ok {
 							post.Description = val.(string)
 						} else if includeDescription {
+							fromText = true
 							post.Description = OpeningParagraphs(fmt.Sprintf("%s", tSrc), 5, "\n\n")
 							if len(post.Description) < len(tSrc) {
 								post.Description += " ..."
@@ -312,6 +362,33 @@ func BlogMetaToRSS(blog *blogit.BlogMeta, feed *RSS2) error {
 					if len(post.Description) > 0 {
 						item.Description = post.Description
 					}
+					if opts.FullContent && includeDescription {
+						setFullContent(item, post.Document, fromText)
+					}
+					// The post's creators, or its author, are the
+					// item's author
+					names := []string{}
+					for _, creator := range post.Creators {
+						if name := strings.TrimSpace(creator.Name); name != "" {
+							names = append(names, name)
+						}
+					}
+					if len(names) > 0 {
+						item.Author = strings.Join(names, ", ")
+					} else if author := strings.TrimSpace(post.Author); author != "" {
+						item.Author = author
+					}
+					// Keywords and the post's category become the
+					// item's categories
+					keywords := append([]string{}, post.Keywords...)
+					if len(post.Document) > 0 {
+						fMatter := readFrontMatter(post.Document)
+						if len(keywords) == 0 {
+							keywords = fmKeywords(fMatter)
+						}
+						podcastSite.setPodcast(item, post.Document, fMatter)
+					}
+					item.Category = Categories("", append(keywords, post.Category)...)
 					if item.Title != "" || item.Description != "" {
 						feed.ItemList = append(feed.ItemList, *item)
 					}
@@ -319,25 +396,37 @@ func BlogMetaToRSS(blog *blogit.BlogMeta, feed *RSS2) error {
 			}
 		}
 	}
+	feed.podcastNameSpaces()
+	feed.SortItems()
 	return nil
 }
 
-// Generate a Feed by walking the file system.
-func WalkRSS(feed *RSS2, htdocs string, baseURL string, excludeList string, titleExp string, bylineExp string, dateExp string) error {
-	validBlogPath := regexp.MustCompile("/[0-9][0-9][0-9][0-9]/[0-9][0-9]/[0-9][0-9]/")
-	err := Walk(htdocs, func(p string, info os.FileInfo) bool {
-		fname := path.Base(p)
-		if validBlogPath.MatchString(p) == true &&
-			strings.HasSuffix(fname, ".md") == true {
-			// NOTE: We have a possible published markdown article.
-			// Make sure we have a HTML version before adding it
-			// to the feed.
-			if _, err := os.Stat(path.Join(p, path.Base(fname)+".html")); os.IsNotExist(err) {
-				return false
-			}
-			return true
-		}
+// blogPathExp matches the YYYY/MM/DD directories of a blog post, the
+// path may be relative to htdocs (e.g. "2022/07/30/post.md").
+var blogPathExp = regexp.MustCompile("(^|/)[0-9][0-9][0-9][0-9]/[0-9][0-9]/[0-9][0-9]/")
+
+// isBlogPost returns true if p is a Markdown document in a blog's
+// YYYY/MM/DD path that has been rendered, i.e. "post.html" is
+// next to "post.md".
+func isBlogPost(p string) bool {
+	if !blogPathExp.MatchString(p) || !strings.HasSuffix(p, ".md") {
+		return false
+	}
+	if _, err := os.Stat(strings.TrimSuffix(p, ".md") + ".html"); os.IsNotExist(err) {
 		return false
+	}
+	return true
+}
+
+// Generate a Feed by walking the file system, the items are sorted
+// newest first. If options are passed the first is used.
+func WalkRSS(feed *RSS2, htdocs string, baseURL string, excludeList string, titleExp string, bylineExp string, dateExp string, options ...*Options) error {
+	opts := getOptions(options)
+	if opts.FullContent {
+		feed.ContentNameSpace = ContentNameSpace
+	}
+	err := Walk(htdocs, func(p string, info os.FileInfo) bool {
+		return isBlogPost(p)
 	}, func(p string, info os.FileInfo) error {
 		// Read the article
 		buf, err := os.ReadFile(p)
@@ -375,6 +464,7 @@ func WalkRSS(feed *RSS2, htdocs string, baseURL string, excludeList string, titl
 		//NOTE: Use front matter if available otherwise
 		var (
 			title, byline, author, description, pubDate string
+			fromText                                    bool
 		)
 		src := fmt.Sprintf("%s", buf)
 		if val, ok := fMatter["title"]; ok {
@@ -387,10 +477,19 @@ func WalkRSS(feed *RSS2, htdocs string, baseURL string, excludeList string, titl
 		} else {
 			byline = Grep(bylineExp, src)
 		}
-		if val, ok := fMatter["pubDate"]; ok {
+		// Hugo style posts name their publication date "date"
+		val, ok := fMatter["pubDate"]
+		if !ok {
+			val, ok = fMatter["date"]
+		}
+		if ok {
 			switch val.(type) {
 			case string:
 				pubDate = val.(string)
+				// YAML and TOML dates arrive as RFC 3339 timestamps
+				if dt, err := time.Parse(time.RFC3339, pubDate); err == nil {
+					pubDate = dt.Format(blogit.DateFmt)
+				}
 			case time.Time:
 				dt := val.(time.Time)
 				pubDate = dt.Format(blogit.DateFmt)
@@ -403,6 +502,7 @@ func WalkRSS(feed *RSS2, htdocs string, baseURL string, excludeList string, titl
 		} else if val, ok := fMatter["abstract"]; ok {
 			description = val.(string)
 		} else {
+			fromText = true
 			description = OpeningParagraphs(fmt.Sprintf("%s", tSrc), 5, "\n\n")
 			if len(description) < len(tSrc) {
 				description += " ..."
@@ -444,9 +544,16 @@ func WalkRSS(feed *RSS2, htdocs string, baseURL string, excludeList string, titl
 		item.PubDate = pubDate
 		item.Link = u.String()
 		item.Description = description
+		item.Category = Categories("", fmKeywords(fMatter)...)
+		if opts.FullContent {
+			setFullContent(item, p, fromText)
+		}
+		site{baseURL: baseURL, htdocs: htdocs}.setPodcast(item, p, fMatter)
 		feed.ItemList = append(feed.ItemList, *item)
 		return nil
 	})
+	feed.podcastNameSpaces()
+	feed.SortItems()
 	return err
 }
 
@@ -459,71 +566,7 @@ var (
 
 // MetadataBlock holds the Pandoc style Metadata block delimited
 // by start '%' at the being of the line in the start of a text file.
-type MetadataBlock struct {
-	Title   string   `json:"title"`
-	Authors []string `json:"authors"`
-	Date    string   `json:"date"`
-}
-
-func (block *MetadataBlock) String() string {
-	return fmt.Sprintf("%% %s\n%% %s\n%% %s", block.Title, strings.Join(block.Authors, "; "), block.Date)
-}
-
-func (block *MetadataBlock) Unmarshal(src []byte) error {
-	lines := bytes.Split(src, []byte("\n"))
-	fieldCnt := 0
-	key := ""
-	block.Title = ""
-	block.Authors = []string{}
-	block.Date = ""
-	for i := 0; i < len(lines); i++ {
-		line := lines[i]
-		if bytes.HasPrefix(line, []byte("% ")) {
-			fieldCnt += 1
-			switch fieldCnt {
-			case 1:
-				key = "title"
-			case 2:
-				key = "authors"
-			case 3:
-				key = "date"
-			default:
-				key = ""
-			}
-			line = bytes.TrimPrefix(line, []byte("% "))
-		}
-		if (len(key) > 0) && (fieldCnt <= 3) {
-			switch key {
-			case "title":
-				if len(block.Title) > 0 {
-					block.Title = fmt.Sprintf("%s\n%s", block.Title, bytes.TrimSpace(line))
-				} else {
-					block.Title = fmt.Sprintf("%s", bytes.TrimSpace(line))
-				}
-			case "authors":
-				if bytes.Contains(line, []byte(";")) {
-					parts := bytes.Split(line, []byte(";"))
-					for _, part := range parts {
-						block.Authors = append(block.Authors, fmt.Sprintf("%s", bytes.TrimSpace(part)))
-					}
-				} else {
-					block.Authors = append(block.Authors, fmt.Sprintf("%s", bytes.TrimSpace(line)))
-				}
-			case "date":
-				block.Date = fmt.Sprintf("%s", bytes.TrimSpace(line))
-				key = ""
-			}
-		}
-	}
-	if fieldCnt != 3 {
-		return fmt.Errorf("Missing or ill formed metablock, expecting title, author(s), date")
-	}
-	return nil
-}
-
-func (block *MetadataBlock) Marshal() ([]byte, error) {
-	return json.Marshal(block)
-}
+type MetadataBlock = frontmatter.MetadataBlock
 
 func scanArgs(s string) (string, []string) {
 	var (
@@ -0,0 +1,1095 @@
+// pttk is software for working with plain text content.
+// Copyright (C) 2022 R. S. Doiel
+//
+// This program is free software: you can redistribute it and/or modify it under the terms of the GNU Affero General Public License as published by the Free Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License along with this program. If not, see <https://www.gnu.org/licenses/>.
+package rss
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	// My packages
+	"github.com/rsdoiel/pttk/feed"
+)
+
+// Query is a compiled feed query. Feed queries are a small jq like
+// language used to pull data out of RSS 2, Atom and JSON Feed
+// documents. Each format is queried in its own terms, the items are
+// .item in RSS, .entry in Atom and .items in JSON Feed, e.g.
+//
+//	.item[0:5] | select(.category | contains("go")) | .link
+//	.entry[0:5] | select(.category[].term == "go") | .link[0].href
+//	.items[0:5] | select(.tags | contains("go")) | .url
+//
+// A query runs over the JSON form of the feed (see FeedData), it
+// produces a stream of results. Paths (.title, .item[2], .item[1:3],
+// .item[]), pipes (|), commas (,), comparisons (==, !=, <, <=, >, >=),
+// "and", "or", literals, objects ({title, url: .link}) and the
+// functions select, length, keys, not, contains, startswith, endswith,
+// test and join are supported. Unlike jq a slice is iterated, select
+// keeps its input once when any result of its condition is true and an
+// object's field holding many results holds them as a list.
+type Query struct {
+	expr string
+	root queryNode
+}
+
+// queryNode is an expression of a query, it returns the stream of
+// results for an input.
+type queryNode interface {
+	eval(v interface{}) ([]interface{}, error)
+}
+
+// queryObject is an object built by a query, it keeps the order of
+// its keys.
+type queryObject struct {
+	keys []string
+	vals map[string]interface{}
+}
+
+// MarshalJSON renders the object with its keys in order
+func (obj *queryObject) MarshalJSON() ([]byte, error) {
+	buf := new(bytes.Buffer)
+	buf.WriteString("{")
+	for i, key := range obj.keys {
+		if i > 0 {
+			buf.WriteString(",")
+		}
+		k, _ := json.Marshal(key)
+		v, err := json.Marshal(obj.vals[key])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(k)
+		buf.WriteString(":")
+		buf.Write(v)
+	}
+	buf.WriteString("}")
+	return buf.Bytes(), nil
+}
+
+type identityNode struct{}
+
+func (n *identityNode) eval(v interface{}) ([]interface{}, error) {
+	return []interface{}{v}, nil
+}
+
+type literalNode struct {
+	val interface{}
+}
+
+func (n *literalNode) eval(v interface{}) ([]interface{}, error) {
+	return []interface{}{n.val}, nil
+}
+
+type fieldNode struct {
+	target queryNode
+	name   string
+}
+
+func (n *fieldNode) eval(v interface{}) ([]interface{}, error) {
+	vals, err := n.target.eval(v)
+	if err != nil {
+		return nil, err
+	}
+	results := []interface{}{}
+	for _, val := range vals {
+		switch obj := val.(type) {
+		case nil:
+			results = append(results, nil)
+		case map[string]interface{}:
+			results = append(results, obj[n.name])
+		case *queryObject:
+			results = append(results, obj.vals[n.name])
+		default:
+			return nil, fmt.Errorf("can't get .%s of %s", n.name, typeName(val))
+		}
+	}
+	return results, nil
+}
+
+type indexNode struct {
+	target queryNode
+	index  int
+}
+
+func (n *indexNode) eval(v interface{}) ([]interface{}, error) {
+	vals, err := n.target.eval(v)
+	if err != nil {
+		return nil, err
+	}
+	results := []interface{}{}
+	for _, val := range vals {
+		switch list := val.(type) {
+		case nil:
+			results = append(results, nil)
+		case []interface{}:
+			i := n.index
+			if i < 0 {
+				i += len(list)
+			}
+			if i >= 0 && i < len(list) {
+				results = append(results, list[i])
+			} else {
+				results = append(results, nil)
+			}
+		default:
+			return nil, fmt.Errorf("can't index %s", typeName(val))
+		}
+	}
+	return results, nil
+}
+
+type sliceNode struct {
+	target queryNode
+	// from and to are nil when left out, e.g. [2:]
+	from *int
+	to   *int
+}
+
+func (n *sliceNode) eval(v interface{}) ([]interface{}, error) {
+	vals, err := n.target.eval(v)
+	if err != nil {
+		return nil, err
+	}
+	results := []interface{}{}
+	for _, val := range vals {
+		if val == nil {
+			continue
+		}
+		list, ok := val.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("can't slice %s", typeName(val))
+		}
+		from, to := 0, len(list)
+		if n.from != nil {
+			from = *n.from
+		}
+		if n.to != nil {
+			to = *n.to
+		}
+		if from < 0 {
+			from += len(list)
+		}
+		if to < 0 {
+			to += len(list)
+		}
+		if from < 0 {
+			from = 0
+		}
+		if to > len(list) {
+			to = len(list)
+		}
+		if from < to {
+			results = append(results, list[from:to]...)
+		}
+	}
+	return results, nil
+}
+
+type iterateNode struct {
+	target queryNode
+}
+
+func (n *iterateNode) eval(v interface{}) ([]interface{}, error) {
+	vals, err := n.target.eval(v)
+	if err != nil {
+		return nil, err
+	}
+	results := []interface{}{}
+	for _, val := range vals {
+		switch obj := val.(type) {
+		case nil:
+		case []interface{}:
+			results = append(results, obj...)
+		case map[string]interface{}:
+			for _, key := range sortedKeys(obj) {
+				results = append(results, obj[key])
+			}
+		case *queryObject:
+			for _, key := range obj.keys {
+				results = append(results, obj.vals[key])
+			}
+		default:
+			return nil, fmt.Errorf("can't iterate over %s", typeName(val))
+		}
+	}
+	return results, nil
+}
+
+type pipeNode struct {
+	left  queryNode
+	right queryNode
+}
+
+func (n *pipeNode) eval(v interface{}) ([]interface{}, error) {
+	vals, err := n.left.eval(v)
+	if err != nil {
+		return nil, err
+	}
+	results := []interface{}{}
+	for _, val := range vals {
+		out, err := n.right.eval(val)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, out...)
+	}
+	return results, nil
+}
+
+type commaNode struct {
+	left  queryNode
+	right queryNode
+}
+
+func (n *commaNode) eval(v interface{}) ([]interface{}, error) {
+	left, err := n.left.eval(v)
+	if err != nil {
+		return nil, err
+	}
+	right, err := n.right.eval(v)
+	if err != nil {
+		return nil, err
+	}
+	return append(left, right...), nil
+}
+
+// binaryNode is a comparison or "and", "or", it returns the result
+// for each pair of results of its left and right.
+type binaryNode struct {
+	op    string
+	left  queryNode
+	right queryNode
+}
+
+func (n *binaryNode) eval(v interface{}) ([]interface{}, error) {
+	left, err := n.left.eval(v)
+	if err != nil {
+		return nil, err
+	}
+	right, err := n.right.eval(v)
+	if err != nil {
+		return nil, err
+	}
+	results := []interface{}{}
+	for _, a := range left {
+		for _, b := range right {
+			var result bool
+			switch n.op {
+			case "and":
+				result = truthy(a) && truthy(b)
+			case "or":
+				result = truthy(a) || truthy(b)
+			case "==":
+				result = reflect.DeepEqual(plainValue(a), plainValue(b))
+			case "!=":
+				result = !reflect.DeepEqual(plainValue(a), plainValue(b))
+			default:
+				cmp, ok := compareValues(a, b)
+				if ok {
+					switch n.op {
+					case "<":
+						result = cmp < 0
+					case "<=":
+						result = cmp <= 0
+					case ">":
+						result = cmp > 0
+					case ">=":
+						result = cmp >= 0
+					}
+				}
+			}
+			results = append(results, result)
+		}
+	}
+	return results, nil
+}
+
+type objectNode struct {
+	keys []string
+	vals []queryNode
+}
+
+func (n *objectNode) eval(v interface{}) ([]interface{}, error) {
+	obj := &queryObject{vals: map[string]interface{}{}}
+	for i, key := range n.keys {
+		vals, err := n.vals[i].eval(v)
+		if err != nil {
+			return nil, err
+		}
+		obj.keys = append(obj.keys, key)
+		switch len(vals) {
+		case 0:
+			obj.vals[key] = nil
+		case 1:
+			obj.vals[key] = vals[0]
+		default:
+			obj.vals[key] = vals
+		}
+	}
+	return []interface{}{obj}, nil
+}
+
+type callNode struct {
+	name string
+	args []queryNode
+}
+
+// queryFuncs are the functions of a query and their number of arguments
+var queryFuncs = map[string]int{
+	"select":     1,
+	"length":     0,
+	"keys":       0,
+	"not":        0,
+	"contains":   1,
+	"startswith": 1,
+	"endswith":   1,
+	"test":       1,
+	"join":       1,
+}
+
+func (n *callNode) eval(v interface{}) ([]interface{}, error) {
+	switch n.name {
+	case "select":
+		vals, err := n.args[0].eval(v)
+		if err != nil {
+			return nil, err
+		}
+		for _, val := range vals {
+			if truthy(val) {
+				return []interface{}{v}, nil
+			}
+		}
+		return []interface{}{}, nil
+	case "length":
+		switch val := v.(type) {
+		case nil:
+			return []interface{}{0.0}, nil
+		case string:
+			return []interface{}{float64(len([]rune(val)))}, nil
+		case []interface{}:
+			return []interface{}{float64(len(val))}, nil
+		case map[string]interface{}:
+			return []interface{}{float64(len(val))}, nil
+		case *queryObject:
+			return []interface{}{float64(len(val.keys))}, nil
+		}
+		return nil, fmt.Errorf("%s has no length", typeName(v))
+	case "keys":
+		keys := []interface{}{}
+		switch val := v.(type) {
+		case map[string]interface{}:
+			for _, key := range sortedKeys(val) {
+				keys = append(keys, key)
+			}
+		case *queryObject:
+			for _, key := range val.keys {
+				keys = append(keys, key)
+			}
+		default:
+			return nil, fmt.Errorf("%s has no keys", typeName(v))
+		}
+		return []interface{}{keys}, nil
+	case "not":
+		return []interface{}{!truthy(v)}, nil
+	}
+
+	// The remaining functions take a string argument
+	args, err := n.args[0].eval(v)
+	if err != nil {
+		return nil, err
+	}
+	results := []interface{}{}
+	for _, arg := range args {
+		s, ok := arg.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s expects a string, got %s", n.name, typeName(arg))
+		}
+		if n.name == "join" {
+			list, ok := v.([]interface{})
+			if !ok && v != nil {
+				return nil, fmt.Errorf("can't join %s", typeName(v))
+			}
+			parts := []string{}
+			for _, item := range list {
+				parts = append(parts, valueString(item))
+			}
+			results = append(results, strings.Join(parts, s))
+			continue
+		}
+		switch val := v.(type) {
+		case nil:
+			results = append(results, false)
+		case string:
+			switch n.name {
+			case "contains":
+				results = append(results, strings.Contains(val, s))
+			case "startswith":
+				results = append(results, strings.HasPrefix(val, s))
+			case "endswith":
+				results = append(results, strings.HasSuffix(val, s))
+			case "test":
+				re, err := regexp.Compile(s)
+				if err != nil {
+					return nil, err
+				}
+				results = append(results, re.MatchString(val))
+			}
+		case []interface{}:
+			if n.name != "contains" {
+				return nil, fmt.Errorf("%s expects a string, got %s", n.name, typeName(v))
+			}
+			found := false
+			for _, item := range val {
+				if item == s {
+					found = true
+					break
+				}
+			}
+			results = append(results, found)
+		default:
+			return nil, fmt.Errorf("%s expects a string, got %s", n.name, typeName(v))
+		}
+	}
+	return results, nil
+}
+
+// truthy is false for null and false, everything else is true
+func truthy(v interface{}) bool {
+	if v == nil {
+		return false
+	}
+	if b, ok := v.(bool); ok {
+		return b
+	}
+	return true
+}
+
+// typeName names the type of a value in error messages
+func typeName(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "a boolean"
+	case float64:
+		return "a number"
+	case string:
+		return "a string"
+	case []interface{}:
+		return "a list"
+	}
+	return "an object"
+}
+
+// sortedKeys returns the keys of an object in order
+func sortedKeys(m map[string]interface{}) []string {
+	keys := []string{}
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// plainValue turns objects built by a query into maps so they can
+// be compared with the objects of a feed.
+func plainValue(v interface{}) interface{} {
+	switch val := v.(type) {
+	case *queryObject:
+		return val.vals
+	case []interface{}:
+		list := []interface{}{}
+		for _, item := range val {
+			list = append(list, plainValue(item))
+		}
+		return list
+	}
+	return v
+}
+
+// compareValues orders two numbers or two strings, ok is false for
+// other values.
+func compareValues(a interface{}, b interface{}) (int, bool) {
+	switch x := a.(type) {
+	case float64:
+		if y, ok := b.(float64); ok {
+			switch {
+			case x < y:
+				return -1, true
+			case x > y:
+				return 1, true
+			}
+			return 0, true
+		}
+	case string:
+		if y, ok := b.(string); ok {
+			return strings.Compare(x, y), true
+		}
+	}
+	return 0, false
+}
+
+// valueString renders a result as text, strings as is and other
+// values as JSON.
+func valueString(v interface{}) string {
+	switch val := v.(type) {
+	case string:
+		return val
+	case nil:
+		return ""
+	}
+	src, _ := json.Marshal(v)
+	return string(src)
+}
+
+// queryToken is a token of a query, kind is "ident", "string",
+// "number", "field" (a .name) or the punctuation itself.
+type queryToken struct {
+	kind string
+	val  string
+	pos  int
+}
+
+// lexQuery splits a query into tokens
+func lexQuery(expr string) ([]*queryToken, error) {
+	tokens := []*queryToken{}
+	runes := []rune(expr)
+	isIdent := func(r rune) bool {
+		return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+	}
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '"':
+			j := i + 1
+			for ; j < len(runes) && runes[j] != '"'; j++ {
+				if runes[j] == '\\' {
+					j++
+				}
+			}
+			if j >= len(runes) {
+				return nil, fmt.Errorf("unterminated string at %d", i+1)
+			}
+			s, err := strconv.Unquote(string(runes[i : j+1]))
+			if err != nil {
+				return nil, fmt.Errorf("bad string at %d, %s", i+1, err)
+			}
+			tokens = append(tokens, &queryToken{kind: "string", val: s, pos: i + 1})
+			i = j + 1
+		case r == '.' && i+1 < len(runes) && isIdent(runes[i+1]) && !unicode.IsDigit(runes[i+1]):
+			j := i + 1
+			for j < len(runes) && isIdent(runes[j]) {
+				j++
+			}
+			tokens = append(tokens, &queryToken{kind: "field", val: string(runes[i+1 : j]), pos: i + 1})
+			i = j
+		case unicode.IsDigit(r) || (r == '-' && i+1 < len(runes) && unicode.IsDigit(runes[i+1])):
+			j := i + 1
+			for j < len(runes) && (unicode.IsDigit(runes[j]) || runes[j] == '.') {
+				j++
+			}
+			tokens = append(tokens, &queryToken{kind: "number", val: string(runes[i:j]), pos: i + 1})
+			i = j
+		case isIdent(r):
+			j := i
+			for j < len(runes) && isIdent(runes[j]) {
+				j++
+			}
+			tokens = append(tokens, &queryToken{kind: "ident", val: string(runes[i:j]), pos: i + 1})
+			i = j
+		default:
+			op := string(r)
+			if i+1 < len(runes) {
+				switch two := string(runes[i : i+2]); two {
+				case "==", "!=", "<=", ">=":
+					op = two
+				}
+			}
+			if !strings.Contains("|,()[]{}:.<>", op) && len(op) == 1 {
+				return nil, fmt.Errorf("unexpected %q at %d", op, i+1)
+			}
+			tokens = append(tokens, &queryToken{kind: op, val: op, pos: i + 1})
+			i += len([]rune(op))
+		}
+	}
+	return tokens, nil
+}
+
+// queryParser is a recursive descent parser for queries
+type queryParser struct {
+	tokens []*queryToken
+	pos    int
+}
+
+func (p *queryParser) peek() *queryToken {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return &queryToken{kind: "end", pos: -1}
+}
+
+func (p *queryParser) next() *queryToken {
+	tok := p.peek()
+	if p.pos < len(p.tokens) {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *queryParser) expect(kind string) (*queryToken, error) {
+	tok := p.next()
+	if tok.kind != kind {
+		return nil, p.unexpected(tok, kind)
+	}
+	return tok, nil
+}
+
+func (p *queryParser) unexpected(tok *queryToken, want string) error {
+	if tok.kind == "end" {
+		return fmt.Errorf("expected %s at end of query", want)
+	}
+	return fmt.Errorf("expected %s at %d, got %q", want, tok.pos, tok.val)
+}
+
+// pipe := comma ("|" comma)*
+func (p *queryParser) pipe() (queryNode, error) {
+	left, err := p.comma()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == "|" {
+		p.next()
+		right, err := p.comma()
+		if err != nil {
+			return nil, err
+		}
+		left = &pipeNode{left: left, right: right}
+	}
+	return left, nil
+}
+
+// comma := or ("," or)*
+func (p *queryParser) comma() (queryNode, error) {
+	left, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == "," {
+		p.next()
+		right, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		left = &commaNode{left: left, right: right}
+	}
+	return left, nil
+}
+
+// or := and ("or" and)*
+func (p *queryParser) or() (queryNode, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for tok := p.peek(); tok.kind == "ident" && tok.val == "or"; tok = p.peek() {
+		p.next()
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{op: "or", left: left, right: right}
+	}
+	return left, nil
+}
+
+// and := compare ("and" compare)*
+func (p *queryParser) and() (queryNode, error) {
+	left, err := p.compare()
+	if err != nil {
+		return nil, err
+	}
+	for tok := p.peek(); tok.kind == "ident" && tok.val == "and"; tok = p.peek() {
+		p.next()
+		right, err := p.compare()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{op: "and", left: left, right: right}
+	}
+	return left, nil
+}
+
+// compare := postfix (op postfix)?
+func (p *queryParser) compare() (queryNode, error) {
+	left, err := p.postfix()
+	if err != nil {
+		return nil, err
+	}
+	switch op := p.peek().kind; op {
+	case "==", "!=", "<", "<=", ">", ">=":
+		p.next()
+		right, err := p.postfix()
+		if err != nil {
+			return nil, err
+		}
+		return &binaryNode{op: op, left: left, right: right}, nil
+	}
+	return left, nil
+}
+
+// postfix := primary (.name | [] | [n] | [n:m])*
+func (p *queryParser) postfix() (queryNode, error) {
+	node, err := p.primary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		switch tok := p.peek(); tok.kind {
+		case "field":
+			p.next()
+			node = &fieldNode{target: node, name: tok.val}
+		case ".":
+			// ."name"
+			if p.pos+1 < len(p.tokens) && p.tokens[p.pos+1].kind == "string" {
+				p.next()
+				node = &fieldNode{target: node, name: p.next().val}
+				continue
+			}
+			return node, nil
+		case "[":
+			p.next()
+			if node, err = p.brackets(node); err != nil {
+				return nil, err
+			}
+		default:
+			return node, nil
+		}
+	}
+}
+
+// brackets parses what follows "[", an iteration, index, slice or
+// a "name" field.
+func (p *queryParser) brackets(target queryNode) (queryNode, error) {
+	number := func() (*int, error) {
+		if p.peek().kind != "number" {
+			return nil, nil
+		}
+		tok := p.next()
+		i, err := strconv.Atoi(tok.val)
+		if err != nil {
+			return nil, fmt.Errorf("expected an integer at %d, got %q", tok.pos, tok.val)
+		}
+		return &i, nil
+	}
+	if p.peek().kind == "]" {
+		p.next()
+		return &iterateNode{target: target}, nil
+	}
+	if p.peek().kind == "string" {
+		name := p.next().val
+		if _, err := p.expect("]"); err != nil {
+			return nil, err
+		}
+		return &fieldNode{target: target, name: name}, nil
+	}
+	from, err := number()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek().kind == ":" {
+		p.next()
+		to, err := number()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.expect("]"); err != nil {
+			return nil, err
+		}
+		return &sliceNode{target: target, from: from, to: to}, nil
+	}
+	if from == nil {
+		return nil, p.unexpected(p.peek(), "an index")
+	}
+	if _, err := p.expect("]"); err != nil {
+		return nil, err
+	}
+	return &indexNode{target: target, index: *from}, nil
+}
+
+// primary := "." | .name | literal | "(" pipe ")" | object | function
+func (p *queryParser) primary() (queryNode, error) {
+	tok := p.next()
+	switch tok.kind {
+	case ".":
+		if p.peek().kind == "string" {
+			return &fieldNode{target: &identityNode{}, name: p.next().val}, nil
+		}
+		return &identityNode{}, nil
+	case "field":
+		return &fieldNode{target: &identityNode{}, name: tok.val}, nil
+	case "string":
+		return &literalNode{val: tok.val}, nil
+	case "number":
+		f, err := strconv.ParseFloat(tok.val, 64)
+		if err != nil {
+			return nil, fmt.Errorf("bad number at %d, %q", tok.pos, tok.val)
+		}
+		return &literalNode{val: f}, nil
+	case "(":
+		node, err := p.pipe()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		return node, nil
+	case "{":
+		return p.object()
+	case "ident":
+		switch tok.val {
+		case "true":
+			return &literalNode{val: true}, nil
+		case "false":
+			return &literalNode{val: false}, nil
+		case "null":
+			return &literalNode{val: nil}, nil
+		}
+		argc, ok := queryFuncs[tok.val]
+		if !ok {
+			return nil, fmt.Errorf("unknown function %q at %d", tok.val, tok.pos)
+		}
+		node := &callNode{name: tok.val}
+		if argc > 0 {
+			if _, err := p.expect("("); err != nil {
+				return nil, err
+			}
+			arg, err := p.pipe()
+			if err != nil {
+				return nil, err
+			}
+			if _, err := p.expect(")"); err != nil {
+				return nil, err
+			}
+			node.args = append(node.args, arg)
+		}
+		return node, nil
+	}
+	return nil, p.unexpected(tok, "a path, value or function")
+}
+
+// object := "{" (name | name ":" or) ("," ...)* "}", a name on its
+// own is short for name: .name
+func (p *queryParser) object() (queryNode, error) {
+	node := &objectNode{}
+	for p.peek().kind != "}" {
+		tok := p.next()
+		if tok.kind != "ident" && tok.kind != "string" {
+			return nil, p.unexpected(tok, "a key")
+		}
+		var val queryNode = &fieldNode{target: &identityNode{}, name: tok.val}
+		if p.peek().kind == ":" {
+			p.next()
+			var err error
+			if val, err = p.or(); err != nil {
+				return nil, err
+			}
+		}
+		node.keys = append(node.keys, tok.val)
+		node.vals = append(node.vals, val)
+		if p.peek().kind != "," {
+			break
+		}
+		p.next()
+	}
+	if _, err := p.expect("}"); err != nil {
+		return nil, err
+	}
+	return node, nil
+}
+
+// CompileQuery parses a feed query
+func CompileQuery(expr string) (*Query, error) {
+	tokens, err := lexQuery(expr)
+	if err != nil {
+		return nil, fmt.Errorf("query %q, %s", expr, err)
+	}
+	p := &queryParser{tokens: tokens}
+	root, err := p.pipe()
+	if err == nil && p.pos < len(tokens) {
+		err = p.unexpected(p.peek(), "end of query")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("query %q, %s", expr, err)
+	}
+	return &Query{expr: expr, root: root}, nil
+}
+
+// Run returns the results of the query for data, data is the JSON
+// form of a feed as returned by FeedData.
+func (q *Query) Run(data interface{}) ([]interface{}, error) {
+	results, err := q.root.eval(data)
+	if err != nil {
+		return nil, fmt.Errorf("query %q, %s", q.expr, err)
+	}
+	return results, nil
+}
+
+// String returns the query's expression
+func (q *Query) String() string {
+	return q.expr
+}
+
+// toData converts a value to its JSON form
+func toData(v interface{}) (interface{}, error) {
+	src, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var data interface{}
+	if err := json.Unmarshal(src, &data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// categoryData replaces the categories of an RSS channel or item,
+// in its JSON form, with their values so they can be tested, e.g.
+// select(.category | contains("go")). "category" is always a list of
+// strings, empty without categories, and "categories" the list of
+// categories with their domains.
+func categoryData(obj map[string]interface{}) {
+	categories, _ := obj["category"].([]interface{})
+	if categories == nil {
+		categories = []interface{}{}
+	}
+	values := []interface{}{}
+	for _, val := range categories {
+		if category, ok := val.(map[string]interface{}); ok {
+			values = append(values, category["value"])
+		}
+	}
+	obj["category"] = values
+	obj["categories"] = categories
+}
+
+// FeedData parses an RSS 2, Atom or JSON Feed document returning its
+// JSON form for a query. RSS channel elements are at the top with
+// the items in .item, an RSS .category is the list of its category
+// values with the domains in .categories. Atom has .entry and JSON
+// Feed .items.
+func FeedData(src []byte) (interface{}, error) {
+	txt := bytes.TrimSpace(src)
+	switch feed.Sniff(txt) {
+	case feed.JSONFeed:
+		var data interface{}
+		if err := json.Unmarshal(txt, &data); err != nil {
+			return nil, err
+		}
+		return data, nil
+	case feed.RSS:
+		r, err := Parse(txt)
+		if err != nil {
+			return nil, err
+		}
+		data, err := toData(r)
+		if err != nil {
+			return nil, err
+		}
+		if channel, ok := data.(map[string]interface{}); ok {
+			categoryData(channel)
+			items, _ := channel["item"].([]interface{})
+			for _, item := range items {
+				if item, ok := item.(map[string]interface{}); ok {
+					categoryData(item)
+				}
+			}
+		}
+		return data, nil
+	case feed.Atom:
+		atom, err := ParseAtom(txt)
+		if err != nil {
+			return nil, err
+		}
+		return toData(atom)
+	}
+	return nil, fmt.Errorf("not an RSS, Atom or JSON Feed document")
+}
+
+// FormatResults renders the results of a query as "json" (a list),
+// "csv" or "lines". For CSV an object is a row, with a header row
+// from the first object's keys, a list is a row and other values
+// are a row of one column. Lines holds one result per line, strings
+// as is and other values as JSON.
+func FormatResults(results []interface{}, format string) ([]byte, error) {
+	switch format {
+	case "json":
+		return json.MarshalIndent(results, "", "    ")
+	case "lines":
+		lines := []string{}
+		for _, result := range results {
+			if s, ok := result.(string); ok {
+				lines = append(lines, s)
+			} else {
+				src, err := json.Marshal(result)
+				if err != nil {
+					return nil, err
+				}
+				lines = append(lines, string(src))
+			}
+		}
+		return []byte(strings.Join(lines, "\n")), nil
+	case "csv":
+		buf := new(bytes.Buffer)
+		w := csv.NewWriter(buf)
+		header := false
+		for _, result := range results {
+			row := []string{}
+			switch val := result.(type) {
+			case *queryObject:
+				if !header {
+					w.Write(val.keys)
+					header = true
+				}
+				for _, key := range val.keys {
+					row = append(row, valueString(val.vals[key]))
+				}
+			case map[string]interface{}:
+				keys := sortedKeys(val)
+				if !header {
+					w.Write(keys)
+					header = true
+				}
+				for _, key := range keys {
+					row = append(row, valueString(val[key]))
+				}
+			case []interface{}:
+				for _, item := range val {
+					row = append(row, valueString(item))
+				}
+			default:
+				row = append(row, valueString(val))
+			}
+			w.Write(row)
+		}
+		w.Flush()
+		return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), w.Error()
+	}
+	return nil, fmt.Errorf("format %q not supported, expected json, csv or lines", format)
+}
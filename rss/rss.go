@@ -12,8 +12,15 @@ import (
 	"encoding/json"
 	"encoding/xml"
 	"fmt"
+	"regexp"
 	"strconv"
 	"strings"
+	"time"
+)
+
+const (
+	// ContentNameSpace is the RSS content module used for content:encoded
+	ContentNameSpace = "http://purl.org/rss/1.0/modules/content/"
 )
 
 type CustomAttrs []xml.Attr
@@ -22,7 +29,7 @@ type AtomLink struct {
 	//XMLName xml.Name `xml:"http://www.w3.org/2005/Atom atom:link"`
 	HRef string `xml:"href,attr"`
 	Rel  string `xml:"rel,attr"`
-	Type string `xml:"type,attr"`
+	Type string `xml:"type,attr,omitempty"`
 }
 
 type RSS2 struct {
@@ -30,10 +37,22 @@ type RSS2 struct {
 	XMLName       xml.Name `xml:"rss" json:"-"`
 	Version       string   `xml:"version,attr" json:"version"`
 	AtomNameSpace string   `xml:"xmlns:atom,attr,omitempty" json:"-"`
+	// xmlns:content="http://purl.org/rss/1.0/modules/content/"
+	ContentNameSpace string `xml:"xmlns:content,attr,omitempty" json:"-"`
+	// xmlns:itunes and xmlns:podcast are declared by podcasts
+	ItunesNameSpace  string `xml:"xmlns:itunes,attr,omitempty" json:"-"`
+	PodcastNameSpace string `xml:"xmlns:podcast,attr,omitempty" json:"-"`
+
+	// xmlns:fh="http://purl.org/syndication/history/1.0"
+	HistoryNameSpace string `xml:"xmlns:fh,attr,omitempty" json:"-"`
 
 	// Recommended, atom:link to "self"
 	// E.g. <atom:link href="https://rsdoiel.github.io/rss.xml" rel="self" type="application/rss+xml" />
-	AtomLink *AtomLink `xml:"channel>atom:link,omitempty"`
+	// Paged and archived feeds (RFC 5005) link to their other
+	// documents, e.g. rel="next" or rel="prev-archive".
+	AtomLinks []*AtomLink `xml:"channel>atom:link,omitempty"`
+	// Archive marks an archive document of an archived feed (RFC 5005)
+	Archive *Archive `xml:"channel>fh:archive,omitempty" json:"archive,omitempty"`
 
 	// Required
 	Title       string `xml:"channel>title" json:"title"`
@@ -41,22 +60,31 @@ type RSS2 struct {
 	Description string `xml:"channel>description" json:"description"`
 
 	// Optional
-	Language       string `xml:"channel>language,omitempty" json:"language,omitempty"`
-	Copyright      string `xml:"channel>copyright,omitempty" json:"copyright,omitempty"`
-	ManagingEditor string `xml:"channel>managingEditor,omitempty" json:"managingEditor,omitempty"`
-	WebMaster      string `xml:"channel>webMaster,omitempty" json:"webMaster,omitempty"`
-	PubDate        string `xml:"channel>pubDate,omitempty" json:"pubDate,omitempty"`
-	LastBuildDate  string `xml:"channel>lastBuildDate,omitempty" json:"lastBuildDate,omitempty"`
-	Category       string `xml:"channel>category,omitempty" json:"category,omitempty"`
-	Generator      string `xml:"channel>generator,omitempty" json:"generator,omitempty"`
-	Docs           string `xml:"channel>docs,omitempty" json:"docs,omitempty"`
-	Cloud          string `xml:"channel>cloud,omitempty" json:"cloud,omitempty"`
-	TTL            string `xml:"channel>ttl,omitempty" json:"ttl,omitempty"`
-	Image          string `xml:"channel>image,omitempty" json:"image,omitempty"`
-	Rating         string `xml:"channel>rating,omitempty" json:"rating,omitempty"`
-	SkipHours      string `xml:"channel>skipHours,omitempty" json:"skipHours,omitempty"`
-	SkipDays       string `xml:"channel>skipDays,omitempty" json:"skipDays,omitempty"`
-	ItemList       []Item `xml:"channel>item,omitempty" json:"item,omitempty"`
+	Language       string      `xml:"channel>language,omitempty" json:"language,omitempty"`
+	Copyright      string      `xml:"channel>copyright,omitempty" json:"copyright,omitempty"`
+	ManagingEditor string      `xml:"channel>managingEditor,omitempty" json:"managingEditor,omitempty"`
+	WebMaster      string      `xml:"channel>webMaster,omitempty" json:"webMaster,omitempty"`
+	PubDate        string      `xml:"channel>pubDate,omitempty" json:"pubDate,omitempty"`
+	LastBuildDate  string      `xml:"channel>lastBuildDate,omitempty" json:"lastBuildDate,omitempty"`
+	Category       []*Category `xml:"channel>category,omitempty" json:"category,omitempty"`
+	Generator      string      `xml:"channel>generator,omitempty" json:"generator,omitempty"`
+	Docs           string      `xml:"channel>docs,omitempty" json:"docs,omitempty"`
+	Cloud          *Cloud      `xml:"channel>cloud,omitempty" json:"cloud,omitempty"`
+	TTL            string      `xml:"channel>ttl,omitempty" json:"ttl,omitempty"`
+	Image          *Image      `xml:"channel>image,omitempty" json:"image,omitempty"`
+	Rating         string      `xml:"channel>rating,omitempty" json:"rating,omitempty"`
+	SkipHours      *SkipHours  `xml:"channel>skipHours,omitempty" json:"skipHours,omitempty"`
+	SkipDays       *SkipDays   `xml:"channel>skipDays,omitempty" json:"skipDays,omitempty"`
+
+	// Podcast, Apple's iTunes tags
+	ItunesAuthor   string            `xml:"channel>itunes:author,omitempty" json:"itunesAuthor,omitempty"`
+	ItunesImage    *ItunesImage      `xml:"channel>itunes:image,omitempty" json:"itunesImage,omitempty"`
+	ItunesCategory []*ItunesCategory `xml:"channel>itunes:category,omitempty" json:"itunesCategory,omitempty"`
+	ItunesExplicit string            `xml:"channel>itunes:explicit,omitempty" json:"itunesExplicit,omitempty"`
+	ItunesOwner    *ItunesOwner      `xml:"channel>itunes:owner,omitempty" json:"itunesOwner,omitempty"`
+	ItunesType     string            `xml:"channel>itunes:type,omitempty" json:"itunesType,omitempty"`
+
+	ItemList []Item `xml:"channel>item,omitempty" json:"item,omitempty"`
 }
 
 type Item struct {
@@ -70,30 +98,218 @@ type Item struct {
 	// Optional
 	Author      string      `xml:"author,omitempty" json:"author,omitempty"`
 	Description string      `xml:"description,omitempty" json:"description,omitempty"`
-	Category    string      `xml:"category,omitempty" json:"category,omitempty"`
-	Content     string      `xml:"encoded,omitempty" json:"encoded,omitempty"`
+	Category    []*Category `xml:"category,omitempty" json:"category,omitempty"`
+	Content     *CData      `xml:"content:encoded,omitempty" json:"encoded,omitempty"`
 	PubDate     string      `xml:"pubDate,omitempty" json:"pubDate,omitempty"`
 	Comments    string      `xml:"comments,omitempty" json:"comments,omitempty"`
-	Enclosure   string      `xml:"enclosure,omitempty" json:"enclosure,omitempty"`
+	Enclosure   *Enclosure  `xml:"enclosure,omitempty" json:"enclosure,omitempty"`
 	GUID        string      `xml:"guid,omitempty" json:"guid,omitempty"`
-	Source      string      `xml:"source,omitempty" json:"source,omitempty"`
+	Source      *Source     `xml:"source,omitempty" json:"source,omitempty"`
 	OtherAttr   CustomAttrs `xml:",any,attr" json:"other_attrs,omitempty"`
+
+	// Podcast, iTunes and Podcasting 2.0 tags of an episode
+	Duration    string        `xml:"itunes:duration,omitempty" json:"duration,omitempty"`
+	Episode     string        `xml:"itunes:episode,omitempty" json:"episode,omitempty"`
+	Season      string        `xml:"itunes:season,omitempty" json:"season,omitempty"`
+	EpisodeType string        `xml:"itunes:episodeType,omitempty" json:"episodeType,omitempty"`
+	Explicit    string        `xml:"itunes:explicit,omitempty" json:"explicit,omitempty"`
+	Transcripts []*Transcript `xml:"podcast:transcript,omitempty" json:"transcript,omitempty"`
+	Chapters    *Chapters     `xml:"podcast:chapters,omitempty" json:"chapters,omitempty"`
+}
+
+// Category is a channel's or an item's category, Domain names the
+// taxonomy it comes from when there is one.
+type Category struct {
+	Domain string `xml:"domain,attr,omitempty" json:"domain,omitempty"`
+	Value  string `xml:",chardata" json:"value"`
+}
+
+// Image is a GIF, JPEG or PNG shown with the channel, its title and
+// link are usually the channel's.
+type Image struct {
+	URL         string `xml:"url" json:"url"`
+	Title       string `xml:"title" json:"title"`
+	Link        string `xml:"link" json:"link"`
+	Width       int    `xml:"width,omitempty" json:"width,omitempty"`
+	Height      int    `xml:"height,omitempty" json:"height,omitempty"`
+	Description string `xml:"description,omitempty" json:"description,omitempty"`
+}
+
+// Cloud is the rssCloud service notified when the channel changes
+type Cloud struct {
+	Domain            string `xml:"domain,attr" json:"domain"`
+	Port              int    `xml:"port,attr" json:"port"`
+	Path              string `xml:"path,attr" json:"path"`
+	RegisterProcedure string `xml:"registerProcedure,attr" json:"registerProcedure"`
+	Protocol          string `xml:"protocol,attr" json:"protocol"`
+}
+
+// SkipHours lists the hours (0 to 23, GMT) aggregators may skip
+type SkipHours struct {
+	Hours []int `xml:"hour" json:"hour"`
+}
+
+// SkipDays lists the days (e.g. Saturday) aggregators may skip
+type SkipDays struct {
+	Days []string `xml:"day" json:"day"`
+}
+
+// Categories returns a category in domain for each of values,
+// empty values and repeats are skipped.
+func Categories(domain string, values ...string) []*Category {
+	categories := []*Category{}
+	seen := map[string]bool{}
+	for _, val := range values {
+		val = strings.TrimSpace(val)
+		if val == "" || seen[strings.ToLower(val)] {
+			continue
+		}
+		seen[strings.ToLower(val)] = true
+		categories = append(categories, &Category{Domain: domain, Value: val})
+	}
+	return categories
+}
+
+// SetImage sets the channel's image to href titled and linked as
+// the channel.
+func (r *RSS2) SetImage(href string) {
+	r.Image = &Image{URL: href, Title: r.Title, Link: r.Link}
+}
+
+// ParseSkipHours reads a comma separated list of hours, 0 to 23.
+func ParseSkipHours(s string) (*SkipHours, error) {
+	skip := new(SkipHours)
+	for _, val := range strings.Split(s, ",") {
+		if val = strings.TrimSpace(val); val == "" {
+			continue
+		}
+		hour, err := strconv.Atoi(val)
+		if err != nil || hour < 0 || hour > 23 {
+			return nil, fmt.Errorf("%q isn't an hour, 0 to 23", val)
+		}
+		skip.Hours = append(skip.Hours, hour)
+	}
+	return skip, nil
+}
+
+// ParseSkipDays reads a comma separated list of days of the week,
+// e.g. "Saturday, Sunday".
+func ParseSkipDays(s string) (*SkipDays, error) {
+	skip := new(SkipDays)
+	for _, val := range strings.Split(s, ",") {
+		if val = strings.TrimSpace(val); val == "" {
+			continue
+		}
+		day := ""
+		for wd := time.Sunday; wd <= time.Saturday; wd++ {
+			if strings.EqualFold(val, wd.String()) {
+				day = wd.String()
+			}
+		}
+		if day == "" {
+			return nil, fmt.Errorf("%q isn't a day of the week", val)
+		}
+		skip.Days = append(skip.Days, day)
+	}
+	return skip, nil
 }
 
 type CData struct {
-	value string `xml:",cdata,omitempty" json:"value,omitempty"`
+	Value string `xml:",cdata" json:"value,omitempty"`
 }
 
 func (cdata *CData) Set(src string) {
-	cdata.value = src
+	cdata.Value = src
 }
 
 func (cdata *CData) String() string {
-	return cdata.value
+	return cdata.Value
 }
 
 func (cdata *CData) ToJSON() string {
-	return cdata.value
+	return cdata.Value
+}
+
+// MarshalJSON renders CData as a JSON string
+func (cdata *CData) MarshalJSON() ([]byte, error) {
+	return json.Marshal(cdata.Value)
+}
+
+// UnmarshalXML reads a feed, the iTunes tags are matched by
+// their name space so they are found whatever prefix a feed uses.
+func (r *RSS2) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type Plain RSS2
+	// Fields are matched in order, the name spaced elements come
+	// before Plain's (e.g. link, image and category) which match
+	// any name space.
+	obj := struct {
+		AtomLinks      []*AtomLink       `xml:"http://www.w3.org/2005/Atom channel>link"`
+		ItunesAuthor   string            `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd channel>author"`
+		ItunesImage    *ItunesImage      `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd channel>image"`
+		ItunesCategory []*ItunesCategory `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd channel>category"`
+		ItunesExplicit string            `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd channel>explicit"`
+		ItunesOwner    *struct {
+			Name  string `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd name"`
+			Email string `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd email"`
+		} `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd channel>owner"`
+		ItunesType string `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd channel>type"`
+		Plain
+	}{}
+	if err := d.DecodeElement(&obj, &start); err != nil {
+		return err
+	}
+	*r = RSS2(obj.Plain)
+	r.ItunesAuthor = obj.ItunesAuthor
+	r.ItunesImage = obj.ItunesImage
+	r.ItunesCategory = obj.ItunesCategory
+	r.ItunesExplicit = obj.ItunesExplicit
+	if obj.ItunesOwner != nil {
+		r.ItunesOwner = &ItunesOwner{Name: obj.ItunesOwner.Name, Email: obj.ItunesOwner.Email}
+	}
+	r.ItunesType = obj.ItunesType
+	r.AtomLinks = obj.AtomLinks
+	return nil
+}
+
+// UnmarshalXML reads an item, content:encoded and the podcast tags
+// are matched by their name spaces so they are found whatever
+// prefix a feed uses.
+func (item *Item) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type Plain Item
+	obj := struct {
+		Plain
+		Encoded     *CData        `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+		Duration    string        `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd duration"`
+		Episode     string        `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd episode"`
+		Season      string        `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd season"`
+		EpisodeType string        `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd episodeType"`
+		Explicit    string        `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd explicit"`
+		Transcripts []*Transcript `xml:"https://podcastindex.org/namespace/1.0 transcript"`
+		Chapters    *Chapters     `xml:"https://podcastindex.org/namespace/1.0 chapters"`
+	}{}
+	if err := d.DecodeElement(&obj, &start); err != nil {
+		return err
+	}
+	*item = Item(obj.Plain)
+	if obj.Encoded != nil {
+		item.Content = obj.Encoded
+	}
+	item.Duration = obj.Duration
+	item.Episode = obj.Episode
+	item.Season = obj.Season
+	item.EpisodeType = obj.EpisodeType
+	item.Explicit = obj.Explicit
+	item.Transcripts = obj.Transcripts
+	item.Chapters = obj.Chapters
+	return nil
+}
+
+// ContentString returns the item's content:encoded, if any.
+func (item *Item) ContentString() string {
+	if item.Content == nil {
+		return ""
+	}
+	return item.Content.Value
 }
 
 // MarshalJSON() marshals the custom attributes that might
@@ -120,166 +336,75 @@ func Parse(buf []byte) (*RSS2, error) {
 	return data, nil
 }
 
-func (r *RSS2) channel(dataPath string) (map[string]interface{}, error) {
-	results := make(map[string]interface{})
-	switch {
-	case strings.Compare(dataPath, ".channel") == 0:
-		// package and return all the channel fields
-		results[".title"] = r.Title
-		results[".link"] = r.Link
-		results[".description"] = r.Description
-		if r.PubDate != "" {
-			results[".pubDate"] = r.PubDate
-		}
-	case strings.HasSuffix(dataPath, ".title"):
-		results[".title"] = r.Title
-	case strings.HasSuffix(dataPath, ".link"):
-		results[".link"] = r.Link
-	case strings.HasSuffix(dataPath, ".description"):
-		results[".description"] = r.Description
-	case strings.HasSuffix(dataPath, ".pubDate"):
-		results[".pubDate"] = r.PubDate
-	default:
-		return nil, fmt.Errorf("Unknown data path %s", dataPath)
-	}
-	return results, nil
-}
-
-type rangeExpression struct {
-	first int
-	last  int
-}
-
-func getRange(listLength int, exp string) *rangeExpression {
-	rexp := new(rangeExpression)
-	rexp.first = 0
-	rexp.last = listLength - 1
-
-	if strings.Contains(exp, "-") == true {
-		nums := strings.SplitN(exp, "-", 2)
-		i, err := strconv.Atoi(nums[0])
-		if err == nil {
-			rexp.first = i
-		}
-		i, err = strconv.Atoi(nums[1])
-		if err == nil {
-			rexp.last = i
-		}
-	} else {
-		i, err := strconv.Atoi(exp)
-		if err == nil {
-			rexp.first = i
-			rexp.last = i
-		}
-	}
-	return rexp
-}
-
-func (rexp *rangeExpression) inRange(val int) bool {
-	if val >= rexp.first && val <= rexp.last {
-		return true
+// Marshal renders the feed as an indented RSS 2 document, the
+// elements holding only attributes are self closed.
+func (r *RSS2) Marshal() ([]byte, error) {
+	src, err := xml.MarshalIndent(r, "", "    ")
+	if err != nil {
+		return nil, err
 	}
-	return false
+	return []byte(emptyElements.Replace(xml.Header + string(src))), nil
 }
 
-func (r *RSS2) items(dataPath string) (map[string]interface{}, error) {
-	rexp := new(rangeExpression)
-	rexp.first = 0
-	rexp.last = len(r.ItemList) - 1
-
-	// Get the range expression so we know when to add it to results.
-	s := strings.Index(dataPath, "[")
-	e := strings.Index(dataPath, "]")
-	if s >= 0 && e >= 0 {
-		rexp = getRange(len(r.ItemList), dataPath[s:e])
-	}
-
-	results := make(map[string]interface{})
-	switch {
-	case strings.HasSuffix(dataPath, ".title") == true:
-		vals := []string{}
-		for i, item := range r.ItemList {
-			if rexp.inRange(i) == true {
-				vals = append(vals, item.Title)
-			}
-		}
-		results["title"] = vals
-	case strings.HasSuffix(dataPath, ".link") == true:
-		vals := []string{}
-		for i, item := range r.ItemList {
-			if rexp.inRange(i) == true {
-				vals = append(vals, item.Link)
-			}
-		}
-		results["link"] = vals
-	case strings.HasSuffix(dataPath, ".description") == true:
-		vals := []string{}
-		for i, item := range r.ItemList {
-			if rexp.inRange(i) == true {
-				vals = append(vals, item.Description)
-			}
-		}
-		results["description"] = vals
-	case strings.HasSuffix(dataPath, ".content") == true:
-		vals := []string{}
-		for i, item := range r.ItemList {
-			if rexp.inRange(i) == true {
-				vals = append(vals, item.Content)
-			}
-		}
-		results["content"] = vals
-	case strings.HasSuffix(dataPath, ".pubDate") == true:
-		vals := []string{}
-		for i, item := range r.ItemList {
-			if rexp.inRange(i) == true {
-				vals = append(vals, item.PubDate)
-			}
-		}
-		results["pubDate"] = vals
-	case strings.HasSuffix(dataPath, ".comments") == true:
-		vals := []string{}
-		for i, item := range r.ItemList {
-			if rexp.inRange(i) == true {
-				vals = append(vals, item.Comments)
-			}
-		}
-		results["comments"] = vals
-	}
-	return results, nil
-}
+// legacyRangeExp matches the "[first-last]" ranges of Filter's
+// data paths, the last item is included.
+var legacyRangeExp = regexp.MustCompile(`\[(-?\d*)-(-?\d*)\]`)
 
 // Filter given an RSS2 document return all the entries matching so we
 // can apply return each of the data paths requested.
 // e.g. .version, .channel.title, .channel.link, .item[].link,
 // .item[].guid, .item[].title, .item[].description
+//
+// The data paths are run as queries (see CompileQuery), the values
+// of item paths are returned as a list of strings.
 func (r *RSS2) Filter(dataPaths []string) (map[string]interface{}, error) {
-	var (
-		err  error
-		data map[string]interface{}
-	)
+	data, err := toData(r)
+	if err != nil {
+		return nil, err
+	}
 	result := make(map[string]interface{})
 	for _, dataPath := range dataPaths {
+		expr := dataPath
 		switch {
-		case strings.Compare(dataPath, ".version") == 0:
-			result["version"] = r.Version
-		case strings.HasPrefix(dataPath, ".channel"):
-			data, err = r.channel(dataPath)
-			// Merge data into results keyed' by path
-			for _, val := range data {
-				result[dataPath] = val
+		case dataPath == ".channel":
+			expr = "{title, link, description, pubDate}"
+		case strings.HasPrefix(dataPath, ".channel."):
+			expr = strings.TrimPrefix(dataPath, ".channel")
+		case strings.HasPrefix(dataPath, ".item[") && strings.HasSuffix(dataPath, ".content"):
+			expr = strings.TrimSuffix(dataPath, ".content") + ".encoded"
+		}
+		expr = legacyRangeExp.ReplaceAllStringFunc(expr, func(s string) string {
+			m := legacyRangeExp.FindStringSubmatch(s)
+			if last, err := strconv.Atoi(m[2]); err == nil {
+				return fmt.Sprintf("[%s:%d]", m[1], last+1)
 			}
-		case strings.HasPrefix(dataPath, ".item[]"):
-			data, err = r.items(dataPath)
-			// Merge data into results keyed' by path
-			for _, val := range data {
-				result[dataPath] = val
+			return fmt.Sprintf("[%s:]", m[1])
+		})
+		if !strings.HasPrefix(expr, ".") && !strings.HasPrefix(expr, "{") {
+			return nil, fmt.Errorf("path %q not found", dataPath)
+		}
+		q, err := CompileQuery(expr)
+		if err != nil {
+			return nil, fmt.Errorf("path %q not found, %s", dataPath, err)
+		}
+		vals, err := q.Run(data)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case dataPath == ".version":
+			result["version"] = r.Version
+		case strings.HasPrefix(dataPath, ".item["):
+			strs := []string{}
+			for _, val := range vals {
+				strs = append(strs, valueString(val))
 			}
+			result[dataPath] = strs
+		case len(vals) == 1:
+			result[dataPath] = vals[0]
 		default:
-			return nil, fmt.Errorf("path %q not found", dataPath)
+			result[dataPath] = vals
 		}
 	}
-	if result == nil {
-		return nil, fmt.Errorf("No data paths found")
-	}
-	return result, err
+	return result, nil
 }
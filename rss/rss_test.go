@@ -11,9 +11,18 @@ package rss
 import (
 	"bytes"
 	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
 	"net/url"
+	"os"
+	"path"
 	"strings"
 	"testing"
+
+	// My packages
+	"github.com/rsdoiel/pttk/blogit"
+	"github.com/rsdoiel/pttk/feed"
+	_ "github.com/rsdoiel/pttk/jsonfeed"
 )
 
 func TestRSS2(t *testing.T) {
@@ -169,3 +178,1101 @@ func TestNoTitleFeedItem(t *testing.T) {
 		t.Errorf("expected not title element, got\n%s\n", src)
 	}
 }
+
+func TestAtom(t *testing.T) {
+	feed := new(RSS2)
+	feed.Title = "My Blog"
+	feed.Link = "https://example.org/blog"
+	feed.Description = "A blog"
+	feed.LastBuildDate = "Sat, 30 Jul 2022 00:00:00 +0000"
+	feed.ItemList = append(feed.ItemList, Item{
+		Title:       "Turbo Oberon, the dream",
+		Link:        "/2022/07/30/Turbo-Oberon.html",
+		Description: "Sometimes I have odd dreams & more.\n\nA second paragraph.",
+		PubDate:     "Sat, 30 Jul 2022 00:00:00 +0000",
+	})
+	atom, err := feed.ToAtom("https://example.org/blog/atom.xml", "Jane Doe")
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	src, err := xml.MarshalIndent(atom, "", "    ")
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	for _, expected := range []string{
+		`<feed xmlns="http://www.w3.org/2005/Atom">`,
+		`<id>https://example.org/blog/atom.xml</id>`,
+		`<updated>2022-07-30T00:00:00Z</updated>`,
+		`<link href="https://example.org/blog/atom.xml" rel="self" type="application/atom+xml"></link>`,
+		`<name>Jane Doe</name>`,
+		`<id>https://example.org/blog/2022/07/30/Turbo-Oberon.html</id>`,
+		`<published>2022-07-30T00:00:00Z</published>`,
+		`<content type="html">&lt;p&gt;Sometimes I have odd dreams &amp;amp; more.&lt;/p&gt;`,
+	} {
+		if !bytes.Contains(src, []byte(expected)) {
+			t.Errorf("expected %s in\n%s", expected, src)
+		}
+	}
+	parsed, err := ParseAtom(src)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if len(parsed.Entries) != 1 || parsed.Entries[0].Title != "Turbo Oberon, the dream" {
+		t.Errorf("expected one entry to round trip, got %+v", parsed.Entries)
+	}
+}
+
+func TestAtomFromFeed(t *testing.T) {
+	// The newest entry is found by time, not by comparing the text
+	f := &feed.Feed{Title: "Dates", Link: "https://example.org/", Entries: []*feed.Entry{
+		{ID: "1", Title: "Earlier", Published: "2022-08-03T10:00:00+02:00"},
+		{ID: "2", Title: "Newest", Published: "2022-08-03T09:00:00.5Z"},
+		{ID: "3", Title: "Between", Published: "2022-08-03T09:00:00Z"},
+	}}
+	if atom := AtomFromFeed(f); atom.Updated != "2022-08-03T09:00:00.5Z" {
+		t.Errorf("expected the newest entry's date, got %q", atom.Updated)
+	}
+
+	// xhtml content keeps its markup
+	src := []byte(`<feed xmlns="http://www.w3.org/2005/Atom"><title>X</title><id>x</id><updated>2022-08-03T10:00:00Z</updated>
+<entry><title>Markup</title><id>1</id><updated>2022-08-03T10:00:00Z</updated>
+<content type="xhtml"><div xmlns="http://www.w3.org/1999/xhtml"><p>Some <em>emphasis</em></p></div></content></entry>
+</feed>`)
+	atom, err := ParseAtom(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry := atom.ToFeed().Entries[0]; entry.ContentHTML != "<p>Some <em>emphasis</em></p>" {
+		t.Errorf("expected the xhtml markup as HTML content, got %q", entry.ContentHTML)
+	}
+	out, err := atom.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(out, []byte(`<content type="xhtml"><div xmlns="http://www.w3.org/1999/xhtml"><p>Some <em>emphasis</em></p></div></content>`)) {
+		t.Errorf("expected the xhtml content written as markup, got\n%s", out)
+	}
+}
+
+func TestFullContent(t *testing.T) {
+	dName := t.TempDir()
+	fName := path.Join(dName, "post.md")
+	if err := os.WriteFile(fName, []byte("# A post\n\nFirst paragraph.\n"), 0664); err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	page := `<html><body><nav>menu</nav><article><h1>A post</h1>
+<p>First paragraph &amp; more.</p>
+<p>Second paragraph.</p>
+</article></body></html>`
+	if err := os.WriteFile(path.Join(dName, "post.html"), []byte(page), 0664); err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	blog := new(blogit.BlogMeta)
+	blog.Name = "My Blog"
+	blog.BaseURL = "https://example.org/blog"
+	blog.Years = []*blogit.YearObj{{
+		Year: "2022",
+		Months: []*blogit.MonthObj{{
+			Month: "07",
+			Days: []*blogit.DayObj{{
+				Day:   "30",
+				Posts: []*blogit.PostObj{{Title: "A post", Document: fName}},
+			}},
+		}},
+	}}
+	feed := new(RSS2)
+	feed.Version = "2.0"
+	if err := BlogMetaToRSS(blog, feed, &Options{FullContent: true}); err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	src, err := xml.MarshalIndent(feed, "", "    ")
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	for _, expected := range []string{
+		`xmlns:content="http://purl.org/rss/1.0/modules/content/"`,
+		`<content:encoded><![CDATA[<h1>A post</h1>`,
+		`<description>&lt;p&gt;First paragraph &amp;amp; more.&lt;/p&gt;`,
+	} {
+		if !bytes.Contains(src, []byte(expected)) {
+			t.Errorf("expected %s in\n%s", expected, src)
+		}
+	}
+	if bytes.Contains(src, []byte("<nav>")) {
+		t.Errorf("expected only the article in content:encoded\n%s", src)
+	}
+	parsed, err := Parse(src)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if len(parsed.ItemList) != 1 || !strings.HasPrefix(parsed.ItemList[0].ContentString(), "<h1>A post</h1>") {
+		t.Errorf("expected content:encoded to round trip, got %+v", parsed.ItemList)
+	}
+}
+
+func TestBlogMetaAuthors(t *testing.T) {
+	blog := new(blogit.BlogMeta)
+	blog.Name = "My Blog"
+	blog.BaseURL = "https://example.org/blog"
+	blog.Years = []*blogit.YearObj{{
+		Year: "2022",
+		Months: []*blogit.MonthObj{{
+			Month: "07",
+			Days: []*blogit.DayObj{{
+				Day: "30",
+				Posts: []*blogit.PostObj{
+					{Title: "By author", Description: "One", Document: "one.html", Author: "Jane Doe"},
+					{Title: "By creators", Description: "Two", Document: "two.html", Author: "Ignored",
+						Creators: []blogit.CreatorObj{{Name: "Ann Smith"}, {Name: "Bob Jones"}}},
+					{Title: "Anonymous", Description: "Three", Document: "three.html"},
+				},
+			}},
+		}},
+	}}
+	r := new(RSS2)
+	r.Version = "2.0"
+	if err := BlogMetaToRSS(blog, r); err != nil {
+		t.Fatal(err)
+	}
+	authors := map[string]string{}
+	for _, item := range r.ItemList {
+		authors[item.Title] = item.Author
+	}
+	for title, expected := range map[string]string{
+		"By author":   "Jane Doe",
+		"By creators": "Ann Smith, Bob Jones",
+		"Anonymous":   "",
+	} {
+		if authors[title] != expected {
+			t.Errorf("%q, expected author %q, got %q", title, expected, authors[title])
+		}
+	}
+	out, err := AtomFromFeed(r.ToFeed()).Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{
+		`<name>Jane Doe</name>`,
+		`<name>Ann Smith, Bob Jones</name>`,
+		`<name>My Blog</name>`,
+	} {
+		if !bytes.Contains(out, []byte(expected)) {
+			t.Errorf("expected %s in\n%s", expected, out)
+		}
+	}
+	if bytes.Count(out, []byte(`<name>My Blog</name>`)) != 1 {
+		t.Errorf("expected only the feed to fall back to the blog's name\n%s", out)
+	}
+}
+
+func TestIsBlogPost(t *testing.T) {
+	dName := t.TempDir()
+	dayDir := path.Join(dName, "2022", "07", "30")
+	if err := os.MkdirAll(dayDir, 0775); err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	for _, fName := range []string{"post.md", "post.html", "draft.md"} {
+		if err := os.WriteFile(path.Join(dayDir, fName), []byte("# A post\n"), 0664); err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+	}
+	if err := os.WriteFile(path.Join(dName, "about.md"), []byte("# About\n"), 0664); err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if err := os.WriteFile(path.Join(dName, "about.html"), []byte("<p>About</p>\n"), 0664); err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	for p, expected := range map[string]bool{
+		path.Join(dayDir, "post.md"):   true,
+		path.Join(dayDir, "draft.md"):  false,
+		path.Join(dayDir, "post.html"): false,
+		path.Join(dName, "about.md"):   false,
+	} {
+		if got := isBlogPost(p); got != expected {
+			t.Errorf("isBlogPost(%q) expected %t, got %t", p, expected, got)
+		}
+	}
+	// A path relative to htdocs has no leading slash
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	defer os.Chdir(cwd)
+	if err := os.Chdir(dName); err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if !isBlogPost("2022/07/30/post.md") {
+		t.Errorf("isBlogPost(%q) expected true for a relative path", "2022/07/30/post.md")
+	}
+}
+
+func TestPodcast(t *testing.T) {
+	htdocs := t.TempDir()
+	dName := path.Join(htdocs, "2022", "07", "30")
+	if err := os.MkdirAll(dName, 0775); err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	files := map[string]string{
+		"episode.md": `---
+title: Episode three
+author: Jane Doe
+pubDate: "2022-07-30"
+duration: 1805
+episode: 3
+season: 1
+explicit: false
+chapters: episode.chapters.json
+---
+
+# Episode three
+
+In this episode we talk about plain text.
+`,
+		"episode.html": "<article><p>In this episode we talk about plain text.</p></article>",
+		"episode.mp3":  strings.Repeat("x", 1234),
+		"episode.vtt":  "WEBVTT\n",
+	}
+	for name, src := range files {
+		if err := os.WriteFile(path.Join(dName, name), []byte(src), 0664); err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+	}
+	feed := new(RSS2)
+	feed.Version = "2.0"
+	feed.Title = "A show"
+	feed.Link = "https://example.org/show"
+	feed.ItunesOwner = ParseOwner("Jane Doe <jane@example.org>")
+	feed.ItunesCategory = ParseItunesCategories("Technology, Arts/Books")
+	if err := WalkRSS(feed, htdocs, "https://example.org/show", "", TitleExp, BylineExp, DateExp); err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	src, err := xml.MarshalIndent(feed, "", "    ")
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	for _, expected := range []string{
+		`xmlns:itunes="http://www.itunes.com/dtds/podcast-1.0.dtd"`,
+		`xmlns:podcast="https://podcastindex.org/namespace/1.0"`,
+		`<itunes:category text="Books"></itunes:category>`,
+		`<itunes:email>jane@example.org</itunes:email>`,
+		`<enclosure url="https://example.org/show/2022/07/30/episode.mp3" length="1234" type="audio/mpeg"></enclosure>`,
+		`<itunes:duration>1805</itunes:duration>`,
+		`<itunes:episode>3</itunes:episode>`,
+		`<itunes:season>1</itunes:season>`,
+		`<itunes:explicit>false</itunes:explicit>`,
+		`<podcast:transcript url="https://example.org/show/2022/07/30/episode.vtt" type="text/vtt"></podcast:transcript>`,
+		`<podcast:chapters url="https://example.org/show/2022/07/30/episode.chapters.json" type="application/json+chapters"></podcast:chapters>`,
+	} {
+		if !bytes.Contains(src, []byte(expected)) {
+			t.Errorf("expected %s in\n%s", expected, src)
+		}
+	}
+	parsed, err := Parse(src)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if parsed.ItunesOwner == nil || parsed.ItunesOwner.Email != "jane@example.org" {
+		t.Errorf("expected itunes:owner to round trip, got %+v", parsed.ItunesOwner)
+	}
+	if len(parsed.ItemList) != 1 {
+		t.Errorf("expected one item, got %d", len(parsed.ItemList))
+		t.FailNow()
+	}
+	item := parsed.ItemList[0]
+	if item.Enclosure == nil || item.Enclosure.Length != 1234 || item.Enclosure.Type != "audio/mpeg" {
+		t.Errorf("expected enclosure to round trip, got %+v", item.Enclosure)
+	}
+	if item.Duration != "1805" || item.Episode != "3" || len(item.Transcripts) != 1 || item.Chapters == nil {
+		t.Errorf("expected podcast tags to round trip, got %+v", item)
+	}
+}
+
+func TestPodcastRootRelative(t *testing.T) {
+	htdocs := t.TempDir()
+	dName := path.Join(htdocs, "2022", "07", "30")
+	for _, name := range []string{dName, path.Join(htdocs, "media")} {
+		if err := os.MkdirAll(name, 0775); err != nil {
+			t.Fatal(err)
+		}
+	}
+	fName := path.Join(dName, "episode.md")
+	files := map[string]string{
+		fName: `---
+title: Episode four
+description: Media kept in one place
+enclosure: /media/episode.mp3
+transcript: /media/episode.vtt
+---
+
+# Episode four
+`,
+		path.Join(htdocs, "media", "episode.mp3"): strings.Repeat("x", 42),
+	}
+	for name, src := range files {
+		if err := os.WriteFile(name, []byte(src), 0664); err != nil {
+			t.Fatal(err)
+		}
+	}
+	blog := new(blogit.BlogMeta)
+	blog.Name = "A show"
+	blog.BaseURL = "https://example.org/show"
+	blog.Years = []*blogit.YearObj{{
+		Year: "2022",
+		Months: []*blogit.MonthObj{{
+			Month: "07",
+			Days: []*blogit.DayObj{{
+				Day:   "30",
+				Posts: []*blogit.PostObj{{Title: "Episode four", Document: fName}},
+			}},
+		}},
+	}}
+	r := new(RSS2)
+	r.Version = "2.0"
+	if err := BlogMetaToRSS(blog, r, &Options{Htdocs: htdocs}); err != nil {
+		t.Fatal(err)
+	}
+	if len(r.ItemList) != 1 {
+		t.Fatalf("expected one item, got %d", len(r.ItemList))
+	}
+	item := r.ItemList[0]
+	if item.Enclosure == nil || item.Enclosure.URL != "https://example.org/show/media/episode.mp3" || item.Enclosure.Length != 42 {
+		t.Errorf("expected the enclosure resolved against the site with its length, got %+v", item.Enclosure)
+	}
+	if len(item.Transcripts) != 1 || item.Transcripts[0].URL != "https://example.org/show/media/episode.vtt" {
+		t.Errorf("expected the transcript resolved against the site, got %+v", item.Transcripts)
+	}
+}
+
+func TestBlogMetaToFeeds(t *testing.T) {
+	if slug := Slug(" Plain Text, Go! "); slug != "plain-text-go" {
+		t.Errorf("expected plain-text-go, got %q", slug)
+	}
+	blog := new(blogit.BlogMeta)
+	blog.Name = "My Blog"
+	blog.BaseURL = "https://example.org/blog"
+	blog.Years = []*blogit.YearObj{{
+		Year: "2022",
+		Months: []*blogit.MonthObj{{
+			Month: "07",
+			Days: []*blogit.DayObj{{
+				Day: "30",
+				Posts: []*blogit.PostObj{
+					{Title: "One", Document: "2022/07/30/one.md", Description: "first", Keywords: []string{"Go", "Plain Text"}, Author: "Jane Doe", Series: "Notes"},
+					{Title: "Two", Document: "2022/07/30/two.md", Description: "second", Keywords: []string{"go"}, Category: "Programming"},
+				},
+			}},
+		}},
+	}}
+	feed := new(RSS2)
+	feed.Version = "2.0"
+	feeds, err := BlogMetaToFeeds(blog, feed, "rss.xml", "https://example.org/feeds/")
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	expected := map[string]int{
+		"rss.xml":                    2,
+		"tags/go.xml":                2,
+		"tags/plain-text.xml":        1,
+		"categories/programming.xml": 1,
+		"series/notes.xml":           1,
+		"authors/jane-doe.xml":       1,
+	}
+	if len(feeds) != len(expected) {
+		t.Errorf("expected %d feeds, got %d", len(expected), len(feeds))
+	}
+	for name, cnt := range expected {
+		f, ok := feeds[name]
+		if !ok {
+			t.Errorf("expected feed %q", name)
+			continue
+		}
+		if len(f.ItemList) != cnt {
+			t.Errorf("expected %d items in %q, got %d", cnt, name, len(f.ItemList))
+		}
+		if href := f.AtomLinkHRef("self"); href != "https://example.org/feeds/"+name {
+			t.Errorf("expected atom:link self for %q, got %q", name, href)
+		}
+	}
+	if f, ok := feeds["tags/plain-text.xml"]; ok && f.Title != "My Blog: Plain Text" {
+		t.Errorf("expected title %q, got %q", "My Blog: Plain Text", f.Title)
+	}
+	// Items are categorized by their keywords and category, the
+	// topic feeds by their name and their items sourced from the blog
+	f, ok := feeds["rss.xml"]
+	if !ok {
+		t.Fatalf("expected feed %q", "rss.xml")
+	}
+	src, err := xml.Marshal(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{`<category>Go</category><category>Plain Text</category>`, `<category>go</category><category>Programming</category>`} {
+		if !bytes.Contains(src, []byte(s)) {
+			t.Errorf("expected %s in\n%s", s, src)
+		}
+	}
+	f, ok = feeds["categories/programming.xml"]
+	if !ok {
+		t.Fatalf("expected feed %q", "categories/programming.xml")
+	}
+	if len(f.Category) != 1 || f.Category[0].Domain != FeedCategories || f.Category[0].Value != "Programming" {
+		t.Errorf("unexpected categories %+v", f.Category)
+	}
+	if source := f.ItemList[0].Source; source == nil || source.URL != "https://example.org/feeds/rss.xml" || source.Value != "My Blog" {
+		t.Errorf("expected the item's source to be the blog's feed, got %+v", source)
+	}
+}
+
+func TestArchived(t *testing.T) {
+	feed := new(RSS2)
+	feed.Version = "2.0"
+	feed.Title = "My Blog"
+	feed.Link = "https://example.org/blog"
+	for _, day := range []string{"03", "05", "01", "04", "02"} {
+		feed.ItemList = append(feed.ItemList, Item{
+			Title:   "Post " + day,
+			Link:    "https://example.org/blog/2022/07/" + day + "/post.html",
+			PubDate: day + " Jul 22 00:00 +0000",
+		})
+	}
+	feed.ItemList = append(feed.ItemList, Item{Title: "Undated"})
+	feed.SortItems()
+	titles := []string{}
+	for _, item := range feed.ItemList {
+		titles = append(titles, item.Title)
+	}
+	if strings.Join(titles, ", ") != "Post 05, Post 04, Post 03, Post 02, Post 01, Undated" {
+		t.Errorf("expected newest first, got %s", strings.Join(titles, ", "))
+	}
+
+	feedURL := "https://example.org/blog/rss.xml"
+	pages := feed.Paginate(2, feedURL)
+	if len(pages) != 3 {
+		t.Errorf("expected 3 pages, got %d", len(pages))
+		t.FailNow()
+	}
+	if href := pages[1].AtomLinkHRef("next"); href != "https://example.org/blog/rss-3.xml" {
+		t.Errorf("expected next link to page 3, got %q", href)
+	}
+	if href := pages[1].AtomLinkHRef("previous"); href != feedURL {
+		t.Errorf("expected previous link to page 1, got %q", href)
+	}
+	if href := pages[0].AtomLinkHRef("last"); href != "https://example.org/blog/rss-3.xml" {
+		t.Errorf("expected last link to page 3, got %q", href)
+	}
+
+	current, archives := feed.Archived(2, feedURL)
+	if len(current.ItemList) != 2 || current.ItemList[0].Title != "Post 05" {
+		t.Errorf("expected the newest two items in the subscription feed, got %+v", current.ItemList)
+	}
+	if len(archives) != 2 {
+		t.Errorf("expected 2 archives, got %d", len(archives))
+		t.FailNow()
+	}
+	if archives[0].ItemList[1].Title != "Undated" || archives[0].ItemList[0].Title != "Post 01" {
+		t.Errorf("expected the oldest items in the first archive, got %+v", archives[0].ItemList)
+	}
+	if archives[1].ItemList[0].Title != "Post 03" || archives[1].ItemList[1].Title != "Post 02" {
+		t.Errorf("expected the items after the subscription feed in the newest archive, got %+v", archives[1].ItemList)
+	}
+	if href := current.AtomLinkHRef("prev-archive"); href != "https://example.org/blog/rss-archive-2.xml" {
+		t.Errorf("expected prev-archive link to the newest archive, got %q", href)
+	}
+	// A new post stays in the subscription feed, the archives don't change
+	newer := new(RSS2)
+	*newer = *feed
+	newer.ItemList = append([]Item{{Title: "Post 06", Link: "https://example.org/blog/2022/07/06/post.html", PubDate: "06 Jul 22 00:00 +0000"}}, feed.ItemList...)
+	current, newArchives := newer.Archived(2, feedURL)
+	if len(current.ItemList) != 3 || len(newArchives) != 2 || newArchives[1].ItemList[0].Title != "Post 03" {
+		t.Errorf("expected three items in the subscription feed and the same archives, got %+v, %d archives", current.ItemList, len(newArchives))
+	}
+	src, err := xml.MarshalIndent(archives[1], "", "    ")
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	for _, expected := range []string{
+		`xmlns:fh="http://purl.org/syndication/history/1.0"`,
+		`<fh:archive></fh:archive>`,
+		`<atom:link href="https://example.org/blog/rss-archive-2.xml" rel="self" type="application/rss+xml"></atom:link>`,
+		`<atom:link href="https://example.org/blog/rss.xml" rel="current" type="application/rss+xml"></atom:link>`,
+		`<atom:link href="https://example.org/blog/rss-archive-1.xml" rel="prev-archive" type="application/rss+xml"></atom:link>`,
+	} {
+		if !bytes.Contains(src, []byte(expected)) {
+			t.Errorf("expected %s in\n%s", expected, src)
+		}
+	}
+	if href := archives[0].AtomLinkHRef("next-archive"); href != "https://example.org/blog/rss-archive-2.xml" {
+		t.Errorf("expected next-archive link to the newest archive, got %q", href)
+	}
+	feed.Limit(3)
+	if len(feed.ItemList) != 3 {
+		t.Errorf("expected 3 items, got %d", len(feed.ItemList))
+	}
+}
+
+func TestQuery(t *testing.T) {
+	src := []byte(`<?xml version="1.0"?>
+<rss version="2.0">
+    <channel>
+        <title>Query Test</title>
+        <link>https://example.org/</link>
+        <description>Items to query</description>
+        <item><title>One</title><link>https://example.org/1</link><category>go</category></item>
+        <item><title>Two</title><link>https://example.org/2</link><category>oberon</category></item>
+        <item><title>Three, "3"</title><link>https://example.org/3</link><category>go</category><category domain="tags">web</category></item>
+        <item><title>Four</title><link>https://example.org/4</link><category>go</category></item>
+    </channel>
+</rss>`)
+	data, err := FeedData(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{
+		`.title`: `Query Test`,
+		`.item[0:3] | select(.category | contains("go")) | .link`: "https://example.org/1\nhttps://example.org/3",
+		// The documented query, "Three" has the categories go and web
+		`.item[0:5] | select(.category | contains("go")) | .link`: "https://example.org/1\nhttps://example.org/3\nhttps://example.org/4",
+		`.item[-1].title`: `Four`,
+		`.item | length`:  `4`,
+		`.item[] | select(.title | startswith("T")) | .title`:                                "Two\nThree, \"3\"",
+		`.item[1:] | select((.category | contains("go") | not) or .title == "Four") | .link`: "https://example.org/2\nhttps://example.org/4",
+		`.item[0] | {title, url: .link}`:                                                     `{"title":"One","url":"https://example.org/1"}`,
+		// An item's categories are a list whatever their number
+		`.category | length`: `0`,
+		`.item[0].category`:  `["go"]`,
+		`.item[2] | select(.category | contains("web")) | .title`:   `Three, "3"`,
+		`.item[] | select(.categories[].domain == "tags") | .title`: `Three, "3"`,
+		`.item[1].categories[0].value`:                              `oberon`,
+		// Building lists isn't supported, an empty string expects an error
+		`[.item[] | .category] | join(",")`: ``,
+	}
+	for expr, want := range expected {
+		q, err := CompileQuery(expr)
+		if err != nil {
+			if want == "" {
+				continue
+			}
+			t.Errorf("%s", err)
+			continue
+		}
+		if want == "" {
+			t.Errorf("expected %q to fail", expr)
+			continue
+		}
+		results, err := q.Run(data)
+		if err != nil {
+			t.Errorf("%s", err)
+			continue
+		}
+		got, err := FormatResults(results, "lines")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != want {
+			t.Errorf("%s: expected %q, got %q", expr, want, got)
+		}
+	}
+
+	q, err := CompileQuery(`.item[] | select(.category | contains("go")) | {title, link}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, err := q.Run(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := FormatResults(results, "csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `title,link
+One,https://example.org/1
+"Three, ""3""",https://example.org/3
+Four,https://example.org/4`
+	if string(got) != want {
+		t.Errorf("expected CSV\n%s\ngot\n%s", want, got)
+	}
+	if _, err := FormatResults(results, "yaml"); err == nil {
+		t.Errorf("expected an error for an unknown format")
+	}
+	// A category column holds a list for every row
+	if q, err = CompileQuery(`.item[1:3] | {title, category}`); err != nil {
+		t.Fatal(err)
+	}
+	if results, err = q.Run(data); err != nil {
+		t.Fatal(err)
+	}
+	if got, err = FormatResults(results, "csv"); err != nil {
+		t.Fatal(err)
+	}
+	want = `title,category
+Two,"[""oberon""]"
+"Three, ""3""","[""go"",""web""]"`
+	if string(got) != want {
+		t.Errorf("expected CSV\n%s\ngot\n%s", want, got)
+	}
+
+	// Atom and JSON Feed documents are queried in their own terms
+	atom := []byte(`<feed xmlns="http://www.w3.org/2005/Atom"><title>Atom</title><entry><title>Entry</title><id>1</id><link href="https://example.org/entry"/><category term="go"/></entry></feed>`)
+	jsonFeed := []byte(`{"version": "https://jsonfeed.org/version/1.1", "title": "JSON", "items": [{"id": "1", "url": "https://example.org/item", "tags": ["go"]}]}`)
+	for doc, expr := range map[string]string{
+		string(atom):     `.entry[0:5] | select(.category[].term == "go") | .link[0].href`,
+		string(jsonFeed): `.items[] | select(.tags | contains("go")) | .url`,
+	} {
+		data, err := FeedData([]byte(doc))
+		if err != nil {
+			t.Fatal(err)
+		}
+		q, err := CompileQuery(expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		results, err := q.Run(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(results) != 1 || !strings.HasPrefix(results[0].(string), "https://example.org/") {
+			t.Errorf("%s: unexpected results %+v", expr, results)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	valid := []byte(`<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0" xmlns:atom="http://www.w3.org/2005/Atom">
+    <channel>
+        <title>Valid</title>
+        <link>https://example.org/</link>
+        <description>A valid feed</description>
+        <atom:link href="https://example.org/rss.xml" rel="self" type="application/rss+xml"/>
+        <pubDate>Tue, 02 Aug 2022 10:00:00 GMT</pubDate>
+        <item>
+            <title>One</title>
+            <link>https://example.org/1</link>
+            <guid>https://example.org/1</guid>
+            <pubDate>02 Aug 22 10:00 -0700</pubDate>
+        </item>
+        <item>
+            <description>Two</description>
+            <guid isPermaLink="false">two</guid>
+        </item>
+    </channel>
+</rss>`)
+	if problems := Validate(valid); len(problems) > 0 {
+		t.Errorf("expected no problems, got %+v", problems)
+	}
+
+	invalid := []byte(`<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0" xmlns:atom="http://www.w3.org/2005/Atom/">
+    <channel>
+        <title>Invalid</title>
+        <link>/blog/</link>
+        <pubDate>Monday, 1 Aug 2022</pubDate>
+        <item>
+            <link>https://example.org/1</link>
+            <guid>/blog/1</guid>
+            <pubDate>Mon, 02 Aug 2022 10:00:00 GMT</pubDate>
+            <dc:creator>Jane</dc:creator>
+        </item>
+        <item>
+            <title>Two</title>
+            <guid isPermaLink="false">/blog/1</guid>
+            <enclosure url="https://example.org/1.mp3" type="audio/mpeg"/>
+        </item>
+    </channel>
+</rss>`)
+	expected := []string{
+		`line 2: error: xmlns:atom`,
+		`line 3: error: <channel> is missing <description>`,
+		`line 3: warning: <channel> is missing <atom:link rel="self">`,
+		`line 5: error: <link> "/blog/" isn't an absolute URL`,
+		`line 6: error: <pubDate> "Monday, 1 Aug 2022" isn't an RFC 822 date`,
+		`line 7: error: <item> needs a <title> or <description>`,
+		`line 9: error: <guid> "/blog/1" is a permalink`,
+		`line 10: error: <pubDate> "Mon, 02 Aug 2022 10:00:00 GMT", the day is a Tuesday`,
+		`line 11: error: <dc:creator> uses the undeclared name space prefix "dc"`,
+		`line 15: error: <guid> "/blog/1" isn't unique, first seen on line 9`,
+		`line 16: error: <enclosure> is missing the length attribute`,
+	}
+	problems := Validate(invalid)
+	if len(problems) != len(expected) {
+		t.Errorf("expected %d problems, got %d", len(expected), len(problems))
+	}
+	for i, problem := range problems {
+		if i < len(expected) && !strings.HasPrefix(problem.String(), expected[i]) {
+			t.Errorf("expected %q, got %q", expected[i], problem)
+		}
+	}
+
+	problems = Validate([]byte("<rss version=\"2.0\">\n<channel>\n</rss>"))
+	if len(problems) != 1 || problems[0].Line != 3 || !strings.Contains(problems[0].Message, "not well formed") {
+		t.Errorf("expected not well formed on line 3, got %+v", problems)
+	}
+}
+
+func TestHTMLPages(t *testing.T) {
+	htdocs := t.TempDir()
+	pages := map[string]string{
+		"index.html": `<html><head><title>Home</title></head><body><p>Welcome</p></body></html>`,
+		"notes/first.html": `<!DOCTYPE html>
+<html>
+<head>
+<title>First &amp; Foremost</title>
+<meta name="description" content="The first note.">
+<meta name="author" content="Jane Doe">
+<meta property="article:published_time" content="2022-08-01T10:00:00Z">
+<meta name="keywords" content="go, notes">
+</head>
+<body><article><h1>First</h1><p>Opening paragraph.</p></article></body>
+</html>`,
+		"notes/second.html": `<html><head><title>Second</title>
+<link rel="canonical" href="https://example.org/notes/second/">
+</head>
+<body><main><p>Posted <time datetime="2022-08-02">August 2nd</time>.</p><p>More.</p></main></body></html>`,
+		"drafts/third.html": `<html><head><title>Third</title></head><body><time datetime="2022-08-03"></time></body></html>`,
+		"about/index.html":  `<html><head><title>About</title></head><body><p>About us.</p></body></html>`,
+	}
+	for name, src := range pages {
+		fName := path.Join(htdocs, name)
+		if err := os.MkdirAll(path.Dir(fName), 0775); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(fName, []byte(src), 0664); err != nil {
+			t.Fatal(err)
+		}
+	}
+	page := ReadHTMLMeta([]byte(pages["notes/first.html"]))
+	if page.Title != "First & Foremost" || page.Description != "The first note." || page.Author != "Jane Doe" || strings.Join(page.Keywords, ",") != "go,notes" {
+		t.Errorf("unexpected page metadata %+v", page)
+	}
+
+	feed := &RSS2{Title: "Notes", Link: "https://example.org"}
+	if err := WalkHTML(feed, htdocs, "", "drafts"); err != nil {
+		t.Fatal(err)
+	}
+	if len(feed.ItemList) != 2 {
+		t.Fatalf("expected 2 items, got %d", len(feed.ItemList))
+	}
+	second, first := feed.ItemList[0], feed.ItemList[1]
+	if second.Link != "https://example.org/notes/second/" || second.Description != "Posted August 2nd." || second.PubDate != "Tue, 02 Aug 2022 00:00:00 +0000" {
+		t.Errorf("unexpected item %+v", second)
+	}
+	if first.Link != "https://example.org/notes/first.html" || first.Title != "First & Foremost" || first.Description != "The first note." {
+		t.Errorf("unexpected item %+v", first)
+	}
+
+	sitemap := []byte(`<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+<url><loc>https://example.org/about/</loc><lastmod>2022-07-01</lastmod></url>
+<url><loc>https://example.org/notes/first.html</loc></url>
+<url><loc>https://example.org/drafts/third.html</loc></url>
+<url><loc>https://example.org/old/moved.html</loc><lastmod>2022-07-02</lastmod></url>
+</urlset>`)
+	feed = &RSS2{Title: "Notes", Link: "https://example.org"}
+	if err := SitemapToRSS(feed, sitemap, htdocs, "https://example.org", &Options{FullContent: true}); err != nil {
+		t.Fatal(err)
+	}
+	if len(feed.ItemList) != 3 {
+		t.Fatalf("expected 3 items, got %d", len(feed.ItemList))
+	}
+	if item := feed.ItemList[2]; item.Title != "About" || item.Link != "https://example.org/about/" || item.PubDate != "Fri, 01 Jul 2022 00:00:00 +0000" {
+		t.Errorf("unexpected item %+v", item)
+	}
+	if item := feed.ItemList[1]; item.ContentString() != "<h1>First</h1><p>Opening paragraph.</p>" || item.Description != "<p>The first note.</p>" {
+		t.Errorf("unexpected full content %q, %q", item.ContentString(), item.Description)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	sources := map[string]string{
+		"https://a.example.org/rss.xml": `<rss version="2.0"><channel><title>Site A</title><link>https://a.example.org/</link><description>A</description>
+<item><title>A one</title><link>/one.html</link><pubDate>Mon, 01 Aug 2022 10:00:00 +0000</pubDate></item>
+<item><title>Shared</title><link>https://example.com/shared.html</link><guid>shared</guid><pubDate>Tue, 02 Aug 2022 10:00:00 +0000</pubDate></item>
+</channel></rss>`,
+		"https://b.example.org/atom.xml": `<feed xmlns="http://www.w3.org/2005/Atom"><title>Site B</title><link href="https://b.example.org/"/>
+<entry><title>B one</title><id>tag:b.example.org,2022:1</id><link href="https://b.example.org/1"/><updated>2022-08-03T10:00:00Z</updated><author><name>Bea</name></author><summary type="html">&lt;p&gt;B &amp;amp; more&lt;/p&gt;</summary><content type="html">&lt;p&gt;B &amp;amp; more, in full&lt;/p&gt;</content></entry>
+<entry><title>Shared again</title><id>shared-b</id><link href="https://example.com/shared.html"/><updated>2022-08-02T09:00:00Z</updated></entry>
+</feed>`,
+		"https://c.example.org/feed.json": `{"version": "https://jsonfeed.org/version/1.1", "title": "Site C", "home_page_url": "https://c.example.org/",
+"items": [{"id": "c1", "url": "https://c.example.org/1", "title": "C one", "content_text": "Plain [text]", "date_published": "2022-07-31T10:00:00Z"}]}`,
+	}
+	planet := &RSS2{Version: "2.0", Title: "Planet", Link: "https://planet.example.org/"}
+	for _, feedURL := range []string{"https://a.example.org/rss.xml", "https://b.example.org/atom.xml", "https://c.example.org/feed.json"} {
+		f, _, err := feed.Parse([]byte(sources[feedURL]))
+		if err != nil {
+			t.Fatalf("%s, %s", feedURL, err)
+		}
+		planet.Merge(f, feedURL)
+	}
+	planet.SortItems()
+	expected := []string{"B one", "Shared", "A one", "C one"}
+	if len(planet.ItemList) != len(expected) {
+		t.Fatalf("expected %d items, got %d", len(expected), len(planet.ItemList))
+	}
+	for i, item := range planet.ItemList {
+		if item.Title != expected[i] {
+			t.Errorf("expected item %d to be %q, got %q", i, expected[i], item.Title)
+		}
+		if item.Source == nil || !strings.HasPrefix(item.Source.Value, "Site ") {
+			t.Errorf("expected %q to have a source, got %+v", item.Title, item.Source)
+		}
+	}
+	if item := planet.ItemList[2]; item.Link != "https://a.example.org/one.html" || item.Source.URL != "https://a.example.org/rss.xml" {
+		t.Errorf("unexpected item %+v", item)
+	}
+	src, err := xml.Marshal(planet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(src, []byte(`<source url="https://b.example.org/atom.xml">Site B</source>`)) {
+		t.Errorf("expected source element, got\n%s", src)
+	}
+	if planet.ContentNameSpace != ContentNameSpace || !bytes.Contains(src, []byte(`<![CDATA[<p>B &amp; more, in full</p>]]>`)) {
+		t.Errorf("expected B one's content and the content name space, got\n%s", src)
+	}
+
+	buf := new(bytes.Buffer)
+	if err := planet.RenderRiver(buf, "markdown", 80); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{
+		"# Planet\n",
+		"## Wednesday, August 3, 2022\n\n- [B one](https://b.example.org/1), [Site B](https://b.example.org/atom.xml), Bea\n\n    B & more\n",
+		"    Plain \\[text\\]\n",
+	} {
+		if !strings.Contains(buf.String(), s) {
+			t.Errorf("expected %q in river, got\n%s", s, buf.String())
+		}
+	}
+	buf.Reset()
+	if err := planet.RenderRiver(buf, "html", 80); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), `<h3><a href="https://b.example.org/1">B one</a></h3>`) || !strings.Contains(buf.String(), "<p>B &amp; more</p>") {
+		t.Errorf("unexpected HTML river\n%s", buf.String())
+	}
+}
+
+func TestWebSub(t *testing.T) {
+	// A stub hub recording the feeds it is told about
+	pinged := []string{}
+	hub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil || r.Method != http.MethodPost || r.PostForm.Get("hub.mode") != "publish" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		pinged = append(pinged, r.PostForm.Get("hub.url"))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer hub.Close()
+
+	feed := &RSS2{Version: "2.0", Title: "Hubbed", Link: "https://example.org/"}
+	feed.AddHub(hub.URL)
+	feed.AddHub(hub.URL)
+	feed.AddHub("https://hub.example.org/")
+	feed.SetAtomLink("self", "https://example.org/rss.xml")
+	if hubs := feed.Hubs(); len(hubs) != 2 || hubs[0] != hub.URL {
+		t.Errorf("expected two hubs, got %+v", hubs)
+	}
+	src, err := xml.Marshal(feed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(src, []byte(`<atom:link href="https://hub.example.org/" rel="hub"></atom:link>`)) {
+		t.Errorf("expected a hub atom:link, got\n%s", src)
+	}
+	for i := 0; i < 3; i++ {
+		feed.ItemList = append(feed.ItemList, Item{Title: "Post", Link: "https://example.org/post.html"})
+	}
+	for _, page := range feed.Paginate(1, "https://example.org/rss.xml") {
+		if len(page.Hubs()) != 2 {
+			t.Errorf("expected page %q to declare the hubs", page.AtomLinkHRef("self"))
+		}
+	}
+	atom, err := feed.ToAtom(feed.AtomLinkHRef("self"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	src, err = xml.Marshal(atom)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(src, []byte(`<link href="https://hub.example.org/" rel="hub"></link>`)) {
+		t.Errorf("expected a hub link, got\n%s", src)
+	}
+
+	topics := []string{"https://example.org/rss.xml", "https://example.org/tags/go.xml"}
+	if err := PingHub(hub.URL, topics); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(pinged, " ") != strings.Join(topics, " ") {
+		t.Errorf("expected the hub to be pinged for %+v, got %+v", topics, pinged)
+	}
+	broken := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "hub is down", http.StatusServiceUnavailable)
+	}))
+	defer broken.Close()
+	if err := PingHub(broken.URL, topics[:1]); err == nil || !strings.Contains(err.Error(), "hub is down") {
+		t.Errorf("expected the hub's error, got %v", err)
+	}
+}
+
+func TestChannelMetadata(t *testing.T) {
+	feed := &RSS2{Version: "2.0", Title: "Metadata", Link: "https://example.org/", Description: "Structured channel elements"}
+	feed.Category = Categories("https://example.org/topics", "Go", "go", " ", "Plain Text")
+	feed.SetImage("https://example.org/logo.png")
+	feed.AtomNameSpace = AtomNameSpace
+	feed.SetAtomLink("self", "https://example.org/rss.xml")
+	feed.Cloud = &Cloud{Domain: "rpc.example.org", Port: 80, Path: "/RPC2", RegisterProcedure: "pingMe", Protocol: "xml-rpc"}
+	var err error
+	if feed.SkipHours, err = ParseSkipHours("0, 1,23"); err != nil {
+		t.Fatal(err)
+	}
+	if feed.SkipDays, err = ParseSkipDays("saturday,Sunday"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseSkipHours("24"); err == nil {
+		t.Errorf("expected an error for hour 24")
+	}
+	if _, err := ParseSkipDays("Caturday"); err == nil {
+		t.Errorf("expected an error for Caturday")
+	}
+	feed.ItemList = append(feed.ItemList, Item{Title: "Post", Link: "https://example.org/post.html", Category: []*Category{{Value: "go"}, {Domain: "series", Value: "Notes"}}})
+	src, err := feed.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{
+		`<category domain="https://example.org/topics">Go</category>`,
+		`<category domain="https://example.org/topics">Plain Text</category>`,
+		`<cloud domain="rpc.example.org" port="80" path="/RPC2" registerProcedure="pingMe" protocol="xml-rpc"/>`,
+		`<image>
+            <url>https://example.org/logo.png</url>
+            <title>Metadata</title>
+            <link>https://example.org/</link>
+        </image>`,
+		`<skipHours>
+            <hour>0</hour>
+            <hour>1</hour>
+            <hour>23</hour>
+        </skipHours>`,
+		`<skipDays>
+            <day>Saturday</day>
+            <day>Sunday</day>
+        </skipDays>`,
+		`<category>go</category>`,
+		`<category domain="series">Notes</category>`,
+	} {
+		if !bytes.Contains(src, []byte(s)) {
+			t.Errorf("expected %s in\n%s", s, src)
+		}
+	}
+	for _, problem := range Validate(src) {
+		t.Errorf("unexpected problem %s", problem)
+	}
+
+	// and they are read back
+	parsed, err := Parse(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(parsed.Category) != 2 || parsed.Category[1].Value != "Plain Text" || parsed.Category[1].Domain != "https://example.org/topics" {
+		t.Errorf("unexpected categories %+v", parsed.Category)
+	}
+	if parsed.Image == nil || parsed.Image.URL != "https://example.org/logo.png" || parsed.Image.Title != "Metadata" {
+		t.Errorf("unexpected image %+v", parsed.Image)
+	}
+	if parsed.Cloud == nil || parsed.Cloud.Port != 80 || parsed.Cloud.RegisterProcedure != "pingMe" {
+		t.Errorf("unexpected cloud %+v", parsed.Cloud)
+	}
+	if parsed.SkipHours == nil || len(parsed.SkipHours.Hours) != 3 || parsed.SkipDays == nil || len(parsed.SkipDays.Days) != 2 {
+		t.Errorf("unexpected skipHours %+v and skipDays %+v", parsed.SkipHours, parsed.SkipDays)
+	}
+	if categories := parsed.ItemList[0].Category; len(categories) != 2 || categories[1].Domain != "series" {
+		t.Errorf("unexpected item categories %+v", categories)
+	}
+	atom, err := feed.ToAtom("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if atom.Logo != "https://example.org/logo.png" || len(atom.Category) != 2 || atom.Entries[0].Category[1].Scheme != "series" {
+		t.Errorf("unexpected Atom feed %+v", atom)
+	}
+}
+
+func TestTOMLPost(t *testing.T) {
+	htdocs := t.TempDir()
+	dName := path.Join(htdocs, "2022", "10", "31")
+	if err := os.MkdirAll(dName, 0775); err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	files := map[string]string{
+		"hugo.md": `+++
+title = "A Hugo post"
+date = 2022-10-31T09:30:00Z
+tags = ["go", "toml"]
++++
+
+A post written for Hugo.
+`,
+		"hugo.html": "<p>A post written for Hugo.</p>",
+		"yaml.md": `---
+title: A YAML post
+pubDate: 2022-10-30
+---
+
+A post with an unquoted date.
+`,
+		"yaml.html": "<p>A post with an unquoted date.</p>",
+	}
+	for name, src := range files {
+		if err := os.WriteFile(path.Join(dName, name), []byte(src), 0664); err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+	}
+	feed := new(RSS2)
+	feed.Version = "2.0"
+	feed.Title = "A blog"
+	feed.Link = "https://example.org"
+	if err := WalkRSS(feed, htdocs, "https://example.org", "", TitleExp, BylineExp, DateExp); err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if len(feed.ItemList) != 2 {
+		t.Errorf("expected two items, got %d", len(feed.ItemList))
+		t.FailNow()
+	}
+	item := feed.ItemList[0]
+	if item.Title != "A Hugo post" || item.PubDate != "Mon, 31 Oct 2022 00:00:00 +0000" {
+		t.Errorf("expected the TOML post first, got %q %q", item.Title, item.PubDate)
+	}
+	if len(item.Category) != 2 || item.Category[1].Value != "toml" {
+		t.Errorf("expected the TOML tags as categories, got %+v", item.Category)
+	}
+	if item = feed.ItemList[1]; item.PubDate != "Sun, 30 Oct 2022 00:00:00 +0000" {
+		t.Errorf("expected the YAML post's date, got %q", item.PubDate)
+	}
+}
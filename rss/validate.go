@@ -0,0 +1,441 @@
+// pttk is software for working with plain text content.
+// Copyright (C) 2022 R. S. Doiel
+//
+// This program is free software: you can redistribute it and/or modify it under the terms of the GNU Affero General Public License as published by the Free Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License along with this program. If not, see <https://www.gnu.org/licenses/>.
+package rss
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DublinCoreNameSpace is the XML name space of the dc:* elements
+	DublinCoreNameSpace = "http://purl.org/dc/elements/1.1/"
+)
+
+var (
+	// rfc822Exp matches an RFC 822 date, four digit years are
+	// accepted as the RSS 2.0 specification recommends.
+	rfc822Exp = regexp.MustCompile(`^(?:(Mon|Tue|Wed|Thu|Fri|Sat|Sun), )?(\d{1,2}) (Jan|Feb|Mar|Apr|May|Jun|Jul|Aug|Sep|Oct|Nov|Dec) (\d{2}|\d{4}) (\d{2}:\d{2}(?::\d{2})?) (UT|GMT|EST|EDT|CST|CDT|MST|MDT|PST|PDT|Z|[+-]\d{4})$`)
+
+	// knownNameSpaces maps the usual prefixes to their name space
+	knownNameSpaces = map[string]string{
+		"atom":    AtomNameSpace,
+		"content": ContentNameSpace,
+		"dc":      DublinCoreNameSpace,
+		"fh":      HistoryNameSpace,
+		"itunes":  ItunesNameSpace,
+		"podcast": PodcastNameSpace,
+	}
+
+	// channelElements are the RSS 2.0 elements of a channel, true
+	// for those allowed more than once.
+	channelElements = map[string]bool{
+		"title": false, "link": false, "description": false,
+		"language": false, "copyright": false, "managingEditor": false,
+		"webMaster": false, "pubDate": false, "lastBuildDate": false,
+		"category": true, "generator": false, "docs": false,
+		"cloud": false, "ttl": false, "image": false, "rating": false,
+		"textInput": false, "skipHours": false, "skipDays": false,
+		"item": true,
+	}
+
+	// itemElements are the RSS 2.0 elements of an item, true for
+	// those allowed more than once.
+	itemElements = map[string]bool{
+		"title": false, "link": false, "description": false,
+		"author": false, "category": true, "comments": false,
+		"enclosure": false, "guid": false, "pubDate": false,
+		"source": false,
+	}
+)
+
+// Problem is an issue found validating a feed
+type Problem struct {
+	// Line of the feed the problem was found on
+	Line int `json:"line"`
+	// Level is "error" or "warning"
+	Level string `json:"level"`
+	// Message describes the problem
+	Message string `json:"message"`
+}
+
+// String formats a problem as "line N: LEVEL: MESSAGE"
+func (p *Problem) String() string {
+	return fmt.Sprintf("line %d: %s: %s", p.Line, p.Level, p.Message)
+}
+
+// element is an XML element read for validation
+type element struct {
+	prefix   string
+	name     string
+	attrs    []xml.Attr
+	text     string
+	line     int
+	children []*element
+}
+
+// qname returns the element's name as written in the feed
+func (elem *element) qname() string {
+	if elem.prefix != "" {
+		return elem.prefix + ":" + elem.name
+	}
+	return elem.name
+}
+
+// attr returns the value of an unprefixed attribute
+func (elem *element) attr(name string) (string, bool) {
+	for _, attr := range elem.attrs {
+		if attr.Name.Space == "" && attr.Name.Local == name {
+			return attr.Value, true
+		}
+	}
+	return "", false
+}
+
+// child returns the first child named prefix:name
+func (elem *element) child(prefix string, name string) *element {
+	for _, child := range elem.children {
+		if child.prefix == prefix && child.name == name {
+			return child
+		}
+	}
+	return nil
+}
+
+// validator collects the problems found in a feed
+type validator struct {
+	problems []*Problem
+}
+
+func (v *validator) errorf(line int, format string, args ...interface{}) {
+	v.problems = append(v.problems, &Problem{Line: line, Level: "error", Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *validator) warnf(line int, format string, args ...interface{}) {
+	v.problems = append(v.problems, &Problem{Line: line, Level: "warning", Message: fmt.Sprintf(format, args...)})
+}
+
+// readElements reads the feed's element tree checking that it is
+// well formed and that the name space prefixes used are declared.
+func (v *validator) readElements(src []byte) *element {
+	d := xml.NewDecoder(bytes.NewReader(src))
+	var (
+		root   *element
+		stack  []*element
+		scopes []map[string]string
+	)
+	lookup := func(prefix string) (string, bool) {
+		for i := len(scopes) - 1; i >= 0; i-- {
+			if uri, ok := scopes[i][prefix]; ok {
+				return uri, true
+			}
+		}
+		return "", prefix == "xml"
+	}
+	for {
+		line, _ := d.InputPos()
+		tok, err := d.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			msg := err.Error()
+			var syntaxErr *xml.SyntaxError
+			if errors.As(err, &syntaxErr) {
+				line, msg = syntaxErr.Line, syntaxErr.Msg
+			}
+			v.errorf(line, "not well formed, %s", msg)
+			return nil
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			elem := &element{prefix: t.Name.Space, name: t.Name.Local, line: line}
+			scope := map[string]string{}
+			for _, attr := range t.Attr {
+				switch {
+				case attr.Name.Space == "xmlns":
+					scope[attr.Name.Local] = attr.Value
+					if uri, ok := knownNameSpaces[attr.Name.Local]; ok && uri != attr.Value {
+						v.errorf(line, "xmlns:%s is %q, expected %q", attr.Name.Local, attr.Value, uri)
+					}
+				case attr.Name.Space == "" && attr.Name.Local == "xmlns":
+					scope[""] = attr.Value
+				default:
+					elem.attrs = append(elem.attrs, attr)
+				}
+			}
+			scopes = append(scopes, scope)
+			if elem.prefix != "" {
+				if _, ok := lookup(elem.prefix); !ok {
+					v.errorf(line, "<%s> uses the undeclared name space prefix %q", elem.qname(), elem.prefix)
+				}
+			} else if uri, _ := lookup(""); uri != "" {
+				v.errorf(line, "<%s> is in the name space %q, RSS 2.0 elements aren't in a name space", elem.name, uri)
+			}
+			for _, attr := range elem.attrs {
+				if attr.Name.Space != "" {
+					if _, ok := lookup(attr.Name.Space); !ok {
+						v.errorf(line, "attribute %s:%s uses the undeclared name space prefix %q", attr.Name.Space, attr.Name.Local, attr.Name.Space)
+					}
+				}
+			}
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.children = append(parent.children, elem)
+			} else if root == nil {
+				root = elem
+			}
+			stack = append(stack, elem)
+		case xml.EndElement:
+			if len(stack) == 0 {
+				v.errorf(line, "not well formed, unexpected </%s>", t.Name.Local)
+				return nil
+			}
+			elem := stack[len(stack)-1]
+			if elem.prefix != t.Name.Space || elem.name != t.Name.Local {
+				v.errorf(line, "not well formed, <%s> on line %d is closed by </%s>", elem.qname(), elem.line, strings.TrimPrefix(t.Name.Space+":"+t.Name.Local, ":"))
+				return nil
+			}
+			elem.text = strings.TrimSpace(elem.text)
+			stack = stack[:len(stack)-1]
+			scopes = scopes[:len(scopes)-1]
+		case xml.CharData:
+			if len(stack) > 0 {
+				stack[len(stack)-1].text += string(t)
+			}
+		}
+	}
+	if len(stack) > 0 {
+		elem := stack[len(stack)-1]
+		v.errorf(elem.line, "not well formed, <%s> isn't closed", elem.qname())
+		return nil
+	}
+	return root
+}
+
+// checkDate reports a date that isn't RFC 822
+func (v *validator) checkDate(elem *element) {
+	m := rfc822Exp.FindStringSubmatch(elem.text)
+	if m == nil {
+		v.errorf(elem.line, "<%s> %q isn't an RFC 822 date, e.g. \"Mon, 02 Jan 2006 15:04:05 -0700\"", elem.qname(), elem.text)
+		return
+	}
+	layout := "2 Jan 2006 15:04"
+	if len(m[4]) == 2 {
+		layout = "2 Jan 06 15:04"
+	}
+	if len(m[5]) > 5 {
+		layout += ":05"
+	}
+	zone := m[6]
+	switch {
+	case zone == "Z" || zone == "UT":
+		zone = "+0000"
+		layout += " -0700"
+	case strings.HasPrefix(zone, "+") || strings.HasPrefix(zone, "-"):
+		layout += " -0700"
+	default:
+		layout += " MST"
+	}
+	dt, err := time.Parse(layout, strings.Join([]string{m[2], m[3], m[4], m[5], zone}, " "))
+	if err != nil {
+		v.errorf(elem.line, "<%s> %q isn't a valid date", elem.qname(), elem.text)
+		return
+	}
+	if m[1] != "" && m[1] != dt.Format("Mon") {
+		v.errorf(elem.line, "<%s> %q, the day is a %s", elem.qname(), elem.text, dt.Format("Monday"))
+	}
+}
+
+// checkURL reports a URL that isn't absolute
+func (v *validator) checkURL(line int, what string, s string) {
+	u, err := url.Parse(strings.TrimSpace(s))
+	if err != nil || !u.IsAbs() || (u.Host == "" && u.Opaque == "") {
+		v.errorf(line, "%s %q isn't an absolute URL", what, s)
+	}
+}
+
+// checkRequired reports the required child elements that are missing
+func (v *validator) checkRequired(elem *element, names ...string) {
+	for _, name := range names {
+		if child := elem.child("", name); child == nil || child.text == "" {
+			v.errorf(elem.line, "<%s> is missing <%s>", elem.qname(), name)
+		}
+	}
+}
+
+// checkElements reports unknown and repeated RSS 2.0 elements
+func (v *validator) checkElements(elem *element, known map[string]bool) {
+	seen := map[string]int{}
+	for _, child := range elem.children {
+		if child.prefix != "" {
+			continue
+		}
+		repeats, ok := known[child.name]
+		if !ok {
+			v.warnf(child.line, "<%s> isn't an RSS 2.0 element of <%s>", child.name, elem.name)
+			continue
+		}
+		if first, ok := seen[child.name]; ok && !repeats {
+			v.errorf(child.line, "<%s> is repeated, first seen on line %d", child.name, first)
+			continue
+		}
+		seen[child.name] = child.line
+	}
+}
+
+// checkChannel validates a channel and its items
+func (v *validator) checkChannel(channel *element) {
+	v.checkElements(channel, channelElements)
+	v.checkRequired(channel, "title", "link", "description")
+	self := false
+	guids := map[string]int{}
+	for _, child := range channel.children {
+		switch child.qname() {
+		case "link", "docs":
+			if child.text != "" {
+				v.checkURL(child.line, "<"+child.name+">", child.text)
+			}
+		case "pubDate", "lastBuildDate":
+			v.checkDate(child)
+		case "ttl":
+			if _, err := strconv.Atoi(child.text); err != nil {
+				v.errorf(child.line, "<ttl> %q isn't a number of minutes", child.text)
+			}
+		case "image":
+			v.checkRequired(child, "url", "title", "link")
+			for _, name := range []string{"url", "link"} {
+				if elem := child.child("", name); elem != nil && elem.text != "" {
+					v.checkURL(elem.line, "<image><"+name+">", elem.text)
+				}
+			}
+		case "atom:link":
+			href, _ := child.attr("href")
+			v.checkURL(child.line, "<atom:link> href", href)
+			if rel, _ := child.attr("rel"); rel == "self" {
+				self = true
+				if mimeType, _ := child.attr("type"); mimeType != "application/rss+xml" {
+					v.warnf(child.line, "<atom:link rel=\"self\"> type is %q, expected \"application/rss+xml\"", mimeType)
+				}
+			}
+		case "item":
+			v.checkItem(child, guids)
+		}
+	}
+	if !self {
+		v.warnf(channel.line, "<channel> is missing <atom:link rel=\"self\">, the feed's own URL")
+	}
+}
+
+// checkItem validates an item, guids maps the guids seen to their line
+func (v *validator) checkItem(item *element, guids map[string]int) {
+	v.checkElements(item, itemElements)
+	title, description := item.child("", "title"), item.child("", "description")
+	if (title == nil || title.text == "") && (description == nil || description.text == "") {
+		v.errorf(item.line, "<item> needs a <title> or <description>")
+	}
+	for _, child := range item.children {
+		switch child.qname() {
+		case "link", "comments":
+			v.checkURL(child.line, "<"+child.name+">", child.text)
+		case "pubDate":
+			v.checkDate(child)
+		case "guid":
+			if first, ok := guids[child.text]; ok {
+				v.errorf(child.line, "<guid> %q isn't unique, first seen on line %d", child.text, first)
+			} else {
+				guids[child.text] = child.line
+			}
+			isPermaLink, ok := child.attr("isPermaLink")
+			if ok && isPermaLink != "true" && isPermaLink != "false" {
+				v.errorf(child.line, "<guid> isPermaLink is %q, expected \"true\" or \"false\"", isPermaLink)
+			}
+			if isPermaLink != "false" {
+				u, err := url.Parse(child.text)
+				if err != nil || !u.IsAbs() {
+					v.errorf(child.line, "<guid> %q is a permalink (isPermaLink defaults to true) but isn't an absolute URL, add isPermaLink=\"false\" if it isn't a link", child.text)
+				}
+			}
+		case "enclosure":
+			for _, name := range []string{"url", "length", "type"} {
+				if val, _ := child.attr(name); val == "" {
+					v.errorf(child.line, "<enclosure> is missing the %s attribute", name)
+				}
+			}
+			if val, ok := child.attr("url"); ok && val != "" {
+				v.checkURL(child.line, "<enclosure> url", val)
+			}
+			if val, ok := child.attr("length"); ok && val != "" {
+				if _, err := strconv.ParseInt(val, 10, 64); err != nil {
+					v.errorf(child.line, "<enclosure> length %q isn't a number of bytes", val)
+				}
+			}
+		case "source":
+			if val, _ := child.attr("url"); val == "" {
+				v.errorf(child.line, "<source> is missing the url attribute")
+			} else {
+				v.checkURL(child.line, "<source> url", val)
+			}
+		}
+	}
+}
+
+// Validate checks an RSS 2.0 feed against the rules enforced by the
+// W3C Feed Validation Service and the RSS Advisory Board's validator:
+// the feed is well formed, the channel has its required elements,
+// dates are RFC 822, links are absolute URLs, guids are unique and
+// permalinks when isPermaLink is true, the channel links to itself
+// with atom:link rel="self" and name space prefixes are declared and
+// bound to the right name space. The problems are returned in line
+// order.
+func Validate(src []byte) []*Problem {
+	v := new(validator)
+	root := v.readElements(src)
+	switch {
+	case root == nil:
+		if len(v.problems) == 0 {
+			v.errorf(1, "no XML elements found")
+		}
+	case root.qname() != "rss":
+		v.errorf(root.line, "<%s> isn't an RSS 2.0 feed, expected <rss>", root.qname())
+	default:
+		if version, _ := root.attr("version"); version != "2.0" {
+			v.errorf(root.line, "<rss> version is %q, expected \"2.0\"", version)
+		}
+		channels := []*element{}
+		for _, child := range root.children {
+			if child.qname() == "channel" {
+				channels = append(channels, child)
+			} else {
+				v.errorf(child.line, "<%s> isn't allowed in <rss>", child.qname())
+			}
+		}
+		switch len(channels) {
+		case 0:
+			v.errorf(root.line, "<rss> is missing <channel>")
+		case 1:
+			v.checkChannel(channels[0])
+		default:
+			v.errorf(channels[1].line, "<rss> has more than one <channel>")
+		}
+	}
+	sort.SliceStable(v.problems, func(i, j int) bool {
+		return v.problems[i].Line < v.problems[j].Line
+	})
+	return v.problems
+}
@@ -0,0 +1,57 @@
+// pttk is software for working with plain text content.
+// Copyright (C) 2022 R. S. Doiel
+//
+// This program is free software: you can redistribute it and/or modify it under the terms of the GNU Affero General Public License as published by the Free Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License along with this program. If not, see <https://www.gnu.org/licenses/>.
+package rss
+
+import (
+	"fmt"
+	"strings"
+
+	// My packages
+	"github.com/rsdoiel/pttk/websub"
+)
+
+// AddHub declares a WebSub hub for the feed with an atom:link whose
+// relation is "hub", a feed may declare several.
+func (r *RSS2) AddHub(href string) {
+	for _, link := range r.AtomLinks {
+		if link.Rel == "hub" && link.HRef == href {
+			return
+		}
+	}
+	if r.AtomNameSpace == "" {
+		r.AtomNameSpace = AtomNameSpace
+	}
+	r.AtomLinks = append(r.AtomLinks, &AtomLink{HRef: href, Rel: "hub"})
+}
+
+// Hubs returns the WebSub hubs declared by the feed.
+func (r *RSS2) Hubs() []string {
+	hubs := []string{}
+	for _, link := range r.AtomLinks {
+		if link.Rel == "hub" {
+			hubs = append(hubs, link.HRef)
+		}
+	}
+	return hubs
+}
+
+// PingHub sends a WebSub publish notification to hub for each of
+// the feed URLs in topics.
+func PingHub(hub string, topics []string) error {
+	errs := []string{}
+	for _, topic := range topics {
+		if err := websub.Ping(hub, topic); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(errs, "\n"))
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+// pttk is software for working with plain text content.
+// Copyright (C) 2022 R. S. Doiel
+//
+// This program is free software: you can redistribute it and/or modify it under the terms of the GNU Affero General Public License as published by the Free Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+// Package websub sends WebSub (formerly PubSubHubbub) publish
+// notifications to the hubs a feed declares so subscribers learn of
+// new content without polling, see https://www.w3.org/TR/websub/.
+package websub
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	// My packages
+	"github.com/rsdoiel/pttk"
+	"github.com/rsdoiel/pttk/feed"
+)
+
+const (
+	// HubType is the hub type used by JSON Feed's hubs
+	HubType = "WebSub"
+)
+
+var (
+	// Timeout points at feed.Timeout, how long Ping waits for a hub
+	// to answer.
+	//
+	// Deprecated: set feed.Timeout.
+	Timeout = &feed.Timeout
+)
+
+// Ping tells hub that the feed at topic has been updated. It posts the
+// form hub.mode=publish and hub.url=topic, the publish request
+// supported by the common hubs. Any 2xx status is success.
+func Ping(hub string, topic string) error {
+	form := url.Values{}
+	form.Set("hub.mode", "publish")
+	form.Set("hub.url", topic)
+	req, err := http.NewRequest(http.MethodPost, hub, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("User-Agent", "pttk/"+pttk.Version)
+	client := &http.Client{Timeout: feed.Timeout}
+	res, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("Pinging %q, %s", hub, err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(res.Body, 512))
+		return fmt.Errorf("Pinging %q for %q, %s %s", hub, topic, res.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+// pttk is software for working with plain text content.
+// Copyright (C) 2022 R. S. Doiel
+//
+// This program is free software: you can redistribute it and/or modify it under the terms of the GNU Affero General Public License as published by the Free Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License along with this program. If not, see <https://www.gnu.org/licenses/>.
+package websub
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPing(t *testing.T) {
+	var form map[string][]string
+	hub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Type") != "application/x-www-form-urlencoded" {
+			http.Error(w, "expected a form", http.StatusBadRequest)
+			return
+		}
+		r.ParseForm()
+		if r.PostForm.Get("hub.url") == "" {
+			http.Error(w, "hub.url is required", http.StatusBadRequest)
+			return
+		}
+		form = r.PostForm
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer hub.Close()
+
+	topic := "https://example.org/feed.json"
+	if err := Ping(hub.URL, topic); err != nil {
+		t.Fatal(err)
+	}
+	for key, val := range map[string]string{"hub.mode": "publish", "hub.url": topic} {
+		if len(form[key]) != 1 || form[key][0] != val {
+			t.Errorf("expected %s=%q, got %+v", key, val, form[key])
+		}
+	}
+	if err := Ping(hub.URL, ""); err == nil {
+		t.Errorf("expected an error for a bad request")
+	}
+	if err := Ping("http://127.0.0.1:1/", topic); err == nil {
+		t.Errorf("expected an error when the hub can't be reached")
+	}
+}